@@ -0,0 +1,59 @@
+# PowerShell script to back up a WSL instance to a tar archive
+# Usage: ./backup_instance.ps1 -DistroName "Ubuntu-24.04" -OutputFile "D:\Backups\Ubuntu-24.04.tar"
+
+param(
+    [Parameter(Mandatory=$true)]
+    [string]$DistroName,
+
+    [Parameter(Mandatory=$true)]
+    [string]$OutputFile
+)
+
+$ErrorActionPreference = "Stop"
+
+# --- Logging Setup ---
+. "$PSScriptRoot\pwsh_utils.ps1"
+Setup-Logger -LogFileName "backup.log"
+
+# Get list of distros, trim whitespace, and filter empty lines
+# This handles potential encoding issues with wsl output
+$rawOutput = wsl --list --quiet
+$distros = $rawOutput | ForEach-Object { $_.Trim() -replace "`0", "" } | Where-Object { -not [string]::IsNullOrWhiteSpace($_) }
+
+if ($distros -notcontains $DistroName) {
+    $msg = "WSL instance '$DistroName' not found. Available: $($distros -join ', ')"
+    Log-Message $msg "ERROR"
+    Write-Error $msg
+    exit 1
+}
+
+$OutputFile = [System.IO.Path]::GetFullPath($OutputFile)
+$OutputDir = Split-Path $OutputFile -Parent
+if (-not (Test-Path $OutputDir)) {
+    New-Item -ItemType Directory -Force -Path $OutputDir | Out-Null
+}
+if (Test-Path $OutputFile) {
+    $msg = "Backup file '$OutputFile' already exists."
+    Log-Message $msg "ERROR"
+    Write-Error $msg
+    exit 1
+}
+
+try {
+    Write-ProgressEvent -Stage "export" -Percent -1 -Message "Exporting '$DistroName'..." -Stages @("export")
+    Log-Message "Backing up '$DistroName' to '$OutputFile'..."
+    wsl --export $DistroName $OutputFile
+    if ($LASTEXITCODE -ne 0 -or -not (Test-Path $OutputFile)) {
+        throw "wsl --export exited with code $LASTEXITCODE"
+    }
+
+    $SizeMB = "{0:N2}" -f ((Get-Item $OutputFile).Length / 1MB)
+    Write-ProgressEvent -Stage "export" -Percent 100 -Message "Backup complete ($SizeMB MB)"
+    Log-Message "Backup complete: $OutputFile ($SizeMB MB)"
+} catch {
+    $err = "Backup failed: $_"
+    Log-Message $err "ERROR"
+    Write-Error $err
+    if (Test-Path $OutputFile) { Remove-Item $OutputFile -Force -ErrorAction SilentlyContinue }
+    exit 1
+}
@@ -72,6 +72,54 @@ if ($GlobalSettings.DistroCachePath) {
 # Normalize path
 $BaseDir = [System.IO.Path]::GetFullPath($BaseDir)
 
+# Returns a catalog version with the same Url whose cached file exists
+function Find-CachedByUrl($Url) {
+    foreach ($fk in $ConfigRaw.PSObject.Properties.Name) {
+        $fam = $ConfigRaw.$fk
+        if (-not $fam.PSObject.Properties['Versions']) { continue }
+        foreach ($vk in $fam.Versions.PSObject.Properties.Name) {
+            $v = $fam.Versions.$vk
+            if ($v.Url -eq $Url -and $v.LocalPath -and (Test-Path $v.LocalPath)) { return $v }
+        }
+    }
+    return $null
+}
+
+# Records a stored package in <cache>/index.json (source URLs and use times for GC and LRU eviction).
+# Identical content can come from several URLs; every one of them is kept.
+function Update-CacheIndex([string]$Hash, [string]$Url, [string]$Filename, [long]$Size) {
+    $IndexPath = Join-Path $BaseDir "index.json"
+    $Index = [ordered]@{}
+    if (Test-Path $IndexPath) {
+        try {
+            $Raw = Get-Content -Raw -Path $IndexPath | ConvertFrom-Json
+            foreach ($p in $Raw.PSObject.Properties) { $Index[$p.Name] = $p.Value }
+        } catch {
+            Log-Message "WARNING: cache index unreadable, rebuilding: $_"
+        }
+    }
+    $Now = (Get-Date).ToUniversalTime().ToString("o")
+    $Urls = @()
+    $Added = $Now
+    if ($Index.Contains($Hash)) {
+        $Old = $Index[$Hash]
+        if ($Old.PSObject.Properties['Urls']) { $Urls += @($Old.Urls) }
+        if ($Old.Url -and $Urls -notcontains $Old.Url) { $Urls += $Old.Url }
+        if ($Old.Added) { $Added = $Old.Added }
+        if ($Old.Filename) { $Filename = $Old.Filename }
+    }
+    if ($Urls -notcontains $Url) { $Urls += $Url }
+    $Index[$Hash] = [ordered]@{
+        Url      = $Url
+        Urls     = $Urls
+        Filename = $Filename
+        Size     = $Size
+        Added    = $Added
+        LastUsed = $Now
+    }
+    $Index | ConvertTo-Json -Depth 3 | Set-Content -Path $IndexPath -Encoding UTF8
+}
+
 Log-Message "=== WSL Distro Downloader ==="
 Log-Message "Base Directory: $BaseDir"
 if ($SelectFamily) { Log-Message "Filtering Family: [$SelectFamily]" }
@@ -94,23 +142,31 @@ foreach ($FamilyKey in $DistroCatalog.Keys) {
 
         $Version = $Family.Versions[$VerKey]
         
-        # Organization: distro/FamilyName/VersionName/File
-        $TargetDir = Join-Path $BaseDir $Family.Name
-        $TargetDir = Join-Path $TargetDir $Version.Name
-        
-        if (-not (Test-Path $TargetDir)) {
-            New-Item -ItemType Directory -Path $TargetDir -Force | Out-Null
-        }
-
-        $OutFile = Join-Path $TargetDir $Version.Filename
-        
+        # Content-addressed store: <cache>/sha256/<hash>/<Filename>
+        # Catalog entries with the same Url share one file.
         Log-Message "[$($Family.Name)] $($Version.Name)"
 
-        $FileExists = Test-Path $OutFile
-        if ($FileExists) {
+        $OutFile = $null
+        $Hash = $null
+        $Current = $ConfigRaw.$FamilyKey.Versions.$VerKey
+        if ($Current.LocalPath -and (Test-Path $Current.LocalPath)) {
+            $OutFile = $Current.LocalPath
+            $Hash = $Current.Sha256
             Log-Message " -> Skipped (Already exists)" "WARN"
         } else {
+            $Shared = Find-CachedByUrl $Version.Url
+            if ($Shared) {
+                $OutFile = $Shared.LocalPath
+                $Hash = $Shared.Sha256
+                Log-Message " -> Reusing cached copy of the same URL: $OutFile"
+            }
+        }
+
+        if (-not $OutFile) {
             Log-Message " -> Downloading..."
+            $PartialDir = Join-Path $BaseDir ".partial"
+            if (-not (Test-Path $PartialDir)) { New-Item -ItemType Directory -Path $PartialDir -Force | Out-Null }
+            $PartFile = Join-Path $PartialDir $Version.Filename
             try {
                 # Use .NET HttpClient for better progress tracking
                 $httpClient = New-Object System.Net.Http.HttpClient
@@ -125,7 +181,7 @@ foreach ($FamilyKey in $DistroCatalog.Keys) {
                     $streamTask.Wait()
                     $stream = $streamTask.Result
                     
-                    $fileStream = [System.IO.File]::Create($OutFile)
+                    $fileStream = [System.IO.File]::Create($PartFile)
                     $buffer = New-Object byte[] 81920 # 80KB buffer
                     $totalRead = 0
                     $lastPercent = -1
@@ -138,9 +194,10 @@ foreach ($FamilyKey in $DistroCatalog.Keys) {
                             
                             if ($totalBytes -gt 0) {
                                 $percent = [Math]::Floor(($totalRead / $totalBytes) * 100)
-                                if ($percent -gt $lastPercent -and $percent % 5 -eq 0) {
+                                if ($percent -gt $lastPercent) {
                                     $mbRed = "{0:N2}" -f ($totalRead / 1MB)
                                     $mbTotal = "{0:N2}" -f ($totalBytes / 1MB)
+                                    Write-ProgressEvent -Stage "download" -Percent $percent -Message "$($Version.Name): $mbRed MB / $mbTotal MB"
                                     $msg = "    Progress: $percent% ($mbRed MB / $mbTotal MB)"
                                     if ($percent % 20 -eq 0) { Log-Message $msg }
                                     $lastPercent = $percent
@@ -152,24 +209,47 @@ foreach ($FamilyKey in $DistroCatalog.Keys) {
                         $stream.Close()
                         $httpClient.Dispose()
                     }
-                    $FileExists = $true
                     Log-Message "    Download Completed."
+
+                    $Hash = (Get-FileHash -Path $PartFile -Algorithm SHA256).Hash.ToLower()
+                    $BlobDir = Join-Path (Join-Path $BaseDir "sha256") $Hash
+                    $Existing = Get-ChildItem -Path $BlobDir -File -ErrorAction SilentlyContinue | Select-Object -First 1
+                    if ($Existing) {
+                        # Same content already stored under another name or URL
+                        Remove-Item $PartFile -Force
+                        $OutFile = $Existing.FullName
+                        Log-Message "    Identical content already cached: $OutFile"
+                    } else {
+                        New-Item -ItemType Directory -Path $BlobDir -Force | Out-Null
+                        $OutFile = Join-Path $BlobDir $Version.Filename
+                        Move-Item -Path $PartFile -Destination $OutFile -Force
+                    }
+                    Update-CacheIndex -Hash $Hash -Url $Version.Url -Filename $Version.Filename -Size (Get-Item $OutFile).Length
                 } else {
                     throw "HTTP Status: $($response.StatusCode)"
                 }
             } catch {
                 Log-Message " -> Failed: $_" "ERROR"
-                if (Test-Path $OutFile) { Remove-Item $OutFile }
+                if (Test-Path $PartFile) { Remove-Item $PartFile }
+                $OutFile = $null
             }
         }
 
-        # Update Config if path changed or is new
-        if ($FileExists) {
-            $CurrentSavedPath = $ConfigRaw.$FamilyKey.Versions.$VerKey.LocalPath
-            if ($CurrentSavedPath -ne $OutFile) {
-                # We need to assign to the PSObject
-                $ConfigRaw.$FamilyKey.Versions.$VerKey | Add-Member -MemberType NoteProperty -Name "LocalPath" -Value $OutFile -Force
-                $ConfigChanged = $true
+        # Point every catalog entry with this URL at the stored file
+        if ($OutFile) {
+            foreach ($fk in $ConfigRaw.PSObject.Properties.Name) {
+                $fam = $ConfigRaw.$fk
+                if (-not $fam.PSObject.Properties['Versions']) { continue }
+                foreach ($vk in $fam.Versions.PSObject.Properties.Name) {
+                    $v = $fam.Versions.$vk
+                    $IsTarget = ("$fk" -eq "$FamilyKey" -and "$vk" -eq "$VerKey")
+                    if (-not $IsTarget -and $v.Url -ne $Version.Url) { continue }
+                    if ($v.LocalPath -ne $OutFile -or ($Hash -and $v.Sha256 -ne $Hash)) {
+                        $v | Add-Member -MemberType NoteProperty -Name "LocalPath" -Value $OutFile -Force
+                        if ($Hash) { $v | Add-Member -MemberType NoteProperty -Name "Sha256" -Value $Hash -Force }
+                        $ConfigChanged = $true
+                    }
+                }
             }
         }
     }
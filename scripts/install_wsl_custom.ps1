@@ -6,6 +6,13 @@ param (
     [string]$InstallPath,
     [string]$SelectFamily,
     [string]$SelectVersion,
+    # Local file or URL of the package to install: a custom package, or a catalog package
+    # the GUI already unpacked (the selection then only names the release)
+    [string]$PackageSource,
+    # PackageSource is a VHDX disk, imported with --vhd
+    [switch]$Vhd,
+    # The GUI already wrote the user, password and wsl.conf into PackageSource
+    [switch]$Preconfigured,
     [string]$name,
     [string]$user,
     [string]$pass,
@@ -196,6 +203,13 @@ if ($SelectFamily) {
     }
 }
 
+# --- Package Given Directly ---
+# Takes precedence over the catalog download
+if ($PackageSource) {
+    $DownloadUrl = $PackageSource
+    Log-Message "Package: $PackageSource"
+}
+
 if (-not $DownloadUrl) {
     # Select Family
     Show-Menu -Title "Select Distribution Family" -Options $DistroCatalog
@@ -269,13 +283,24 @@ $TempDir = Join-Path $env:TEMP "WSL_Install_$(Get-Random)"
 New-Item -ItemType Directory -Force -Path $TempDir | Out-Null
 Log-Message "Preparing workspace at $TempDir..."
 
+# Announce the stages so the GUI can render a determinate progress bar
+$InstallStages = @("download", "extract", "import")
+if ($user -and -not $Preconfigured) { $InstallStages += "create_user" }
+Write-ProgressEvent -Stage "download" -Percent 0 -Message "Checking package cache..." -Stages $InstallStages
+
 try {
     # 1. Acquire Package (Cache Check)
     $CachedFile = $null
     $SourcePath = $null
     
+    # Priority 0: Package given as a local file (already unpacked by the GUI)
+    if ($PackageSource -and (Test-Path -LiteralPath $PackageSource -PathType Leaf)) {
+        $SourcePath = $PackageSource
+        Log-Message "Using package: $SourcePath"
+    }
+
     # Priority 1: Check LocalPath from Config
-    if ($SelectedVersion.LocalPath) {
+    if (-not $SourcePath -and $SelectedVersion.LocalPath) {
         if (Test-Path $SelectedVersion.LocalPath) {
             $SourcePath = $SelectedVersion.LocalPath
             Log-Message "Using registered local copy: $SourcePath"
@@ -329,7 +354,10 @@ try {
 
     $ProcessingFile = Join-Path $TempDir $SourceFileName
     
-    if ($CachedFile) {
+    if ($CachedFile -and $CachedFile -eq $PackageSource) {
+        # Prepared by the GUI in its own work folder (or a user file): use it in place
+        $ProcessingFile = $CachedFile
+    } elseif ($CachedFile) {
         Log-Message "Copying to workspace..."
         Copy-Item $CachedFile $ProcessingFile
     } else {
@@ -337,11 +365,16 @@ try {
         Log-Message "Downloading from URL..."
         Invoke-WebRequest -Uri $DownloadUrl -OutFile $ProcessingFile -UseBasicParsing
     }
+    Write-ProgressEvent -Stage "download" -Percent 100 -Message "Package ready"
     
     $RootFs = $null
+    Write-ProgressEvent -Stage "extract" -Percent -1 -Message "Preparing root filesystem..."
 
     # Check for known Archive types that need extraction (Appx, Zip)
-    if ($ProcessingFile -match "\.(zip|appx|appxbundle)$") {
+    if ($Vhd) {
+        Log-Message "Package is a VHDX disk"
+        $RootFs = $ProcessingFile
+    } elseif ($ProcessingFile -match "\.(zip|appx|appxbundle|msix|msixbundle)$") {
         # 2. Extract 
         Log-Message "Extracting package..."
         $ExtractDir = Join-Path $TempDir "extracted"
@@ -353,7 +386,7 @@ try {
         
         if (-not (Test-Path $RootFs)) {
             # Check 2: It's a bundle, find the x64 appx
-            $AppxFile = Get-ChildItem -Path $ExtractDir -Filter "*x64*.appx" -Recurse | Select-Object -First 1
+            $AppxFile = Get-ChildItem -Path $ExtractDir -Recurse | Where-Object { $_.Name -match "x64.*\.(appx|msix)$" } | Select-Object -First 1
             
             if ($AppxFile) {
                 Log-Message "Found inner package: $($AppxFile.Name)"
@@ -375,6 +408,8 @@ try {
         throw "Could not find 'install.tar.gz' or valid RootFS in the package."
     }
 
+    Write-ProgressEvent -Stage "extract" -Percent 100 -Message "Root filesystem ready"
+
     # 4. Create Install Directory
     if (-not (Test-Path $InstallPath)) {
         New-Item -ItemType Directory -Force -Path $InstallPath | Out-Null
@@ -382,11 +417,20 @@ try {
 
     # 5. Import into WSL
     Log-Message "Registering '$DistroName'..."
-    wsl --import $DistroName $InstallPath $RootFs --version 2
+    Write-ProgressEvent -Stage "import" -Percent -1 -Message "Importing into WSL (this may take a while)..."
+    if ($Vhd) {
+        wsl --import $DistroName $InstallPath $RootFs --vhd
+    } else {
+        wsl --import $DistroName $InstallPath $RootFs --version 2
+    }
+    Write-ProgressEvent -Stage "import" -Percent 100 -Message "Imported '$DistroName'"
 
-    # 6. User Setup (if requested)
-    if ($user) {
+    # 6. User Setup (if requested and not already done offline)
+    if ($Preconfigured) {
+        Log-Message "User and configuration were written before import."
+    } elseif ($user) {
         Log-Message "Setting up user '$user'..."
+        Write-ProgressEvent -Stage "create_user" -Percent -1 -Message "Creating user '$user'..."
         
         # Create user
         # Note: Using 'exec' to run commands directly inside the distro
@@ -410,6 +454,7 @@ try {
         wsl -d $DistroName -u root -- exec sh -c "printf '[user]\ndefault=$user\n' > /etc/wsl.conf"
         
         Log-Message "User '$user' configured as default."
+        Write-ProgressEvent -Stage "create_user" -Percent 100 -Message "User '$user' configured"
         
         # Terminate to ensure next start picks up the config? Defaults usually apply on next session.
         wsl --terminate $DistroName
@@ -89,12 +89,19 @@ function Get-WslDistros {
         $Release = ""
         $User = ""
         $InstallTime = ""
+        # User-maintained metadata, only ever written by the GUI
+        $Tags = @()
+        $Note = ""
+        $Owner = ""
         
         if (-not $IsNew) {
             # Use cached values
             $Release = $CachedItem.Release
             $User = $CachedItem.User
             $InstallTime = $CachedItem.InstallTime
+            if ($CachedItem.Tags) { $Tags = @($CachedItem.Tags) }
+            if ($CachedItem.Note) { $Note = $CachedItem.Note }
+            if ($CachedItem.Owner) { $Owner = $CachedItem.Owner }
         }
 
         # Initialize InstallTime if missing
@@ -184,6 +191,9 @@ function Get-WslDistros {
             User        = $User
             InstallTime = $InstallTime
             DiskSize    = $DiskSize
+            Tags        = $Tags
+            Note        = $Note
+            Owner       = $Owner
         }
         
         $CurrentDistros += $DistroObj
@@ -199,7 +209,7 @@ function Get-WslDistros {
     
     # Save Cache if needed
     if ($CacheChanged) {
-        $JsonData = $CurrentDistros | ConvertTo-Json -Depth 2
+        $JsonData = $CurrentDistros | ConvertTo-Json -Depth 4
         Set-Content -Path $CacheFile -Value $JsonData -Encoding UTF8
     }
 
@@ -207,4 +217,4 @@ function Get-WslDistros {
 }
 
 $Available = Get-WslDistros
-$Available | ConvertTo-Json -Depth 2
\ No newline at end of file
+$Available | ConvertTo-Json -Depth 4
\ No newline at end of file
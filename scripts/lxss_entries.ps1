@@ -0,0 +1,50 @@
+# PowerShell script to list or remove raw WSL registry (Lxss) entries
+# Usage: ./lxss_entries.ps1                 -> JSON array of all entries
+#        ./lxss_entries.ps1 -Remove "{guid}" -> deletes one entry (does not touch files)
+
+param(
+    [string]$Remove
+)
+
+$ErrorActionPreference = "Stop"
+[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+
+# --- Logging Setup ---
+. "$PSScriptRoot\pwsh_utils.ps1"
+Setup-Logger -LogFileName "orphans.log"
+
+$LxssPath = "HKCU:\Software\Microsoft\Windows\CurrentVersion\Lxss"
+
+if ($Remove) {
+    $KeyPath = Join-Path $LxssPath $Remove
+    if (-not (Test-Path $KeyPath)) {
+        $msg = "Registry entry $Remove not found."
+        Log-Message $msg "ERROR"
+        Write-Error $msg
+        exit 1
+    }
+    $Name = (Get-ItemProperty -Path $KeyPath).DistributionName
+    Log-Message "Removing registry entry $Remove ($Name)..."
+    Remove-Item -Path $KeyPath -Recurse -Force
+    Log-Message "Registry entry $Remove removed."
+    exit 0
+}
+
+$Entries = @()
+if (Test-Path $LxssPath) {
+    foreach ($Key in Get-ChildItem -Path $LxssPath) {
+        $Props = Get-ItemProperty -Path $Key.PSPath
+        $VhdFile = $Props.VhdFileName
+        if (-not $VhdFile) { $VhdFile = "ext4.vhdx" }
+        $Entries += [ordered]@{
+            Id          = $Key.PSChildName
+            Name        = "$($Props.DistributionName)"
+            BasePath    = "$($Props.BasePath)"
+            Version     = [int]$Props.Version
+            VhdFileName = $VhdFile
+        }
+    }
+}
+
+Log-Message "Listed $($Entries.Count) registry entries" -FileOnly
+ConvertTo-Json -InputObject @($Entries) -Depth 2
@@ -50,6 +50,7 @@ $TempExport = Join-Path $NewPath "export_temp.tar"
 
 try {
     # 1. Export
+    Write-ProgressEvent -Stage "export" -Percent -1 -Message "Exporting '$DistroName'..." -Stages @("export", "import", "cleanup")
     Log-Message "Exporting instance (this may take time)..."
     wsl --terminate $DistroName
     wsl --export $DistroName $TempExport
@@ -72,6 +73,7 @@ try {
     wsl --unregister $DistroName
 
     # 4. Import to new location
+    Write-ProgressEvent -Stage "import" -Percent -1 -Message "Importing to $NewPath..."
     Log-Message "Importing to new location..."
     wsl --import $DistroName $NewPath $TempExport --version 2
 
@@ -89,11 +91,13 @@ try {
     }
 
     # 6. Cleanup
+    Write-ProgressEvent -Stage "cleanup" -Percent -1 -Message "Removing temporary export..."
     Remove-Item $TempExport -Force
     
     # 7. Update Registry
     & "$PSScriptRoot\scan_wsl_instances.ps1"
 
+    Write-ProgressEvent -Stage "cleanup" -Percent 100 -Message "Move complete"
     Log-Message "Move complete."
 
 } catch {
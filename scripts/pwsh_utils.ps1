@@ -78,3 +78,25 @@ function Log-Message {
         }
     }
 }
+
+# Emits a machine-readable progress line for the GUI:
+#   ::progress::{"stage":"download","percent":42,"message":"..."}
+# Percent is the completion of the current stage (0-100) or -1 when unknown.
+# Pass -Stages once at the start of an operation to announce the full stage list.
+function Write-ProgressEvent {
+    param(
+        [Parameter(Mandatory=$true)]
+        [string]$Stage,
+        [double]$Percent = -1,
+        [string]$Message = "",
+        [string[]]$Stages
+    )
+    $ProgressData = [ordered]@{
+        stage   = $Stage
+        percent = $Percent
+        message = $Message
+    }
+    if ($Stages) { $ProgressData.stages = @($Stages) }
+    $Json = $ProgressData | ConvertTo-Json -Compress
+    Write-Host "::progress::$Json"
+}
@@ -100,6 +100,7 @@ try {
     $ConfigPath = Join-Path $PSScriptRoot "..\config\instances.json"
     $User = "root"
     $Release = "Custom"
+    $Instance = $null
     if (Test-Path $ConfigPath) {
         $Json = Get-Content $ConfigPath -Raw | ConvertFrom-Json
         $Instance = $Json | Where-Object { $_.Name -eq $OldName }
@@ -147,6 +148,12 @@ try {
             User = $User
             InstallTime = (Get-Date).ToString("yyyy-MM-dd HH:mm:ss")
         }
+        # Tags, note and owner follow the instance to its new name
+        if ($Instance) {
+            if ($Instance.Tags) { $NewObj.Tags = @($Instance.Tags) }
+            if ($Instance.Note) { $NewObj.Note = $Instance.Note }
+            if ($Instance.Owner) { $NewObj.Owner = $Instance.Owner }
+        }
         $Json += $NewObj
         
         $Json | ConvertTo-Json -Depth 4 | Set-Content $ConfigPath -Force
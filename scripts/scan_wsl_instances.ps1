@@ -77,7 +77,7 @@ if (Test-Path $LxssPath) {
     }
 }
 
-# 2. Merge with existing config to preserve manual metadata (Release, User)
+# 2. Merge with existing config to preserve manual metadata (Release, User, Tags, Note, Owner)
 $ExistingData = @()
 if (Test-Path $ConfigPath) {
     try {
@@ -93,6 +93,9 @@ foreach ($d in $Distros) {
     if ($Match) {
         if ($Match.Release) { $d.Release = $Match.Release }
         if ($Match.User) { $d.User = $Match.User }
+        if ($Match.Tags) { $d.Tags = @($Match.Tags) }
+        if ($Match.Note) { $d.Note = $Match.Note }
+        if ($Match.Owner) { $d.Owner = $Match.Owner }
     }
     
     # If still unknown, maybe categorize by name or leave as Unknown
@@ -74,6 +74,7 @@ foreach ($FamilyName in $JsonContent.ModernDistributions.PSObject.Properties.Nam
 
         # Try to find existing entry to preserve LocalPath
         $LocalPath = $null
+        $Sha256 = $null
         if ($ExistingConfig) {
              # Naive search: Match by Filename AND Url
              # Since structure changed to ID-based, we iterate deeply
@@ -85,6 +86,7 @@ foreach ($FamilyName in $JsonContent.ModernDistributions.PSObject.Properties.Nam
                      if ($eVer.Url -eq $AmdUrl -or $eVer.Filename -eq $Filename) {
                          if ($eVer.LocalPath) {
                              $LocalPath = $eVer.LocalPath
+                             $Sha256 = $eVer.Sha256
                          }
                      }
                  }
@@ -101,6 +103,7 @@ foreach ($FamilyName in $JsonContent.ModernDistributions.PSObject.Properties.Nam
         
         if ($LocalPath) {
             $NexusVer["LocalPath"] = $LocalPath
+            if ($Sha256) { $NexusVer["Sha256"] = $Sha256 }
         }
 
         $NexusVersions["$Counter"] = $NexusVer
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"distronexus-gui/internal/applog"
 	"distronexus-gui/internal/ui"
+	"log/slog"
 	"os"
 	"path/filepath"
 
@@ -33,6 +35,12 @@ func main() {
 		}
 	}
 
+	if err := applog.Setup(projectRoot); err != nil {
+		slog.Warn("file logging unavailable", "error", err)
+	}
+	defer applog.Close()
+	slog.Info("DistroNexus starting", "projectRoot", projectRoot)
+
 	mw := ui.NewMainWindow(a, projectRoot)
 	mw.Init()
 
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"distronexus-gui/internal/logic"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Event types sent on the /api/v1/events stream
+const (
+	EventInstanceState = "instance.state"
+	EventJobStarted    = "job.started"
+	EventJobLog        = "job.log"
+	EventJobProgress   = "job.progress"
+	EventJobFinished   = "job.finished"
+	// EventResync tells a resuming client that events it missed are no longer buffered
+	EventResync = "resync"
+)
+
+const (
+	eventBufferSize   = 1024
+	subscriberBacklog = 256
+	heartbeatInterval = 15 * time.Second
+)
+
+type streamEvent struct {
+	ID   uint64
+	Type string
+	Data []byte
+}
+
+type instanceStateData struct {
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	Previous string `json:"previous,omitempty"`
+}
+
+type jobLogData struct {
+	JobID    string `json:"jobId"`
+	Instance string `json:"instance,omitempty"`
+	Line     string `json:"line"`
+}
+
+type jobProgressData struct {
+	JobID    string              `json:"jobId"`
+	Instance string              `json:"instance,omitempty"`
+	Progress logic.ProgressEvent `json:"progress"`
+}
+
+// eventHub numbers events from the job manager and state watcher, keeps the most
+// recent ones for Last-Event-ID resumption and fans them out to stream clients
+type eventHub struct {
+	mu     sync.Mutex
+	seq    uint64
+	buf    []streamEvent
+	subs   map[chan streamEvent]struct{}
+	closed chan struct{}
+	detach []func()
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{
+		subs:   make(map[chan streamEvent]struct{}),
+		closed: make(chan struct{}),
+	}
+}
+
+// attach starts forwarding events from the GUI's sources
+func (h *eventHub) attach(jobs *logic.JobManager, watcher *logic.StateWatcher) {
+	if jobs != nil {
+		h.detach = append(h.detach, jobs.Subscribe(func(ev logic.JobEvent) {
+			switch ev.Type {
+			case logic.JobStarted:
+				h.publish(EventJobStarted, newJobView(ev.Job, false))
+			case logic.JobLogLine:
+				h.publish(EventJobLog, jobLogData{JobID: ev.Job.ID, Instance: ev.Job.Instance, Line: ev.Line})
+			case logic.JobProgress:
+				h.publish(EventJobProgress, jobProgressData{JobID: ev.Job.ID, Instance: ev.Job.Instance, Progress: ev.Progress})
+			case logic.JobFinished:
+				h.publish(EventJobFinished, newJobView(ev.Job, false))
+			}
+		}))
+	}
+	if watcher != nil {
+		h.detach = append(h.detach, watcher.Subscribe(func(ev logic.InstanceStateEvent) {
+			h.publish(EventInstanceState, instanceStateData{Name: ev.Name, State: ev.State, Previous: ev.Previous})
+		}))
+	}
+}
+
+// close unsubscribes from the sources and ends every open stream
+func (h *eventHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	select {
+	case <-h.closed:
+		return
+	default:
+	}
+	for _, fn := range h.detach {
+		fn()
+	}
+	close(h.closed)
+}
+
+func (h *eventHub) publish(typ string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Debug("failed to encode stream event", "type", typ, "error", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	ev := streamEvent{ID: h.seq, Type: typ, Data: data}
+	h.buf = append(h.buf, ev)
+	if len(h.buf) > eventBufferSize {
+		h.buf = h.buf[len(h.buf)-eventBufferSize:]
+	}
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+			// Too slow to keep up: drop it; the client reconnects with Last-Event-ID
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe returns the buffered events after lastID and a channel for new ones.
+// gap is true when events after lastID have already been evicted from the buffer.
+func (h *eventHub) subscribe(lastID uint64, resume bool) (backlog []streamEvent, ch chan streamEvent, gap bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if resume {
+		if len(h.buf) > 0 && h.buf[0].ID > lastID+1 {
+			gap = true
+		}
+		for _, ev := range h.buf {
+			if ev.ID > lastID {
+				backlog = append(backlog, ev)
+			}
+		}
+	}
+	ch = make(chan streamEvent, subscriberBacklog)
+	h.subs[ch] = struct{}{}
+	return backlog, ch, gap
+}
+
+func (h *eventHub) unsubscribe(ch chan streamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// handleEvents streams events as Server-Sent Events.
+// Clients resume with the Last-Event-ID header (or ?lastEventId=) after reconnecting.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	hub := s.hub()
+	if hub == nil {
+		writeError(w, http.StatusServiceUnavailable, "event stream not running")
+		return
+	}
+
+	lastRaw := r.Header.Get("Last-Event-ID")
+	if lastRaw == "" {
+		lastRaw = r.URL.Query().Get("lastEventId")
+	}
+	var lastID uint64
+	resume := false
+	if lastRaw != "" {
+		id, err := strconv.ParseUint(lastRaw, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+		lastID, resume = id, true
+	}
+
+	backlog, ch, gap := hub.subscribe(lastID, resume)
+	defer hub.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Tell EventSource clients how long to wait before reconnecting
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if gap {
+		fmt.Fprintf(w, "event: %s\ndata: {\"lastEventId\":%d}\n\n", EventResync, lastID)
+	}
+	for _, ev := range backlog {
+		writeStreamEvent(w, ev)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-hub.closed:
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeStreamEvent(w, ev)
+			flusher.Flush()
+		}
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, ev streamEvent) {
+	// json.Marshal never emits raw newlines, so one data line is enough
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, ev.Data)
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"context"
+	"distronexus-gui/internal/logic"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// jobView is the JSON shape of a job
+type jobView struct {
+	ID         string               `json:"id"`
+	Title      string               `json:"title"`
+	Instance   string               `json:"instance,omitempty"`
+	Status     logic.JobStatus      `json:"status"`
+	Started    time.Time            `json:"started"`
+	Finished   *time.Time           `json:"finished,omitempty"`
+	DurationMs int64                `json:"durationMs"`
+	Error      string               `json:"error,omitempty"`
+	Progress   *logic.ProgressEvent `json:"progress,omitempty"`
+	Logs       string               `json:"logs,omitempty"`
+}
+
+func newJobView(j *logic.Job, withLogs bool) jobView {
+	v := jobView{
+		ID:         j.ID,
+		Title:      j.Title,
+		Instance:   j.Instance,
+		Status:     j.Status(),
+		Started:    j.Started,
+		DurationMs: j.Duration().Milliseconds(),
+	}
+	if f := j.Finished(); !f.IsZero() {
+		v.Finished = &f
+	}
+	if err := j.Err(); err != nil {
+		v.Error = err.Error()
+	}
+	if p, ok := j.Progress(); ok {
+		v.Progress = &p
+	}
+	if withLogs {
+		v.Logs = j.Logs()
+	}
+	return v
+}
+
+type installRequest struct {
+	Family   string `json:"family"`
+	Version  string `json:"version"`
+	Name     string `json:"name"`
+	Path     string `json:"path,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type backupRequest struct {
+	OutputFile string `json:"outputFile,omitempty"`
+}
+
+func (s *Server) handleListInstances(w http.ResponseWriter, r *http.Request) {
+	distros, err := logic.ListDistros(s.ProjectRoot, false)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if distros == nil {
+		distros = []logic.WslInstance{}
+	}
+	writeJSON(w, http.StatusOK, distros)
+}
+
+func (s *Server) handleGetInstance(w http.ResponseWriter, r *http.Request) {
+	inst, ok := s.findInstance(w, r.PathValue("name"))
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, inst)
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	inst, ok := s.findInstance(w, r.PathValue("name"))
+	if !ok {
+		return
+	}
+	s.startJob(w, "Starting "+inst.Name, inst.Name, func(ctx context.Context, log func(string)) error {
+		return logic.StartDistro(ctx, s.ProjectRoot, inst.Name, false, "")
+	})
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	inst, ok := s.findInstance(w, r.PathValue("name"))
+	if !ok {
+		return
+	}
+	s.startJob(w, "Stopping "+inst.Name, inst.Name, func(ctx context.Context, log func(string)) error {
+		return logic.StopDistro(ctx, s.ProjectRoot, inst.Name, log)
+	})
+}
+
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	inst, ok := s.findInstance(w, r.PathValue("name"))
+	if !ok {
+		return
+	}
+	var req backupRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	out := req.OutputFile
+	if out == "" {
+		out = logic.DefaultBackupFile(s.ProjectRoot, inst.Name)
+	}
+	s.startJob(w, "Backing up "+inst.Name, inst.Name, func(ctx context.Context, log func(string)) error {
+		return logic.BackupDistro(ctx, s.ProjectRoot, inst.Name, out, log)
+	})
+}
+
+func (s *Server) handleInstall(w http.ResponseWriter, r *http.Request) {
+	var req installRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Family == "" || req.Version == "" || req.Name == "" {
+		writeError(w, http.StatusBadRequest, "family, version and name are required")
+		return
+	}
+	if err := logic.ValidateDistroName(req.Name); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	distros, err := s.Config.LoadDistros()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	fam, ok := distros[req.Family]
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown family '%s'", req.Family))
+		return
+	}
+	ver, ok := fam.Versions[req.Version]
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown version '%s' for family '%s'", req.Version, req.Family))
+		return
+	}
+
+	if registered, err := logic.IsDistroRegistered(s.ProjectRoot, req.Name); err == nil && registered {
+		writeError(w, http.StatusConflict, fmt.Sprintf("instance '%s' already exists", req.Name))
+		return
+	}
+
+	settings, err := s.Config.LoadSettings()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// Same defaults as the GUI quick mode: settings install path, root user
+	path := req.Path
+	if path == "" {
+		path = filepath.Join(settings.DefaultInstallPath, req.Name)
+	}
+	if err := logic.ValidateInstallPath(path); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	user := req.User
+	if user == "" {
+		user = "root"
+	}
+
+	s.startJob(w, "Installing "+req.Family+" "+req.Version, req.Name, func(ctx context.Context, log func(string)) error {
+		// Fetched into the cache first so the package is configured locally before import
+		packagePath := ver.LocalPath
+		if packagePath == "" {
+			if err := logic.DownloadDistroOnly(ctx, s.ProjectRoot, req.Family, req.Version, log); err != nil {
+				return err
+			}
+			if distros, err := s.Config.LoadDistros(); err == nil {
+				packagePath = distros[req.Family].Versions[req.Version].LocalPath
+			}
+		}
+		resCh := make(chan error, 1)
+		logic.RunInstallScript(ctx, s.ProjectRoot, req.Family, req.Version, packagePath, req.Name, path, user, req.Password, settings.Provisioning, log, func(e error) {
+			resCh <- e
+		})
+		return <-resCh
+	})
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	views := []jobView{}
+	for _, j := range s.Jobs.Jobs() {
+		views = append(views, newJobView(j, false))
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job := s.Jobs.Get(r.PathValue("id"))
+	if job == nil {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, newJobView(job, r.URL.Query().Get("logs") == "true"))
+}
+
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	job := s.Jobs.Get(r.PathValue("id"))
+	if job == nil {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	if job.Status() == logic.JobRunning {
+		job.Cancel()
+	}
+	writeJSON(w, http.StatusAccepted, newJobView(job, false))
+}
+
+// startJob runs fn through the shared JobManager and answers 202 with the job
+func (s *Server) startJob(w http.ResponseWriter, title, instance string, fn logic.JobFunc) {
+	job := s.Jobs.StartFor(title, instance, fn)
+	go func() {
+		<-job.Done()
+		if s.OnChange != nil {
+			s.OnChange()
+		}
+	}()
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, newJobView(job, false))
+}
+
+// findInstance looks the instance up and writes a 404 if it doesn't exist
+func (s *Server) findInstance(w http.ResponseWriter, name string) (logic.WslInstance, bool) {
+	distros, err := logic.ListDistros(s.ProjectRoot, false)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return logic.WslInstance{}, false
+	}
+	for _, d := range distros {
+		if strings.EqualFold(d.Name, name) {
+			return d, true
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Sprintf("instance '%s' not found", name))
+	return logic.WslInstance{}, false
+}
+
+// decodeBody parses an optional JSON body; an empty body leaves v untouched
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"distronexus-gui/internal/logic"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metricsTTL limits how often a scrape re-lists instances and walks the cache directory
+const metricsTTL = 30 * time.Second
+
+// metricsSnapshot holds the gauges that are expensive to collect
+type metricsSnapshot struct {
+	taken      time.Time
+	states     map[string]int
+	diskBytes  map[string]int64
+	cacheBytes int64
+	cacheOK    bool
+	listOK     bool
+}
+
+// SetMetricsEnabled turns the /metrics endpoint on or off
+func (s *Server) SetMetricsEnabled(enabled bool) {
+	s.metricsEnabled.Store(enabled)
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if !s.metricsEnabled.Load() {
+		writeError(w, http.StatusNotFound, "metrics are disabled in settings")
+		return
+	}
+	snap := s.metricsSnapshot()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, snap, logic.OperationStats(), len(s.Jobs.Active()))
+}
+
+func (s *Server) metricsSnapshot() metricsSnapshot {
+	s.metricsMu.Lock()
+	defer s.metricsMu.Unlock()
+	if time.Since(s.lastMetrics.taken) < metricsTTL {
+		return s.lastMetrics
+	}
+
+	snap := metricsSnapshot{
+		taken:     time.Now(),
+		states:    make(map[string]int),
+		diskBytes: make(map[string]int64),
+	}
+	if distros, err := logic.ListDistros(s.ProjectRoot, false); err != nil {
+		slog.Debug("metrics: listing instances failed", "error", err)
+	} else {
+		snap.listOK = true
+		for _, d := range distros {
+			snap.states[d.State]++
+			if size, err := logic.GetDistroSizeBytes(d.BasePath); err == nil {
+				snap.diskBytes[d.Name] = size
+			}
+		}
+	}
+	if s.Config != nil {
+		if settings, err := s.Config.LoadSettings(); err == nil {
+			size, err := logic.DirSize(s.Config.ResolveCachePath(settings.DistroCachePath))
+			snap.cacheBytes, snap.cacheOK = size, err == nil
+		}
+	}
+	s.lastMetrics = snap
+	return snap
+}
+
+// writeMetrics renders the Prometheus text exposition format
+func writeMetrics(w io.Writer, snap metricsSnapshot, ops []logic.OperationStat, runningJobs int) {
+	header := func(name, typ, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+
+	header("distronexus_instance_list_ok", "gauge", "1 if the last instance listing succeeded.")
+	fmt.Fprintf(w, "distronexus_instance_list_ok %d\n", boolMetric(snap.listOK))
+
+	header("distronexus_instances", "gauge", "Registered WSL instances by state.")
+	for _, state := range sortedKeys(snap.states) {
+		fmt.Fprintf(w, "distronexus_instances{state=%s} %d\n", labelValue(state), snap.states[state])
+	}
+
+	header("distronexus_instance_disk_bytes", "gauge", "Size of the instance ext4.vhdx in bytes.")
+	for _, name := range sortedKeys(snap.diskBytes) {
+		fmt.Fprintf(w, "distronexus_instance_disk_bytes{instance=%s} %d\n", labelValue(name), snap.diskBytes[name])
+	}
+
+	if snap.cacheOK {
+		header("distronexus_cache_bytes", "gauge", "Total size of the distro package cache in bytes.")
+		fmt.Fprintf(w, "distronexus_cache_bytes %d\n", snap.cacheBytes)
+	}
+
+	header("distronexus_jobs_running", "gauge", "Operations currently running.")
+	fmt.Fprintf(w, "distronexus_jobs_running %d\n", runningJobs)
+
+	header("distronexus_operations_total", "counter", "Finished operations by type and result since startup.")
+	for _, op := range ops {
+		fmt.Fprintf(w, "distronexus_operations_total{operation=%s,result=%s} %d\n", labelValue(op.Operation), labelValue(op.Result), op.Count)
+	}
+
+	header("distronexus_operation_duration_seconds", "histogram", "Duration of finished operations by type and result.")
+	for _, op := range ops {
+		labels := fmt.Sprintf("operation=%s,result=%s", labelValue(op.Operation), labelValue(op.Result))
+		for i, bound := range logic.OperationDurationBuckets {
+			fmt.Fprintf(w, "distronexus_operation_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, strconv.FormatFloat(bound, 'g', -1, 64), op.Buckets[i])
+		}
+		fmt.Fprintf(w, "distronexus_operation_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, op.Count)
+		fmt.Fprintf(w, "distronexus_operation_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(op.Sum, 'g', -1, 64))
+		fmt.Fprintf(w, "distronexus_operation_duration_seconds_count{%s} %d\n", labels, op.Count)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labelValue(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+func boolMetric(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"bufio"
+	"distronexus-gui/internal/logic"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// cumulative builds an OperationStat whose buckets hold the given number of
+// observations per OperationDurationBuckets bound (not yet cumulative)
+func cumulative(op, result string, perBucket []uint64, overflow uint64, sum float64) logic.OperationStat {
+	st := logic.OperationStat{Operation: op, Result: result, Sum: sum, Buckets: make([]uint64, len(logic.OperationDurationBuckets))}
+	var running uint64
+	for i := range st.Buckets {
+		if i < len(perBucket) {
+			running += perBucket[i]
+		}
+		st.Buckets[i] = running
+	}
+	st.Count = running + overflow
+	return st
+}
+
+func TestWriteMetrics(t *testing.T) {
+	snap := metricsSnapshot{
+		listOK:     true,
+		states:     map[string]int{"Running": 2, "Stopped": 1},
+		diskBytes:  map[string]int64{`odd"name\with` + "\nnewline": 42, "Ubuntu": 1 << 30},
+		cacheBytes: 1234,
+		cacheOK:    true,
+	}
+	ops := []logic.OperationStat{
+		cumulative("install", "success", []uint64{0, 0, 0, 1, 2}, 1, 400),
+		cumulative("start", "failure", []uint64{3}, 0, 1.5),
+	}
+
+	var b strings.Builder
+	writeMetrics(&b, snap, ops, 2)
+	out := b.String()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"list ok", "distronexus_instance_list_ok 1\n"},
+		{"states sorted", "distronexus_instances{state=\"Running\"} 2\ndistronexus_instances{state=\"Stopped\"} 1\n"},
+		{"label escaping", `distronexus_instance_disk_bytes{instance="odd\"name\\with\nnewline"} 42` + "\n"},
+		{"cache", "distronexus_cache_bytes 1234\n"},
+		{"jobs", "distronexus_jobs_running 2\n"},
+		{"counter", `distronexus_operations_total{operation="install",result="success"} 4` + "\n"},
+		{"histogram bucket", `distronexus_operation_duration_seconds_bucket{operation="install",result="success",le="60"} 3` + "\n"},
+		{"histogram inf", `distronexus_operation_duration_seconds_bucket{operation="install",result="success",le="+Inf"} 4` + "\n"},
+		{"histogram sum", `distronexus_operation_duration_seconds_sum{operation="start",result="failure"} 1.5` + "\n"},
+		{"histogram count", `distronexus_operation_duration_seconds_count{operation="start",result="failure"} 3` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(out, tt.want) {
+				t.Fatalf("missing %q in:\n%s", tt.want, out)
+			}
+		})
+	}
+
+	t.Run("help and type", func(t *testing.T) {
+		checkHelpAndType(t, out, map[string]string{
+			"distronexus_instance_list_ok":           "gauge",
+			"distronexus_instances":                  "gauge",
+			"distronexus_instance_disk_bytes":        "gauge",
+			"distronexus_cache_bytes":                "gauge",
+			"distronexus_jobs_running":               "gauge",
+			"distronexus_operations_total":           "counter",
+			"distronexus_operation_duration_seconds": "histogram",
+		})
+	})
+
+	t.Run("buckets cumulative", func(t *testing.T) {
+		checkBuckets(t, out, ops)
+	})
+}
+
+func TestWriteMetricsOmitsUnknownCache(t *testing.T) {
+	var b strings.Builder
+	writeMetrics(&b, metricsSnapshot{}, nil, 0)
+	out := b.String()
+	if strings.Contains(out, "distronexus_cache_bytes") {
+		t.Fatalf("cache size reported although it could not be read:\n%s", out)
+	}
+	if !strings.Contains(out, "distronexus_instance_list_ok 0\n") {
+		t.Fatalf("failed listing not reported:\n%s", out)
+	}
+}
+
+// checkHelpAndType verifies that every family has HELP and TYPE lines, in that order,
+// before its first sample
+func checkHelpAndType(t *testing.T, out string, families map[string]string) {
+	t.Helper()
+	seen := map[string]int{} // 1 = HELP seen, 2 = TYPE seen
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "# HELP "):
+			if len(fields) < 4 {
+				t.Fatalf("HELP without text: %q", line)
+			}
+			seen[fields[2]] = 1
+		case strings.HasPrefix(line, "# TYPE "):
+			name := fields[2]
+			if seen[name] != 1 {
+				t.Fatalf("TYPE before HELP for %s", name)
+			}
+			if want, ok := families[name]; !ok || fields[3] != want {
+				t.Fatalf("TYPE %s is %q, want %q", name, fields[3], want)
+			}
+			seen[name] = 2
+		default:
+			name := strings.FieldsFunc(line, func(r rune) bool { return r == '{' || r == ' ' })[0]
+			family := name
+			for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+				if base, ok := strings.CutSuffix(name, suffix); ok && families[base] == "histogram" {
+					family = base
+				}
+			}
+			if seen[family] != 2 {
+				t.Fatalf("sample %q before HELP/TYPE of %s", line, family)
+			}
+		}
+	}
+	for name := range families {
+		if seen[name] != 2 {
+			t.Fatalf("no HELP/TYPE for %s", name)
+		}
+	}
+}
+
+// checkBuckets verifies that bucket values never decrease, that le="+Inf" equals _count
+// and that every configured bound is present
+func checkBuckets(t *testing.T, out string, ops []logic.OperationStat) {
+	t.Helper()
+	for _, op := range ops {
+		labels := `operation="` + op.Operation + `",result="` + op.Result + `"`
+		prefix := "distronexus_operation_duration_seconds_bucket{" + labels + ",le=\""
+		var values []uint64
+		var inf uint64
+		sc := bufio.NewScanner(strings.NewReader(out))
+		for sc.Scan() {
+			rest, ok := strings.CutPrefix(sc.Text(), prefix)
+			if !ok {
+				continue
+			}
+			le, value, _ := strings.Cut(rest, "\"} ")
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				t.Fatalf("bad bucket value in %q", sc.Text())
+			}
+			if le == "+Inf" {
+				inf = n
+				continue
+			}
+			if len(values) > 0 && n < values[len(values)-1] {
+				t.Fatalf("%s: bucket le=%s drops to %d", op.Operation, le, n)
+			}
+			values = append(values, n)
+		}
+		if len(values) != len(logic.OperationDurationBuckets) {
+			t.Fatalf("%s: %d buckets, want %d", op.Operation, len(values), len(logic.OperationDurationBuckets))
+		}
+		if inf != op.Count || inf < values[len(values)-1] {
+			t.Fatalf("%s: +Inf bucket %d, count %d, last bucket %d", op.Operation, inf, op.Count, values[len(values)-1])
+		}
+	}
+}
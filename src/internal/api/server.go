@@ -0,0 +1,203 @@
+// Package api serves the optional local HTTP/JSON automation API.
+// It drives the same logic functions and JobManager as the GUI, so jobs started
+// through the API show up in the Jobs view and vice versa.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"distronexus-gui/internal/config"
+	"distronexus-gui/internal/logic"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//go:embed openapi.json
+var openAPIDoc []byte
+
+// Server is the local automation API
+type Server struct {
+	ProjectRoot string
+	Jobs        *logic.JobManager
+	Config      *config.Loader
+	// Watcher, when set, feeds instance state changes into the event stream
+	Watcher *logic.StateWatcher
+
+	// OnChange is called after a job started through the API finishes,
+	// so the GUI can refresh its instance list
+	OnChange func()
+
+	mu     sync.Mutex
+	token  string
+	srv    *http.Server
+	addr   string
+	events *eventHub
+
+	metricsEnabled atomic.Bool
+	metricsMu      sync.Mutex
+	lastMetrics    metricsSnapshot
+}
+
+// NewServer creates an API server; call Start to begin listening
+func NewServer(projectRoot string, jobs *logic.JobManager, loader *config.Loader, token string) *Server {
+	return &Server{
+		ProjectRoot: projectRoot,
+		Jobs:        jobs,
+		Config:      loader,
+		token:       token,
+	}
+}
+
+// SetToken replaces the accepted token (e.g. after it was regenerated)
+func (s *Server) SetToken(token string) {
+	s.mu.Lock()
+	s.token = token
+	s.mu.Unlock()
+}
+
+// Handler returns the API routes. Everything except the OpenAPI document requires the token.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPIDoc)
+	})
+
+	api := http.NewServeMux()
+	api.HandleFunc("GET /api/v1/instances", s.handleListInstances)
+	api.HandleFunc("GET /api/v1/instances/{name}", s.handleGetInstance)
+	api.HandleFunc("POST /api/v1/instances/{name}/start", s.handleStart)
+	api.HandleFunc("POST /api/v1/instances/{name}/stop", s.handleStop)
+	api.HandleFunc("POST /api/v1/instances/{name}/backup", s.handleBackup)
+	api.HandleFunc("POST /api/v1/install", s.handleInstall)
+	api.HandleFunc("GET /api/v1/jobs", s.handleListJobs)
+	api.HandleFunc("GET /api/v1/jobs/{id}", s.handleGetJob)
+	api.HandleFunc("POST /api/v1/jobs/{id}/cancel", s.handleCancelJob)
+	api.HandleFunc("GET /api/v1/events", s.handleEvents)
+	mux.Handle("GET /metrics", s.requireToken(http.HandlerFunc(s.handleMetrics)))
+	mux.Handle("/api/", s.requireToken(api))
+
+	return mux
+}
+
+// Start listens on addr (loopback host:port or unix:<path>) and serves in the background
+func (s *Server) Start(addr string) error {
+	if addr == "" {
+		addr = config.DefaultApiListen
+	}
+	if err := config.ValidateApiListen(addr); err != nil {
+		return fmt.Errorf("invalid API address '%s': %w", addr, err)
+	}
+
+	var ln net.Listener
+	var err error
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		// A socket file left behind by a crash would make Listen fail
+		os.Remove(path)
+		ln, err = net.Listen("unix", path)
+	} else {
+		ln, err = net.Listen("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	hub := newEventHub()
+	hub.attach(s.Jobs, s.Watcher)
+
+	s.mu.Lock()
+	s.srv = srv
+	s.addr = addr
+	s.events = hub
+	s.mu.Unlock()
+
+	slog.Info("local API listening", "addr", addr)
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("local API stopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the server is listening on ("" when stopped)
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addr
+}
+
+// Close stops the server; running jobs are not affected
+func (s *Server) Close() error {
+	s.mu.Lock()
+	srv, hub := s.srv, s.events
+	s.srv = nil
+	s.addr = ""
+	s.events = nil
+	s.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+	// Open event streams would otherwise hold Shutdown until the timeout
+	hub.close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	slog.Info("local API shutting down")
+	return srv.Shutdown(ctx)
+}
+
+func (s *Server) hub() *eventHub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.events
+}
+
+// requireToken checks the bearer token. Browsers' EventSource cannot set headers,
+// so ?access_token= is accepted as well.
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		want := s.token
+		s.mu.Unlock()
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			token = r.URL.Query().Get("access_token")
+			ok = token != ""
+		}
+		if !ok || want == "" || subtle.ConstantTimeCompare([]byte(token), []byte(want)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="DistroNexus"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		slog.Debug("failed to write API response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,233 @@
+// Package applog configures the application's structured logging and reads
+// log files back for the in-app viewer.
+package applog
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// AppLogName is the file written by the Go side; scripts write their own *.log files
+	AppLogName = "app.log"
+
+	maxLogSize    = 5 * 1024 * 1024 // Same 5MB threshold Setup-Logger uses
+	maxLogBackups = 5
+)
+
+// Level names accepted in settings.json
+const (
+	LevelDebug = "Debug"
+	LevelInfo  = "Info"
+	LevelWarn  = "Warn"
+	LevelError = "Error"
+)
+
+// Levels lists the selectable log levels, most verbose first
+var Levels = []string{LevelDebug, LevelInfo, LevelWarn, LevelError}
+
+var (
+	level  = new(slog.LevelVar)
+	logDir string
+	writer *RotatingWriter
+)
+
+// Setup installs a rotating text logger as the slog default. Logs go to <projectRoot>/logs
+// when writable (portable mode) and to %LOCALAPPDATA%\DistroNexus\logs otherwise, matching
+// where the PowerShell scripts put theirs.
+func Setup(projectRoot string) error {
+	dir, err := resolveLogDir(projectRoot)
+	if err != nil {
+		return err
+	}
+	w, err := NewRotatingWriter(filepath.Join(dir, AppLogName), maxLogSize, maxLogBackups)
+	if err != nil {
+		return fmt.Errorf("failed to open app log: %w", err)
+	}
+	logDir = dir
+	writer = w
+
+	handler := slog.NewTextHandler(io.MultiWriter(w, os.Stderr), &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// Close flushes and closes the log file
+func Close() {
+	if writer != nil {
+		writer.Close()
+	}
+}
+
+// SetLevel changes the minimum level at runtime. Unknown names fall back to Info.
+func SetLevel(name string) {
+	level.Set(ParseLevel(name))
+}
+
+// ParseLevel maps a settings level name (case-insensitive) to a slog.Level
+func ParseLevel(name string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// ValidLevel reports whether name is empty (default) or one of Levels
+func ValidLevel(name string) bool {
+	if name == "" {
+		return true
+	}
+	for _, l := range Levels {
+		if strings.EqualFold(l, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dir returns the directory logs are written to (empty before Setup)
+func Dir() string {
+	return logDir
+}
+
+// Files lists the app log and the script logs in the log directory, app log first
+func Files() []string {
+	if logDir == "" {
+		return nil
+	}
+	matches, _ := filepath.Glob(filepath.Join(logDir, "*.log"))
+	sort.Slice(matches, func(i, j int) bool {
+		ai := filepath.Base(matches[i]) == AppLogName
+		aj := filepath.Base(matches[j]) == AppLogName
+		if ai != aj {
+			return ai
+		}
+		return matches[i] < matches[j]
+	})
+	return matches
+}
+
+func resolveLogDir(projectRoot string) (string, error) {
+	local := filepath.Join(projectRoot, "logs")
+	if err := os.MkdirAll(local, 0755); err == nil {
+		if f, err := os.CreateTemp(local, "write_test-*.tmp"); err == nil {
+			f.Close()
+			os.Remove(f.Name())
+			return local, nil
+		}
+	}
+
+	base := os.Getenv("LOCALAPPDATA")
+	if base == "" {
+		var err error
+		if base, err = os.UserCacheDir(); err != nil {
+			return "", fmt.Errorf("no writable log directory: %w", err)
+		}
+	}
+	dir := filepath.Join(base, "DistroNexus", "logs")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create log directory: %w", err)
+	}
+	return dir, nil
+}
+
+// Line is one parsed log line
+type Line struct {
+	Level string // DEBUG, INFO, WARN, ERROR or "" when unknown
+	Text  string
+}
+
+var (
+	slogLevelRe   = regexp.MustCompile(`\blevel=([A-Z]+)`)
+	scriptLevelRe = regexp.MustCompile(`^\[[^\]]+\] \[([A-Z]+)\]`)
+)
+
+// ParseLine extracts the level from slog text lines ("level=INFO") and
+// Setup-Logger lines ("[2026-01-01 10:00:00] [INFO] ...")
+func ParseLine(s string) Line {
+	l := Line{Text: s}
+	if m := scriptLevelRe.FindStringSubmatch(s); m != nil {
+		l.Level = normalizeLevel(m[1])
+	} else if m := slogLevelRe.FindStringSubmatch(s); m != nil {
+		l.Level = normalizeLevel(m[1])
+	}
+	return l
+}
+
+func normalizeLevel(s string) string {
+	switch s {
+	case "WARNING":
+		return "WARN"
+	case "ERR":
+		return "ERROR"
+	}
+	return s
+}
+
+// ReadTail returns the parsed lines contained in the last maxBytes of path
+func ReadTail(path string, maxBytes int64) ([]Line, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := int64(0)
+	if info.Size() > maxBytes {
+		offset = info.Size() - maxBytes
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// Scripts may write UTF-8 with BOM; strip it and the partial first line when seeking
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	if offset > 0 {
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			text = text[i+1:]
+		}
+	}
+
+	var lines []Line
+	for _, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		lines = append(lines, ParseLine(raw))
+	}
+	return lines, nil
+}
+
+// LevelRank orders level names for "at least" filtering; unknown levels rank as INFO
+func LevelRank(name string) int {
+	switch strings.ToUpper(name) {
+	case "DEBUG":
+		return 0
+	case "WARN":
+		return 2
+	case "ERROR":
+		return 3
+	default:
+		return 1
+	}
+}
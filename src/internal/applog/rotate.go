@@ -0,0 +1,155 @@
+package applog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotatingWriter is an io.Writer appending to a file that is archived once it grows
+// past MaxSize. Archives follow the scripts' convention: "<file>.<yyyyMMdd-HHmmss>.bak";
+// further rotations within the same second add a sequence number ("<file>.<stamp>-2.bak").
+type RotatingWriter struct {
+	Path       string
+	MaxSize    int64
+	MaxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotatingWriter opens (or creates) path for appending
+func NewRotatingWriter(path string, maxSize int64, maxBackups int) (*RotatingWriter, error) {
+	w := &RotatingWriter{Path: path, MaxSize: maxSize, MaxBackups: maxBackups}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.Path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if the file would exceed MaxSize
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.MaxSize {
+		if err := w.rotate(); err != nil {
+			// Keep logging to the current file rather than losing lines
+			fmt.Fprintln(os.Stderr, "log rotation failed:", err)
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the underlying file
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *RotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	backup := w.backupName(time.Now())
+	if err := os.Rename(w.Path, backup); err != nil {
+		if openErr := w.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	w.pruneBackups()
+	return w.open()
+}
+
+// backupName picks the first archive name for stamp that is not taken yet
+func (w *RotatingWriter) backupName(now time.Time) string {
+	stamp := now.Format(backupStamp)
+	name := fmt.Sprintf("%s.%s.bak", w.Path, stamp)
+	for seq := 2; ; seq++ {
+		if _, err := os.Lstat(name); os.IsNotExist(err) {
+			return name
+		}
+		name = fmt.Sprintf("%s.%s-%d.bak", w.Path, stamp, seq)
+	}
+}
+
+const backupStamp = "20060102-150405"
+
+// backupOrder returns the timestamp and sequence number of an archive name
+func (w *RotatingWriter) backupOrder(name string) (string, int) {
+	rest := strings.TrimSuffix(strings.TrimPrefix(name, w.Path+"."), ".bak")
+	if len(rest) <= len(backupStamp) {
+		return rest, 1
+	}
+	seq, err := strconv.Atoi(strings.TrimPrefix(rest[len(backupStamp):], "-"))
+	if err != nil {
+		return rest, 1
+	}
+	return rest[:len(backupStamp)], seq
+}
+
+// pruneBackups keeps only the newest MaxBackups archives
+func (w *RotatingWriter) pruneBackups() {
+	if w.MaxBackups <= 0 {
+		return
+	}
+	matches, err := filepath.Glob(w.Path + ".*.bak")
+	if err != nil || len(matches) <= w.MaxBackups {
+		return
+	}
+	// Newest first: the timestamp sorts chronologically, then the sequence number
+	sort.Slice(matches, func(i, j int) bool {
+		si, qi := w.backupOrder(matches[i])
+		sj, qj := w.backupOrder(matches[j])
+		if si != sj {
+			return si > sj
+		}
+		return qi > qj
+	})
+	for _, old := range matches[w.MaxBackups:] {
+		if strings.HasSuffix(old, ".bak") {
+			os.Remove(old)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package applog
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupNameSameSecond(t *testing.T) {
+	w := &RotatingWriter{Path: filepath.Join(t.TempDir(), "app.log")}
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+
+	want := []string{"app.log.20260102-030405.bak", "app.log.20260102-030405-2.bak", "app.log.20260102-030405-3.bak"}
+	for _, name := range want {
+		got := w.backupName(now)
+		if filepath.Base(got) != name {
+			t.Fatalf("got %s, want %s", filepath.Base(got), name)
+		}
+		if err := os.WriteFile(got, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPruneBackupsKeepsNewest(t *testing.T) {
+	dir := t.TempDir()
+	w := &RotatingWriter{Path: filepath.Join(dir, "app.log"), MaxBackups: 3}
+	for _, stamp := range []string{"20260101-100000", "20260101-100001", "20260101-100001-2", "20260101-100001-10", "20251231-235959-9"} {
+		if err := os.WriteFile(w.Path+"."+stamp+".bak", nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.pruneBackups()
+
+	got := backups(t, w.Path)
+	want := []string{"app.log.20260101-100001-10.bak", "app.log.20260101-100001-2.bak", "app.log.20260101-100001.bak"}
+	sort.Strings(want)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("kept %v, want %v", got, want)
+	}
+}
+
+func TestRotateKeepsEveryBackup(t *testing.T) {
+	w, err := NewRotatingWriter(filepath.Join(t.TempDir(), "app.log"), 10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	// Each write exceeds MaxSize together with the previous one, so all but the first rotate
+	lines := []string{"first\n", "second\n", "third\n", "fourth\n"}
+	for _, l := range lines {
+		if _, err := w.Write([]byte(l)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var archived []string
+	for _, b := range backups(t, w.Path) {
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(w.Path), b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		archived = append(archived, string(data))
+	}
+	sort.Strings(archived)
+	want := []string{"first\n", "second\n", "third\n"}
+	sort.Strings(want)
+	if strings.Join(archived, "") != strings.Join(want, "") {
+		t.Fatalf("archives hold %q, want %q", archived, want)
+	}
+	if data, _ := os.ReadFile(w.Path); string(data) != "fourth\n" {
+		t.Fatalf("current log holds %q", data)
+	}
+}
+
+func backups(t *testing.T, path string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(path + ".*.bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, m := range matches {
+		names = append(names, filepath.Base(m))
+	}
+	sort.Strings(names)
+	return names
+}
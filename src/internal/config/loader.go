@@ -5,6 +5,7 @@ import (
 	"distronexus-gui/internal/model"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 )
 
@@ -43,6 +44,7 @@ func (l *Loader) LoadSettings() (*model.GlobalSettings, error) {
 
 	// If settings don't exist, return defaults but don't error
 	if _, err := os.Stat(path); os.IsNotExist(err) {
+		slog.Info("settings.json not found, using defaults", "path", path)
 		return &model.GlobalSettings{
 			DefaultInstallPath: "D:\\WSL",
 			DefaultDistro:      "Ubuntu-24.04",
@@ -71,6 +73,7 @@ func (l *Loader) SaveDistros(distros map[string]model.DistroConfig) error {
 	if err != nil {
 		return err
 	}
+	slog.Debug("saving catalog", "path", path, "families", len(distros))
 	return os.WriteFile(path, data, 0644)
 }
 
@@ -81,6 +84,7 @@ func (l *Loader) SaveSettings(settings *model.GlobalSettings) error {
 	if err != nil {
 		return err
 	}
+	slog.Debug("saving settings", "path", path)
 	return os.WriteFile(path, data, 0644)
 }
 
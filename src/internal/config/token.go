@@ -0,0 +1,42 @@
+package config
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+const apiTokenFile = "api_token"
+
+// LoadAPIToken returns the local API token, creating one on first use.
+// The token lives in config/api_token so it never ends up in shared settings.json files.
+func (l *Loader) LoadAPIToken() (string, error) {
+	data, err := os.ReadFile(l.getPath(apiTokenFile))
+	if err == nil {
+		if token := string(bytes.TrimSpace(data)); token != "" {
+			return token, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read API token: %w", err)
+	}
+	return l.RegenerateAPIToken()
+}
+
+// RegenerateAPIToken replaces the local API token, invalidating the old one
+func (l *Loader) RegenerateAPIToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	path := l.getPath(apiTokenFile)
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write API token: %w", err)
+	}
+	slog.Info("generated new API token", "path", path)
+	return token, nil
+}
@@ -0,0 +1,410 @@
+package config
+
+import (
+	"distronexus-gui/internal/applog"
+	"distronexus-gui/internal/model"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Settings field names, matching the JSON keys in settings.json
+const (
+	FieldDefaultInstallPath       = "DefaultInstallPath"
+	FieldDefaultDistro            = "DefaultDistro"
+	FieldDistroCachePath          = "DistroCachePath"
+	FieldDistroSourceUrl          = "DistroSourceUrl"
+	FieldDefaultTerminalStartPath = "DefaultTerminalStartPath"
+	FieldLogLevel                 = "LogLevel"
+	FieldStatePollSeconds         = "StatePollSeconds"
+	FieldNotifyMinDuration        = "Notifications.MinDurationSec"
+	FieldApiListen                = "ApiListen"
+	FieldCacheLimitGB             = "CacheLimitGB"
+	FieldCACertFiles              = "Provisioning.CACertFiles"
+	FieldDotfilesDir              = "Provisioning.DotfilesDir"
+)
+
+// MaxStatePollSeconds caps the state refresh interval at one hour
+const MaxStatePollSeconds = 3600
+
+// MaxCacheLimitGB bounds CacheLimitGB to catch typos (1 PB)
+const MaxCacheLimitGB = 1024 * 1024
+
+// FieldError describes a problem with a single settings field
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors collects every field that failed validation
+type ValidationErrors []*FieldError
+
+func (v ValidationErrors) Error() string {
+	var lines []string
+	for _, e := range v {
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Field returns the error reported for the given field, or nil
+func (v ValidationErrors) Field(name string) *FieldError {
+	for _, e := range v {
+		if e.Field == name {
+			return e
+		}
+	}
+	return nil
+}
+
+var (
+	// Drive-letter (C:\foo) or UNC (\\server\share) paths
+	windowsAbsPath = regexp.MustCompile(`^([A-Za-z]:[\\/]|\\\\[^\\/]+[\\/])`)
+	// Characters Windows refuses in path segments (the drive colon is stripped first)
+	invalidPathChars = regexp.MustCompile(`[<>"|?*\x00-\x1f]`)
+)
+
+// ValidateSettings checks every field of the settings and returns all problems found.
+// distros is the loaded catalog; the DefaultDistro membership check is skipped when it is empty.
+func (l *Loader) ValidateSettings(s *model.GlobalSettings, distros map[string]model.DistroConfig) ValidationErrors {
+	var errs ValidationErrors
+	add := func(field string, err error) {
+		if err != nil {
+			errs = append(errs, &FieldError{Field: field, Message: err.Error()})
+		}
+	}
+
+	add(FieldDefaultInstallPath, l.ValidateDefaultInstallPath(s.DefaultInstallPath))
+	add(FieldDistroCachePath, l.ValidateDistroCachePath(s.DistroCachePath))
+	add(FieldDefaultDistro, ValidateDefaultDistro(s.DefaultDistro, distros))
+	add(FieldDistroSourceUrl, ValidateDistroSourceUrl(s.DistroSourceUrl))
+	add(FieldDefaultTerminalStartPath, ValidateDefaultTerminalStartPath(s.DefaultTerminalStartPath))
+	add(FieldLogLevel, ValidateLogLevel(s.LogLevel))
+	add(FieldStatePollSeconds, ValidateStatePollSeconds(s.StatePollSeconds))
+	add(FieldApiListen, ValidateApiListen(s.ApiListen))
+	add(FieldCacheLimitGB, ValidateCacheLimitGB(s.CacheLimitGB))
+	if s.Notifications != nil {
+		add(FieldNotifyMinDuration, ValidateNotifyMinDuration(s.Notifications.MinDurationSec))
+	}
+	if s.Provisioning != nil {
+		add(FieldCACertFiles, ValidateCACertFiles(s.Provisioning.CACertFiles))
+		add(FieldDotfilesDir, ValidateDotfilesDir(s.Provisioning.DotfilesDir))
+	}
+	return errs
+}
+
+// ValidateDefaultInstallPath requires an absolute Windows path whose closest existing
+// ancestor is a writable directory (the folder itself is created on first install)
+func (l *Loader) ValidateDefaultInstallPath(path string) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return fmt.Errorf("install path is required")
+	}
+	if !isAbsPath(path) {
+		return fmt.Errorf("must be an absolute path such as D:\\WSL")
+	}
+	if err := checkPathChars(path); err != nil {
+		return err
+	}
+	return checkWritableDir(path)
+}
+
+// ValidateDistroCachePath accepts absolute paths or paths relative to the scripts folder
+// (the same resolution download_all_distros.ps1 applies) and requires them to be writable
+func (l *Loader) ValidateDistroCachePath(path string) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return fmt.Errorf("cache path is required")
+	}
+	if err := checkPathChars(path); err != nil {
+		return err
+	}
+	return checkWritableDir(l.ResolveCachePath(path))
+}
+
+// ResolveCachePath turns a configured cache path into an absolute one
+func (l *Loader) ResolveCachePath(path string) string {
+	if isAbsPath(path) {
+		return path
+	}
+	return filepath.Join(l.BaseDir, "scripts", filepath.FromSlash(strings.ReplaceAll(path, "\\", "/")))
+}
+
+// ValidateDefaultDistro checks that the name matches the DefaultName of a catalog version,
+// which is how install_wsl_custom.ps1 resolves it in Quick Mode
+func ValidateDefaultDistro(name string, distros map[string]model.DistroConfig) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("default distro is required for Quick Mode")
+	}
+	if len(distros) == 0 {
+		return nil
+	}
+	var known []string
+	for _, fam := range distros {
+		for _, v := range fam.Versions {
+			if v.DefaultName == name {
+				return nil
+			}
+			if v.DefaultName != "" {
+				known = append(known, v.DefaultName)
+			}
+		}
+	}
+	if suggestion := closestName(name, known); suggestion != "" {
+		return fmt.Errorf("'%s' is not in the catalog (did you mean '%s'?)", name, suggestion)
+	}
+	return fmt.Errorf("'%s' is not in the catalog; use the Default Name of a version from the Package Library", name)
+}
+
+// ValidateDistroSourceUrl accepts an empty value (official source) or an absolute http(s) URL
+func ValidateDistroSourceUrl(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("not a valid URL: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("URL must start with http:// or https://")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("URL is missing a host name")
+	}
+	return nil
+}
+
+// ValidateDefaultTerminalStartPath accepts empty, "~", "~/..." and absolute Linux paths,
+// as well as absolute Windows paths (translated by wsl --cd)
+func ValidateDefaultTerminalStartPath(path string) error {
+	path = strings.TrimSpace(path)
+	if path == "" || path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "/") {
+		if strings.ContainsAny(path, "\x00\n\r") {
+			return fmt.Errorf("path contains control characters")
+		}
+		return nil
+	}
+	if windowsAbsPath.MatchString(path) {
+		return checkPathChars(path)
+	}
+	return fmt.Errorf("use ~, an absolute Linux path (/home/me) or an absolute Windows path (C:\\Projects)")
+}
+
+// ValidateLogLevel accepts empty (Info) or one of the applog level names
+func ValidateLogLevel(name string) error {
+	if !applog.ValidLevel(name) {
+		return fmt.Errorf("unknown level '%s'; use one of %s", name, strings.Join(applog.Levels, ", "))
+	}
+	return nil
+}
+
+// ValidateStatePollSeconds checks the instance state refresh interval (0 = default)
+func ValidateStatePollSeconds(seconds int) error {
+	if seconds < 0 || seconds > MaxStatePollSeconds {
+		return fmt.Errorf("must be between 1 and %d seconds (0 for the default)", MaxStatePollSeconds)
+	}
+	return nil
+}
+
+// ValidateNotifyMinDuration checks the notification threshold (seconds, 0 = always notify)
+func ValidateNotifyMinDuration(seconds int) error {
+	if seconds < 0 {
+		return fmt.Errorf("must not be negative")
+	}
+	return nil
+}
+
+// ValidateCacheLimitGB checks the package cache size limit (GB, 0 = unlimited)
+func ValidateCacheLimitGB(gb int) error {
+	if gb < 0 || gb > MaxCacheLimitGB {
+		return fmt.Errorf("must be between 1 and %d GB (0 for no limit)", MaxCacheLimitGB)
+	}
+	return nil
+}
+
+// ValidateCACertFiles requires every certificate file to exist and hold a PEM certificate
+func ValidateCACertFiles(files []string) error {
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("cannot read %s", f)
+		}
+		if !strings.Contains(string(data), "-----BEGIN CERTIFICATE-----") {
+			return fmt.Errorf("%s is not a PEM certificate", filepath.Base(f))
+		}
+	}
+	return nil
+}
+
+// ValidateDotfilesDir requires an existing folder (empty disables dotfiles)
+func ValidateDotfilesDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("folder does not exist")
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is a file, not a folder", dir)
+	}
+	return nil
+}
+
+// DefaultApiListen is used when ApiListen is empty
+const DefaultApiListen = "127.0.0.1:7788"
+
+// ValidateApiListen accepts a loopback host:port or unix:<path>; the API must never be reachable remotely
+func ValidateApiListen(addr string) error {
+	if addr == "" {
+		return nil
+	}
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		if strings.TrimSpace(path) == "" {
+			return fmt.Errorf("socket path is empty")
+		}
+		return nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("expected host:port or unix:<path>")
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port '%s'", port)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("host must be a loopback address (127.0.0.1, ::1 or localhost)")
+	}
+	return nil
+}
+
+func isAbsPath(path string) bool {
+	return windowsAbsPath.MatchString(path) || filepath.IsAbs(path)
+}
+
+func checkPathChars(path string) error {
+	rest := path
+	if len(rest) >= 2 && rest[1] == ':' {
+		rest = rest[2:]
+	}
+	if invalidPathChars.MatchString(rest) || strings.Contains(rest, ":") {
+		return fmt.Errorf("path contains characters that are not allowed (< > : \" | ? *)")
+	}
+	return nil
+}
+
+// checkWritableDir walks up to the closest existing ancestor and verifies it is a directory
+// we can create files in
+func checkWritableDir(path string) error {
+	dir := path
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				if dir == path {
+					return fmt.Errorf("%s is a file, not a folder", dir)
+				}
+				return fmt.Errorf("cannot create folder: %s is a file", dir)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("cannot access %s: %v", dir, err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return fmt.Errorf("drive or share for %s does not exist", path)
+		}
+		dir = parent
+	}
+
+	// Same probe the scripts use in Setup-Logger
+	f, err := os.CreateTemp(dir, ".write_test-*.tmp")
+	if err != nil {
+		return fmt.Errorf("folder %s is not writable", dir)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
+// closestName returns the candidate sharing a case-insensitive prefix or substring with name
+func closestName(name string, candidates []string) string {
+	lower := strings.ToLower(name)
+	for _, c := range candidates {
+		if strings.EqualFold(c, name) {
+			return c
+		}
+	}
+	for _, c := range candidates {
+		lc := strings.ToLower(c)
+		if strings.Contains(lc, lower) || strings.Contains(lower, lc) {
+			return c
+		}
+	}
+	return ""
+}
+
+// Custom package field names, as reported by ValidateCustomPackage
+const (
+	FieldCustomName    = "Name"
+	FieldCustomVersion = "Version"
+	FieldCustomSource  = "PathOrUrl"
+)
+
+// ValidateCustomPackage checks the fields of a custom package. others are the remaining
+// custom packages (without the one being edited); Name and Version must be unique among
+// them. Whether the source exists or answers is checked by logic.CheckPackageSource.
+func ValidateCustomPackage(pkg model.CustomPackage, others []model.CustomPackage) ValidationErrors {
+	var errs ValidationErrors
+	add := func(field string, err error) {
+		if err != nil {
+			errs = append(errs, &FieldError{Field: field, Message: err.Error()})
+		}
+	}
+
+	name, version := strings.TrimSpace(pkg.Name), strings.TrimSpace(pkg.Version)
+	if name == "" {
+		add(FieldCustomName, fmt.Errorf("name is required"))
+	}
+	if version == "" {
+		add(FieldCustomVersion, fmt.Errorf("version is required"))
+	}
+	for _, o := range others {
+		if name != "" && strings.EqualFold(o.Name, name) && strings.EqualFold(o.Version, version) {
+			add(FieldCustomVersion, fmt.Errorf("'%s %s' already exists", o.Name, o.Version))
+			break
+		}
+	}
+	add(FieldCustomSource, ValidatePackageSource(pkg.PathOrUrl))
+	return errs
+}
+
+// ValidatePackageSource accepts an absolute path to a local file or an http(s) URL
+func ValidatePackageSource(source string) error {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return fmt.Errorf("path or URL is required")
+	}
+	if strings.Contains(source, "://") {
+		return ValidateDistroSourceUrl(source)
+	}
+	if !isAbsPath(source) {
+		return fmt.Errorf("use an absolute path (D:\\images\\rootfs.tar.gz) or an http(s) URL")
+	}
+	return checkPathChars(source)
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"distronexus-gui/internal/model"
+	"strings"
+	"testing"
+)
+
+func TestValidateSettings(t *testing.T) {
+	dir := t.TempDir()
+	valid := func() model.GlobalSettings {
+		return model.GlobalSettings{
+			DefaultInstallPath: dir,
+			DefaultDistro:      "Ubuntu-24.04",
+			DistroCachePath:    dir,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(s *model.GlobalSettings)
+		field  string // "" expects no errors
+	}{
+		{"valid", func(s *model.GlobalSettings) {}, ""},
+		{"valid api listen", func(s *model.GlobalSettings) { s.ApiListen = "127.0.0.1:8080" }, ""},
+		{"port not a number", func(s *model.GlobalSettings) { s.ApiListen = "127.0.0.1:http" }, FieldApiListen},
+		{"port out of range", func(s *model.GlobalSettings) { s.ApiListen = "127.0.0.1:70000" }, FieldApiListen},
+		{"port zero", func(s *model.GlobalSettings) { s.ApiListen = "localhost:0" }, FieldApiListen},
+		{"remote host", func(s *model.GlobalSettings) { s.ApiListen = "0.0.0.0:7788" }, FieldApiListen},
+		{"poll default", func(s *model.GlobalSettings) { s.StatePollSeconds = 0 }, ""},
+		{"poll max", func(s *model.GlobalSettings) { s.StatePollSeconds = MaxStatePollSeconds }, ""},
+		{"poll negative", func(s *model.GlobalSettings) { s.StatePollSeconds = -1 }, FieldStatePollSeconds},
+		{"poll too long", func(s *model.GlobalSettings) { s.StatePollSeconds = MaxStatePollSeconds + 1 }, FieldStatePollSeconds},
+		{"url https", func(s *model.GlobalSettings) { s.DistroSourceUrl = "https://example.com/distros.json" }, ""},
+		{"url scheme", func(s *model.GlobalSettings) { s.DistroSourceUrl = "ftp://example.com/distros.json" }, FieldDistroSourceUrl},
+		{"url no host", func(s *model.GlobalSettings) { s.DistroSourceUrl = "https:///distros.json" }, FieldDistroSourceUrl},
+		{"url unparsable", func(s *model.GlobalSettings) { s.DistroSourceUrl = "http://exa mple.com/%zz" }, FieldDistroSourceUrl},
+		{"log level case-insensitive", func(s *model.GlobalSettings) { s.LogLevel = "debug" }, ""},
+		{"log level unknown", func(s *model.GlobalSettings) { s.LogLevel = "Verbose" }, FieldLogLevel},
+		{"cache limit negative", func(s *model.GlobalSettings) { s.CacheLimitGB = -5 }, FieldCacheLimitGB},
+		{"notify negative", func(s *model.GlobalSettings) {
+			s.Notifications = &model.NotificationSettings{MinDurationSec: -1}
+		}, FieldNotifyMinDuration},
+		{"install path missing", func(s *model.GlobalSettings) { s.DefaultInstallPath = "" }, FieldDefaultInstallPath},
+		{"install path relative", func(s *model.GlobalSettings) { s.DefaultInstallPath = "WSL" }, FieldDefaultInstallPath},
+		{"terminal path relative", func(s *model.GlobalSettings) { s.DefaultTerminalStartPath = "projects" }, FieldDefaultTerminalStartPath},
+	}
+
+	l := NewLoader(dir)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid()
+			tt.modify(&s)
+			errs := l.ValidateSettings(&s, nil)
+			if tt.field == "" {
+				if len(errs) != 0 {
+					t.Fatalf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 {
+				t.Fatalf("want exactly one error for %s, got %v", tt.field, errs)
+			}
+			if fe := errs.Field(tt.field); fe == nil || fe.Message == "" {
+				t.Fatalf("want error for %s, got %v", tt.field, errs)
+			}
+		})
+	}
+}
+
+func TestValidateDefaultDistro(t *testing.T) {
+	distros := map[string]model.DistroConfig{
+		"ubuntu": {Name: "Ubuntu", Versions: map[string]model.Version{
+			"24.04": {DefaultName: "Ubuntu-24.04"},
+		}},
+	}
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"known", "Ubuntu-24.04", false},
+		{"unknown", "Debian-12", true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateDefaultDistro(tt.value, distros); (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateDefaultDistro(%q) = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+	if err := ValidateDefaultDistro("ubuntu-24.04", distros); err == nil || !strings.Contains(err.Error(), "did you mean 'Ubuntu-24.04'") {
+		t.Fatalf("want a suggestion, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// BatchOp is the per-instance body of a batch. It must honour ctx and report output through log.
+type BatchOp func(ctx context.Context, name string, log func(string)) error
+
+// BatchResult is the outcome of a batch operation on one instance
+type BatchResult struct {
+	Instance string
+	Err      error
+	Duration time.Duration
+	// Skipped is true when the batch was canceled before the instance's turn
+	Skipped bool
+}
+
+// BatchResults lists the outcome for every instance of a batch, in run order
+type BatchResults []BatchResult
+
+// Failed counts the instances whose operation returned an error, including skipped ones
+func (r BatchResults) Failed() int {
+	n := 0
+	for _, res := range r {
+		if res.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Err is nil when every instance succeeded, otherwise a *BatchError
+func (r BatchResults) Err() error {
+	if failed := r.Failed(); failed > 0 {
+		return &BatchError{Failed: failed, Total: len(r)}
+	}
+	return nil
+}
+
+// BatchError reports that part of a batch failed. Callers show the per-instance
+// results themselves, so the error only carries the counts.
+type BatchError struct {
+	Failed int
+	Total  int
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d instances failed", e.Failed, e.Total)
+}
+
+// RunBatch applies op to each instance in turn and carries on past failures. Once ctx
+// is canceled the remaining instances are skipped. Progress is reported with one stage
+// per instance; stage progress the operation reports itself is passed through.
+func RunBatch(ctx context.Context, names []string, op BatchOp, onOutput func(string)) BatchResults {
+	log := func(s string) {
+		if onOutput != nil {
+			onOutput(s)
+		}
+	}
+	results := make(BatchResults, 0, len(names))
+	for i, name := range names {
+		if ctx.Err() != nil {
+			results = append(results, BatchResult{Instance: name, Err: ctx.Err(), Skipped: true})
+			continue
+		}
+		message := fmt.Sprintf("%s (%d/%d)", name, i+1, len(names))
+		ReportProgress(ctx, ProgressEvent{Stage: name, Percent: -1, Message: message, Stages: names})
+		log(fmt.Sprintf("==> %s\n", message))
+
+		opCtx := WithProgress(ctx, func(ev ProgressEvent) {
+			if ev.Message != "" {
+				ev.Message = name + ": " + ev.Message
+			}
+			ReportProgress(ctx, ProgressEvent{Stage: name, Percent: ev.Percent, Message: ev.Message})
+		})
+		started := time.Now()
+		err := op(opCtx, name, onOutput)
+		results = append(results, BatchResult{Instance: name, Err: err, Duration: time.Since(started)})
+		if err != nil {
+			log(fmt.Sprintf("FAILED: %s: %v\n", name, err))
+		} else {
+			ReportProgress(ctx, ProgressEvent{Stage: name, Percent: 100, Message: name + " done"})
+		}
+	}
+	return results
+}
@@ -0,0 +1,583 @@
+package logic
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"distronexus-gui/internal/model"
+)
+
+// Package cache layout (written by download_all_distros.ps1):
+//
+//	<cache>/sha256/<hash>/<filename>  one file per distinct content
+//	<cache>/index.json               source URL and use times per hash
+//
+// Catalog versions and custom packages reference a package through LocalPath and Sha256;
+// several entries with the same URL share one file.
+const cacheBlobDir = "sha256"
+
+// CacheIndexEntry is what index.json records for one stored package
+type CacheIndexEntry struct {
+	// Url is the source of the latest download that produced this content
+	Url string `json:"Url"`
+	// Urls lists every source that produced this content; several URLs can serve the same file
+	Urls     []string  `json:"Urls,omitempty"`
+	Filename string    `json:"Filename"`
+	Size     int64     `json:"Size"`
+	Added    time.Time `json:"Added"`
+	LastUsed time.Time `json:"LastUsed"`
+	// Corrupt holds the problem found by the last verification, empty when intact
+	Corrupt    string    `json:"Corrupt,omitempty"`
+	VerifiedAt time.Time `json:"VerifiedAt,omitempty"`
+}
+
+// HasUrl reports whether url is one of the sources recorded for the content
+func (e CacheIndexEntry) HasUrl(url string) bool {
+	return url == e.Url || slices.Contains(e.Urls, url)
+}
+
+// addUrl records url as a source of the content and makes it the latest one
+func (e *CacheIndexEntry) addUrl(url string) {
+	if url == "" {
+		return
+	}
+	if e.Url != "" && !slices.Contains(e.Urls, e.Url) {
+		e.Urls = append(e.Urls, e.Url)
+	}
+	if !slices.Contains(e.Urls, url) {
+		e.Urls = append(e.Urls, url)
+	}
+	e.Url = url
+}
+
+// CacheRef is a catalog version or custom package that uses a cached package
+type CacheRef struct {
+	Family  string
+	Version string
+	Name    string
+	// Custom is set for a custom package; Family and Version are empty then
+	Custom bool
+	// Superseded is true when the version's URL is not one the stored package came from
+	Superseded bool
+
+	customIndex int
+}
+
+// CachedPackage is one file in the content-addressed store
+type CachedPackage struct {
+	Hash     string
+	Path     string
+	Size     int64
+	Url      string
+	LastUsed time.Time
+	Refs     []CacheRef
+}
+
+// Live returns the references whose URL still matches the package
+func (p CachedPackage) Live() []CacheRef {
+	var live []CacheRef
+	for _, r := range p.Refs {
+		if !r.Superseded {
+			live = append(live, r)
+		}
+	}
+	return live
+}
+
+var cacheIndexMu sync.Mutex
+
+func cacheIndexPath(cacheDir string) string {
+	return filepath.Join(cacheDir, "index.json")
+}
+
+func readCacheIndex(cacheDir string) (map[string]CacheIndexEntry, error) {
+	index := make(map[string]CacheIndexEntry)
+	data, err := os.ReadFile(cacheIndexPath(cacheDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if len(bytes.TrimSpace(data)) == 0 {
+		return index, nil
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse cache index: %w", err)
+	}
+	return index, nil
+}
+
+func writeCacheIndex(cacheDir string, index map[string]CacheIndexEntry) error {
+	data, err := json.MarshalIndent(index, "", "    ")
+	if err != nil {
+		return err
+	}
+	tmp := cacheIndexPath(cacheDir) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, cacheIndexPath(cacheDir))
+}
+
+// cacheHashOf returns the hash for a path inside the store, or "" for other paths
+func cacheHashOf(cacheDir, path string) string {
+	rel, err := filepath.Rel(filepath.Join(cacheDir, cacheBlobDir), path)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) != 2 || len(parts[0]) != sha256.Size*2 {
+		return ""
+	}
+	return parts[0]
+}
+
+// ListCachedPackages walks the store and attaches the catalog versions and custom packages
+// referencing each package. Packages missing from index.json are added with their file
+// time as last use.
+func ListCachedPackages(cacheDir string, distros map[string]model.DistroConfig, custom []model.CustomPackage) ([]CachedPackage, error) {
+	cacheIndexMu.Lock()
+	defer cacheIndexMu.Unlock()
+
+	index, err := readCacheIndex(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	refs := catalogRefs(distros, custom)
+
+	dirs, err := os.ReadDir(filepath.Join(cacheDir, cacheBlobDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	indexChanged := false
+	var list []CachedPackage
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		hash := d.Name()
+		files, err := os.ReadDir(filepath.Join(cacheDir, cacheBlobDir, hash))
+		if err != nil || len(files) == 0 {
+			continue
+		}
+		info, err := files[0].Info()
+		if err != nil {
+			continue
+		}
+		pkg := CachedPackage{
+			Hash: hash,
+			Path: filepath.Join(cacheDir, cacheBlobDir, hash, files[0].Name()),
+			Size: info.Size(),
+		}
+
+		entry, ok := index[hash]
+		if !ok {
+			// Unknown origin: every entry pointing at the file counts as a source
+			entry = CacheIndexEntry{Filename: files[0].Name(), Size: info.Size(), Added: info.ModTime(), LastUsed: info.ModTime()}
+			for _, r := range refs[hash] {
+				entry.addUrl(r.url)
+			}
+			index[hash] = entry
+			indexChanged = true
+		}
+		pkg.Url = entry.Url
+		pkg.LastUsed = entry.LastUsed
+
+		for _, r := range refs[hash] {
+			pkg.Refs = append(pkg.Refs, CacheRef{
+				Family:      r.family,
+				Version:     r.version,
+				Name:        r.name,
+				Custom:      r.customIndex >= 0,
+				Superseded:  entry.Url != "" && !entry.HasUrl(r.url),
+				customIndex: r.customIndex,
+			})
+		}
+		list = append(list, pkg)
+	}
+	if indexChanged {
+		if err := writeCacheIndex(cacheDir, index); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LastUsed.After(list[j].LastUsed) })
+	return list, nil
+}
+
+type catalogRef struct {
+	family, version, name, url string
+	// customIndex is the position in the custom packages, -1 for catalog versions
+	customIndex int
+}
+
+// catalogRefs maps package hashes to the catalog versions and custom packages that point at them
+func catalogRefs(distros map[string]model.DistroConfig, custom []model.CustomPackage) map[string][]catalogRef {
+	refs := make(map[string][]catalogRef)
+	for famKey, fam := range distros {
+		for verKey, ver := range fam.Versions {
+			if ver.Sha256 == "" || ver.LocalPath == "" {
+				continue
+			}
+			refs[ver.Sha256] = append(refs[ver.Sha256], catalogRef{family: famKey, version: verKey, name: fam.Name + " " + ver.Name, url: ver.Url, customIndex: -1})
+		}
+	}
+	for i, cp := range custom {
+		if cp.Sha256 == "" || cp.LocalPath == "" {
+			continue
+		}
+		refs[cp.Sha256] = append(refs[cp.Sha256], catalogRef{name: cp.Name + " " + cp.Version + " (custom)", url: cp.PathOrUrl, customIndex: i})
+	}
+	for _, list := range refs {
+		sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	}
+	return refs
+}
+
+// TouchCachedPackage marks the package at path as used now (for LRU eviction).
+// Paths outside the store are ignored.
+func TouchCachedPackage(cacheDir, path string) error {
+	hash := cacheHashOf(cacheDir, path)
+	if hash == "" {
+		return nil
+	}
+	cacheIndexMu.Lock()
+	defer cacheIndexMu.Unlock()
+	index, err := readCacheIndex(cacheDir)
+	if err != nil {
+		return err
+	}
+	entry := index[hash]
+	entry.LastUsed = time.Now().UTC()
+	if entry.Filename == "" {
+		entry.Filename = filepath.Base(path)
+		entry.Added = entry.LastUsed
+	}
+	index[hash] = entry
+	return writeCacheIndex(cacheDir, index)
+}
+
+// unlinkVersion clears the cache reference of one catalog version
+func unlinkVersion(distros map[string]model.DistroConfig, family, version string) {
+	fam, ok := distros[family]
+	if !ok {
+		return
+	}
+	ver, ok := fam.Versions[version]
+	if !ok {
+		return
+	}
+	ver.LocalPath = ""
+	ver.Sha256 = ""
+	fam.Versions[version] = ver
+}
+
+// unlinkCustom clears the cache reference of the custom package at index i
+func unlinkCustom(custom []model.CustomPackage, i int) {
+	if i < 0 || i >= len(custom) {
+		return
+	}
+	custom[i].LocalPath = ""
+	custom[i].Sha256 = ""
+}
+
+// unlinkRef clears the reference r, whichever kind of entry holds it
+func unlinkRef(distros map[string]model.DistroConfig, custom []model.CustomPackage, r CacheRef) {
+	if r.Custom {
+		unlinkCustom(custom, r.customIndex)
+	} else {
+		unlinkVersion(distros, r.Family, r.Version)
+	}
+}
+
+// removeBlob deletes a package and its index entry
+func removeBlob(cacheDir, hash string) error {
+	if err := os.RemoveAll(filepath.Join(cacheDir, cacheBlobDir, hash)); err != nil {
+		return err
+	}
+	cacheIndexMu.Lock()
+	defer cacheIndexMu.Unlock()
+	index, err := readCacheIndex(cacheDir)
+	if err != nil {
+		return err
+	}
+	delete(index, hash)
+	return writeCacheIndex(cacheDir, index)
+}
+
+// ReleaseCachedPackage drops one catalog version's reference. The file is deleted only
+// when no other entry uses it. distros is updated in place; the caller saves it.
+func ReleaseCachedPackage(cacheDir string, distros map[string]model.DistroConfig, custom []model.CustomPackage, family, version string) (removed bool, err error) {
+	fam, ok := distros[family]
+	if !ok {
+		return false, fmt.Errorf("unknown family %s", family)
+	}
+	ver, ok := fam.Versions[version]
+	if !ok {
+		return false, fmt.Errorf("unknown version %s", version)
+	}
+	path, hash := ver.LocalPath, ver.Sha256
+	unlinkVersion(distros, family, version)
+
+	if hash == "" {
+		// Legacy layout without a hash: only delete a file inside the cache that no other
+		// version points at
+		if path == "" || !isInsideDir(cacheDir, path) {
+			return false, nil
+		}
+		for _, f := range distros {
+			for _, v := range f.Versions {
+				if strings.EqualFold(v.LocalPath, path) {
+					return false, nil
+				}
+			}
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+		return true, nil
+	}
+
+	return removeIfUnused(cacheDir, distros, custom, hash)
+}
+
+// ReleaseCustomPackage drops the cache reference of the custom package at index i, deleting
+// the file when no other entry uses it. custom is updated in place; the caller saves the settings.
+func ReleaseCustomPackage(cacheDir string, distros map[string]model.DistroConfig, custom []model.CustomPackage, i int) (removed bool, err error) {
+	if i < 0 || i >= len(custom) {
+		return false, fmt.Errorf("unknown custom package %d", i)
+	}
+	hash := custom[i].Sha256
+	unlinkCustom(custom, i)
+	if hash == "" {
+		return false, nil
+	}
+	return removeIfUnused(cacheDir, distros, custom, hash)
+}
+
+// removeIfUnused deletes the package hash unless a catalog version or custom package still uses it
+func removeIfUnused(cacheDir string, distros map[string]model.DistroConfig, custom []model.CustomPackage, hash string) (bool, error) {
+	if len(catalogRefs(distros, custom)[hash]) > 0 {
+		return false, nil
+	}
+	return true, removeBlob(cacheDir, hash)
+}
+
+// CacheGCResult reports what a garbage collection or eviction removed
+type CacheGCResult struct {
+	Removed    []CachedPackage
+	FreedBytes int64
+	// Unlinked lists entries whose reference was dropped ("Family Version")
+	Unlinked []string
+	// Migrated counts legacy files moved into the store
+	Migrated int
+}
+
+// CollectCacheGarbage moves legacy <family>/<version>/<file> downloads into the store,
+// drops references whose catalog URL changed (superseded packages), removes packages
+// no catalog version or custom package uses and then evicts least recently used packages
+// until the cache fits limitBytes (0 = no limit). distros and custom are updated in place;
+// the caller saves them.
+func CollectCacheGarbage(cacheDir string, distros map[string]model.DistroConfig, custom []model.CustomPackage, limitBytes int64) (CacheGCResult, error) {
+	var res CacheGCResult
+	migrated, err := migrateLegacyCache(cacheDir, distros)
+	if err != nil {
+		return res, err
+	}
+	res.Migrated = migrated
+
+	packages, err := ListCachedPackages(cacheDir, distros, custom)
+	if err != nil {
+		return res, err
+	}
+
+	var kept []CachedPackage
+	for _, pkg := range packages {
+		for _, r := range pkg.Refs {
+			if r.Superseded {
+				unlinkRef(distros, custom, r)
+				res.Unlinked = append(res.Unlinked, r.Name)
+			}
+		}
+		if len(pkg.Live()) > 0 {
+			kept = append(kept, pkg)
+			continue
+		}
+		if err := removeBlob(cacheDir, pkg.Hash); err != nil {
+			return res, err
+		}
+		res.Removed = append(res.Removed, pkg)
+		res.FreedBytes += pkg.Size
+	}
+
+	evicted, err := evictLRU(cacheDir, distros, custom, kept, limitBytes)
+	for _, pkg := range evicted {
+		for _, r := range pkg.Live() {
+			res.Unlinked = append(res.Unlinked, r.Name)
+		}
+		res.Removed = append(res.Removed, pkg)
+		res.FreedBytes += pkg.Size
+	}
+	return res, err
+}
+
+// EnforceCacheLimit evicts least recently used packages until the store fits limitBytes.
+// distros and custom are updated in place; the caller saves them.
+func EnforceCacheLimit(cacheDir string, distros map[string]model.DistroConfig, custom []model.CustomPackage, limitBytes int64) ([]CachedPackage, error) {
+	if limitBytes <= 0 {
+		return nil, nil
+	}
+	packages, err := ListCachedPackages(cacheDir, distros, custom)
+	if err != nil {
+		return nil, err
+	}
+	return evictLRU(cacheDir, distros, custom, packages, limitBytes)
+}
+
+func evictLRU(cacheDir string, distros map[string]model.DistroConfig, custom []model.CustomPackage, packages []CachedPackage, limitBytes int64) ([]CachedPackage, error) {
+	if limitBytes <= 0 {
+		return nil, nil
+	}
+	var total int64
+	for _, p := range packages {
+		total += p.Size
+	}
+	sorted := append([]CachedPackage(nil), packages...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].LastUsed.Before(sorted[j].LastUsed) })
+
+	var evicted []CachedPackage
+	for _, pkg := range sorted {
+		if total <= limitBytes {
+			break
+		}
+		for _, r := range pkg.Refs {
+			unlinkRef(distros, custom, r)
+		}
+		if err := removeBlob(cacheDir, pkg.Hash); err != nil {
+			return evicted, err
+		}
+		total -= pkg.Size
+		evicted = append(evicted, pkg)
+	}
+	return evicted, nil
+}
+
+// migrateLegacyCache hashes files referenced through the old per-version layout and moves
+// them into the store, pointing every version that used the file at the new location.
+// Files outside the cache directory are the user's and stay put.
+func migrateLegacyCache(cacheDir string, distros map[string]model.DistroConfig) (int, error) {
+	moved := make(map[string]string) // old path (lower case) -> hash
+	count := 0
+	for famKey, fam := range distros {
+		for verKey, ver := range fam.Versions {
+			if ver.LocalPath == "" || ver.Sha256 != "" || !isInsideDir(cacheDir, ver.LocalPath) {
+				continue
+			}
+			key := strings.ToLower(ver.LocalPath)
+			hash, done := moved[key]
+			if !done {
+				if _, err := os.Stat(ver.LocalPath); err != nil {
+					// The file is gone: drop the dangling reference
+					unlinkVersion(distros, famKey, verKey)
+					continue
+				}
+				var err error
+				if hash, err = adoptIntoCache(cacheDir, ver.LocalPath, ver.Url); err != nil {
+					return count, err
+				}
+				moved[key] = hash
+				count++
+			}
+			files, err := os.ReadDir(filepath.Join(cacheDir, cacheBlobDir, hash))
+			if err != nil || len(files) == 0 {
+				return count, fmt.Errorf("cache entry %s missing after migration", hash)
+			}
+			ver.LocalPath = filepath.Join(cacheDir, cacheBlobDir, hash, files[0].Name())
+			ver.Sha256 = hash
+			fam.Versions[verKey] = ver
+		}
+	}
+	return count, nil
+}
+
+// adoptIntoCache hashes path and moves it into the store (or deletes it if the same
+// content is already stored) and records url as a source of the content. It returns the hash.
+func adoptIntoCache(cacheDir, path, url string) (string, error) {
+	hash, err := fileSHA256(path)
+	if err != nil {
+		return "", err
+	}
+	blobDir := filepath.Join(cacheDir, cacheBlobDir, hash)
+	target := filepath.Join(blobDir, filepath.Base(path))
+	if files, err := os.ReadDir(blobDir); err == nil && len(files) > 0 {
+		if err := os.Remove(path); err != nil {
+			return "", err
+		}
+		target = filepath.Join(blobDir, files[0].Name())
+	} else {
+		if err := os.MkdirAll(blobDir, 0755); err != nil {
+			return "", err
+		}
+		if err := os.Rename(path, target); err != nil {
+			return "", err
+		}
+	}
+	removeLegacyDirs(path)
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return "", err
+	}
+	cacheIndexMu.Lock()
+	defer cacheIndexMu.Unlock()
+	index, err := readCacheIndex(cacheDir)
+	if err != nil {
+		return "", err
+	}
+	entry, ok := index[hash]
+	if !ok {
+		entry = CacheIndexEntry{Filename: filepath.Base(target), Size: info.Size(), Added: info.ModTime(), LastUsed: info.ModTime()}
+	}
+	entry.addUrl(url)
+	index[hash] = entry
+	return hash, writeCacheIndex(cacheDir, index)
+}
+
+// removeLegacyDirs removes the <family>/<version> folders emptied by moving path away;
+// errors just mean they aren't empty
+func removeLegacyDirs(path string) {
+	os.Remove(filepath.Dir(path))
+	os.Remove(filepath.Dir(filepath.Dir(path)))
+}
+
+// fileSHA256 returns the lower-case hex SHA-256 of a file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// isInsideDir reports whether path is below dir (case-insensitive, as on Windows)
+func isInsideDir(dir, path string) bool {
+	rel, err := filepath.Rel(strings.ToLower(filepath.Clean(dir)), strings.ToLower(filepath.Clean(path)))
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
@@ -0,0 +1,282 @@
+package logic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"distronexus-gui/internal/model"
+)
+
+// storeTestPackage writes content into the store under its hash and records it in index.json
+func storeTestPackage(t *testing.T, cacheDir, filename, url, content string, lastUsed time.Time) (hash, path string) {
+	t.Helper()
+	sum := sha256.Sum256([]byte(content))
+	hash = hex.EncodeToString(sum[:])
+	dir := filepath.Join(cacheDir, cacheBlobDir, hash)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path = filepath.Join(dir, filename)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	index, err := readCacheIndex(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index[hash] = CacheIndexEntry{Url: url, Filename: filename, Size: int64(len(content)), Added: lastUsed, LastUsed: lastUsed}
+	if err := writeCacheIndex(cacheDir, index); err != nil {
+		t.Fatal(err)
+	}
+	return hash, path
+}
+
+func testDistros(versions map[string]model.Version) map[string]model.DistroConfig {
+	return map[string]model.DistroConfig{"ubuntu": {Name: "Ubuntu", Versions: versions}}
+}
+
+func TestCollectCacheGarbage(t *testing.T) {
+	cacheDir := t.TempDir()
+	now := time.Now().UTC()
+	liveHash, livePath := storeTestPackage(t, cacheDir, "live.tar", "https://example.com/live.tar", "live", now)
+	oldHash, oldPath := storeTestPackage(t, cacheDir, "old.tar", "https://example.com/old.tar", "superseded", now)
+	orphanHash, _ := storeTestPackage(t, cacheDir, "orphan.tar", "https://example.com/orphan.tar", "orphaned", now)
+	customHash, customPath := storeTestPackage(t, cacheDir, "custom.tar", "https://example.com/custom.tar", "custom", now)
+
+	distros := testDistros(map[string]model.Version{
+		"24.04": {Name: "24.04", Url: "https://example.com/live.tar", LocalPath: livePath, Sha256: liveHash},
+		// The catalog moved on to a new URL; the stored package is superseded
+		"22.04": {Name: "22.04", Url: "https://example.com/new.tar", LocalPath: oldPath, Sha256: oldHash},
+	})
+	custom := []model.CustomPackage{
+		{Name: "Mine", Version: "1", PathOrUrl: "https://example.com/custom.tar", LocalPath: customPath, Sha256: customHash},
+	}
+
+	res, err := CollectCacheGarbage(cacheDir, distros, custom, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed := map[string]bool{}
+	for _, p := range res.Removed {
+		removed[p.Hash] = true
+	}
+	if len(res.Removed) != 2 || !removed[oldHash] || !removed[orphanHash] {
+		t.Fatalf("removed %v, want the superseded and the orphaned package", res.Removed)
+	}
+	if res.FreedBytes != int64(len("superseded")+len("orphaned")) {
+		t.Errorf("FreedBytes = %d", res.FreedBytes)
+	}
+	if len(res.Unlinked) != 1 || res.Unlinked[0] != "Ubuntu 22.04" {
+		t.Errorf("Unlinked = %v, want [Ubuntu 22.04]", res.Unlinked)
+	}
+	if v := distros["ubuntu"].Versions["22.04"]; v.LocalPath != "" || v.Sha256 != "" {
+		t.Errorf("superseded version still linked: %+v", v)
+	}
+	if v := distros["ubuntu"].Versions["24.04"]; v.Sha256 != liveHash {
+		t.Errorf("live version unlinked: %+v", v)
+	}
+	if custom[0].Sha256 != customHash {
+		t.Errorf("custom package unlinked: %+v", custom[0])
+	}
+
+	for hash, want := range map[string]bool{liveHash: true, customHash: true, oldHash: false, orphanHash: false} {
+		_, err := os.Stat(filepath.Join(cacheDir, cacheBlobDir, hash))
+		if exists := err == nil; exists != want {
+			t.Errorf("package %s exists = %v, want %v", hash[:8], exists, want)
+		}
+	}
+	index, err := readCacheIndex(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index) != 2 {
+		t.Errorf("index has %d entries, want 2", len(index))
+	}
+}
+
+func TestEnforceCacheLimit(t *testing.T) {
+	cacheDir := t.TempDir()
+	base := time.Now().UTC().Add(-time.Hour)
+	// Three 4-byte packages, used in the order a, b, c
+	hashA, pathA := storeTestPackage(t, cacheDir, "a.tar", "https://example.com/a.tar", "aaaa", base)
+	hashB, pathB := storeTestPackage(t, cacheDir, "b.tar", "https://example.com/b.tar", "bbbb", base.Add(time.Minute))
+	hashC, pathC := storeTestPackage(t, cacheDir, "c.tar", "https://example.com/c.tar", "cccc", base.Add(2*time.Minute))
+
+	distros := testDistros(map[string]model.Version{
+		"a": {Name: "a", Url: "https://example.com/a.tar", LocalPath: pathA, Sha256: hashA},
+		"b": {Name: "b", Url: "https://example.com/b.tar", LocalPath: pathB, Sha256: hashB},
+		"c": {Name: "c", Url: "https://example.com/c.tar", LocalPath: pathC, Sha256: hashC},
+	})
+
+	// Using a makes b the least recently used
+	if err := TouchCachedPackage(cacheDir, pathA); err != nil {
+		t.Fatal(err)
+	}
+
+	if evicted, err := EnforceCacheLimit(cacheDir, distros, nil, 0); err != nil || len(evicted) != 0 {
+		t.Fatalf("no limit: evicted %v, err %v", evicted, err)
+	}
+	if evicted, err := EnforceCacheLimit(cacheDir, distros, nil, 12); err != nil || len(evicted) != 0 {
+		t.Fatalf("within limit: evicted %v, err %v", evicted, err)
+	}
+
+	evicted, err := EnforceCacheLimit(cacheDir, distros, nil, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evicted) != 2 || evicted[0].Hash != hashB || evicted[1].Hash != hashC {
+		t.Fatalf("evicted %v, want b then c", evicted)
+	}
+	if v := distros["ubuntu"].Versions["a"]; v.Sha256 != hashA {
+		t.Errorf("recently used package unlinked: %+v", v)
+	}
+	for _, key := range []string{"b", "c"} {
+		if v := distros["ubuntu"].Versions[key]; v.LocalPath != "" || v.Sha256 != "" {
+			t.Errorf("evicted version %s still linked: %+v", key, v)
+		}
+	}
+	if _, err := os.Stat(pathA); err != nil {
+		t.Errorf("kept package missing: %v", err)
+	}
+	for _, p := range []string{pathB, pathC} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s not deleted", filepath.Base(p))
+		}
+	}
+}
+
+func TestTouchCachedPackageIgnoresOutsidePaths(t *testing.T) {
+	cacheDir := t.TempDir()
+	if err := TouchCachedPackage(cacheDir, filepath.Join(t.TempDir(), "elsewhere.tar")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cacheIndexPath(cacheDir)); !os.IsNotExist(err) {
+		t.Errorf("index written for a path outside the store")
+	}
+}
+
+func TestMigrateLegacyCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	writeLegacy := func(rel, content string) string {
+		path := filepath.Join(cacheDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	shared := writeLegacy(filepath.Join("ubuntu", "24.04", "ubuntu.tar"), "ubuntu rootfs")
+	// Same content as an already migrated file: the copy is dropped
+	dup := writeLegacy(filepath.Join("debian", "12", "debian.tar"), "ubuntu rootfs")
+	outside := filepath.Join(t.TempDir(), "mine.tar")
+	if err := os.WriteFile(outside, []byte("user file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	distros := map[string]model.DistroConfig{
+		"ubuntu": {Name: "Ubuntu", Versions: map[string]model.Version{
+			"24.04":  {Name: "24.04", Url: "https://example.com/ubuntu.tar", LocalPath: shared},
+			"latest": {Name: "latest", Url: "https://example.com/ubuntu.tar", LocalPath: shared},
+			"gone":   {Name: "gone", LocalPath: filepath.Join(cacheDir, "ubuntu", "gone", "gone.tar")},
+			"user":   {Name: "user", LocalPath: outside},
+		}},
+		"debian": {Name: "Debian", Versions: map[string]model.Version{
+			"12": {Name: "12", Url: "https://example.com/debian.tar", LocalPath: dup},
+		}},
+	}
+
+	count, err := migrateLegacyCache(cacheDir, distros)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("migrated %d files, want 2", count)
+	}
+
+	sum := sha256.Sum256([]byte("ubuntu rootfs"))
+	hash := hex.EncodeToString(sum[:])
+	for _, ref := range []struct{ family, version string }{{"ubuntu", "24.04"}, {"ubuntu", "latest"}, {"debian", "12"}} {
+		v := distros[ref.family].Versions[ref.version]
+		if v.Sha256 != hash || cacheHashOf(cacheDir, v.LocalPath) != hash {
+			t.Errorf("%s %s not moved into the store: %+v", ref.family, ref.version, v)
+		}
+		if _, err := os.Stat(v.LocalPath); err != nil {
+			t.Errorf("%s %s: %v", ref.family, ref.version, err)
+		}
+	}
+	if v := distros["ubuntu"].Versions["gone"]; v.LocalPath != "" {
+		t.Errorf("dangling reference kept: %+v", v)
+	}
+	if v := distros["ubuntu"].Versions["user"]; v.LocalPath != outside || v.Sha256 != "" {
+		t.Errorf("file outside the cache touched: %+v", v)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("user file moved: %v", err)
+	}
+	for _, dir := range []string{"ubuntu", "debian"} {
+		if _, err := os.Stat(filepath.Join(cacheDir, dir)); !os.IsNotExist(err) {
+			t.Errorf("legacy folder %s left behind", dir)
+		}
+	}
+
+	index, err := readCacheIndex(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Whichever copy was met first is kept; the families are walked in map order
+	if e := index[hash]; (e.Filename != "ubuntu.tar" && e.Filename != "debian.tar") || e.Size != int64(len("ubuntu rootfs")) {
+		t.Errorf("index entry = %+v", e)
+	}
+}
+
+func TestCollectCacheGarbageSharedContent(t *testing.T) {
+	cacheDir := t.TempDir()
+	// Two URLs serving identical content end up as one package with both recorded
+	var hash string
+	for _, url := range []string{"https://mirror-a.example.com/rootfs.tar", "https://mirror-b.example.com/rootfs.tar"} {
+		path := filepath.Join(t.TempDir(), "rootfs.tar")
+		if err := os.WriteFile(path, []byte("same content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		if hash, err = adoptIntoCache(cacheDir, path, url); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index, err := readCacheIndex(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := index[hash]
+	if entry.Url != "https://mirror-b.example.com/rootfs.tar" || len(entry.Urls) != 2 {
+		t.Fatalf("index entry = %+v, want both URLs with mirror-b latest", entry)
+	}
+
+	stored := filepath.Join(cacheDir, cacheBlobDir, hash, "rootfs.tar")
+	distros := testDistros(map[string]model.Version{
+		"a": {Name: "a", Url: "https://mirror-a.example.com/rootfs.tar", LocalPath: stored, Sha256: hash},
+	})
+	custom := []model.CustomPackage{
+		{Name: "Mine", Version: "1", PathOrUrl: "https://mirror-b.example.com/rootfs.tar", LocalPath: stored, Sha256: hash},
+	}
+	res, err := CollectCacheGarbage(cacheDir, distros, custom, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Removed) != 0 || len(res.Unlinked) != 0 {
+		t.Fatalf("removed %v, unlinked %v; both entries still use the package", res.Removed, res.Unlinked)
+	}
+	if distros["ubuntu"].Versions["a"].Sha256 != hash || custom[0].Sha256 != hash {
+		t.Errorf("references dropped: %+v, %+v", distros["ubuntu"].Versions["a"], custom[0])
+	}
+	if _, err := os.Stat(stored); err != nil {
+		t.Errorf("shared package deleted: %v", err)
+	}
+}
@@ -0,0 +1,456 @@
+package logic
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"distronexus-gui/internal/model"
+)
+
+// Archive formats recognised by SniffFormat
+const (
+	FormatGzip    = "gzip"
+	FormatXz      = "xz"
+	FormatBzip2   = "bzip2"
+	FormatZstd    = "zstd"
+	FormatZip     = "zip"
+	FormatTar     = "tar"
+	FormatVhdx    = "vhdx"
+	FormatUnknown = "unknown"
+)
+
+// SniffFormat identifies a package from its first bytes (at least 512 for plain tar)
+func SniffFormat(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return FormatGzip
+	case bytes.HasPrefix(head, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return FormatXz
+	case bytes.HasPrefix(head, []byte("BZh")):
+		return FormatBzip2
+	case bytes.HasPrefix(head, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return FormatZstd
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return FormatZip
+	case bytes.HasPrefix(head, []byte("vhdxfile")):
+		return FormatVhdx
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		return FormatTar
+	}
+	return FormatUnknown
+}
+
+// sniffFile reads the head of a file and returns its format
+func sniffFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return SniffFormat(head[:n]), nil
+}
+
+// PackageCheck is the outcome of VerifyCachedPackage
+type PackageCheck struct {
+	Path   string
+	Format string
+	Size   int64
+	// Problems is empty when the package looks intact
+	Problems []string
+	// Notes are checks that could not be made (e.g. server unreachable)
+	Notes []string
+}
+
+// OK reports whether no problem was found
+func (c PackageCheck) OK() bool {
+	return len(c.Problems) == 0
+}
+
+// VerifyCachedPackage checks a cached package: its size against the server's Content-Length,
+// its SHA-256 against expectedHash (when set), and that the archive can be read to the end
+func VerifyCachedPackage(ctx context.Context, path, expectedHash, url string, onOutput func(string)) (PackageCheck, error) {
+	check := PackageCheck{Path: path}
+	log := func(s string) {
+		if onOutput != nil {
+			onOutput(s)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			check.Problems = append(check.Problems, "file is missing")
+			return check, nil
+		}
+		return check, err
+	}
+	check.Size = info.Size()
+	if check.Size == 0 {
+		check.Problems = append(check.Problems, "file is empty")
+		return check, nil
+	}
+
+	// 1. Size reported by the server
+	if url != "" && (strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")) {
+		if length, err := remoteContentLength(ctx, url); err != nil {
+			check.Notes = append(check.Notes, fmt.Sprintf("server size unavailable: %v", err))
+		} else if length > 0 && length != check.Size {
+			check.Problems = append(check.Problems, fmt.Sprintf("size %d bytes, server reports %d", check.Size, length))
+		}
+	}
+
+	// 2. Archive structure, hashing the file in the same pass
+	if check.Format, err = sniffFile(path); err != nil {
+		return check, err
+	}
+	log(fmt.Sprintf("Checking %s (%s, %s)...\n", filepath.Base(path), check.Format, FormatBytes(check.Size)))
+	hasher := sha256.New()
+	if problem := verifyArchive(ctx, path, check.Format, check.Size, hasher); problem != "" {
+		check.Problems = append(check.Problems, problem)
+	}
+	if ctx.Err() != nil {
+		return check, ctx.Err()
+	}
+
+	// 3. Recorded checksum
+	if expectedHash != "" {
+		if sum := hex.EncodeToString(hasher.Sum(nil)); !strings.EqualFold(sum, expectedHash) {
+			check.Problems = append(check.Problems, fmt.Sprintf("SHA-256 %s does not match recorded %s", sum[:12], expectedHash[:min(12, len(expectedHash))]))
+		}
+	}
+
+	if check.OK() {
+		log("OK\n")
+	} else {
+		log(fmt.Sprintf("CORRUPT: %s\n", strings.Join(check.Problems, "; ")))
+	}
+	return check, nil
+}
+
+// remoteContentLength asks the server for the package size with a HEAD request
+func remoteContentLength(ctx context.Context, url string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("HTTP %s", resp.Status)
+	}
+	return resp.ContentLength, nil
+}
+
+// verifyArchive reads the whole archive (hashing every byte into h) and returns a problem
+// description, or "" when it is readable
+func verifyArchive(ctx context.Context, path, format string, size int64, h hash.Hash) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return err.Error()
+	}
+	defer f.Close()
+
+	counted := &progressReader{r: io.TeeReader(f, h), ctx: ctx, total: size, stage: StageVerify}
+	// Whatever the format check leaves unread still has to go through the hash
+	defer io.Copy(io.Discard, counted)
+
+	switch format {
+	case FormatGzip:
+		gz, err := gzip.NewReader(counted)
+		if err != nil {
+			return "gzip header invalid: " + err.Error()
+		}
+		defer gz.Close()
+		return checkTarStream(gz, "gzip")
+	case FormatBzip2:
+		return checkTarStream(bzip2.NewReader(counted), "bzip2")
+	case FormatTar:
+		return checkTarStream(counted, "tar")
+	case FormatXz:
+		// No xz decoder in the standard library: check the stream header and footer magic
+		if _, err := io.Copy(io.Discard, counted); err != nil {
+			return err.Error()
+		}
+		footer := make([]byte, 2)
+		if _, err := f.ReadAt(footer, size-2); err != nil || string(footer) != "YZ" {
+			return "xz stream footer missing (truncated download)"
+		}
+		return ""
+	case FormatZstd:
+		return checkZstdFrames(counted)
+	case FormatZip:
+		if _, err := io.Copy(io.Discard, counted); err != nil {
+			return err.Error()
+		}
+		return checkZip(ctx, f, size)
+	}
+	return fmt.Sprintf("unrecognised package format (%s)", filepath.Ext(path))
+}
+
+// checkTarStream walks every tar entry so truncation or a bad checksum shows up, then reads
+// r to its end: the tar reader stops at the end-of-archive marker, and a decompressor only
+// verifies its trailer (gzip CRC and length, bzip2 stream CRC) once it reaches EOF
+func checkTarStream(r io.Reader, layer string) string {
+	tr := tar.NewReader(r)
+	entries := 0
+	for {
+		_, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Sprintf("%s archive broken after %d entries: %v", layer, entries, err)
+		}
+		if _, err := io.Copy(io.Discard, tr); err != nil {
+			return fmt.Sprintf("%s archive truncated after %d entries: %v", layer, entries, err)
+		}
+		entries++
+	}
+	if entries == 0 {
+		return layer + " archive has no entries"
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return fmt.Sprintf("%s stream corrupt after %d entries: %v", layer, entries, err)
+	}
+	return ""
+}
+
+// zstd framing (RFC 8878)
+const (
+	zstdFrameMagic     = 0xFD2FB528
+	zstdSkippableMagic = 0x184D2A50 // low 4 bits are free
+	zstdMaxBlockSize   = 128 << 10
+)
+
+// checkZstdFrames walks the frame and block headers of a zstd stream to its end. There is no
+// zstd decoder in the standard library, so the compressed blocks are skipped rather than
+// decoded; truncation and broken framing still show up.
+func checkZstdFrames(r io.Reader) string {
+	var buf [14]byte
+	frames := 0
+	for {
+		if _, err := io.ReadFull(r, buf[:4]); err != nil {
+			if err == io.EOF && frames > 0 {
+				return ""
+			}
+			return fmt.Sprintf("zstd stream truncated after %d frames", frames)
+		}
+		magic := binary.LittleEndian.Uint32(buf[:4])
+		if magic&^0xF == zstdSkippableMagic {
+			if _, err := io.ReadFull(r, buf[:4]); err != nil {
+				return "zstd skippable frame truncated"
+			}
+			if _, err := io.CopyN(io.Discard, r, int64(binary.LittleEndian.Uint32(buf[:4]))); err != nil {
+				return "zstd skippable frame truncated"
+			}
+			continue
+		}
+		if magic != zstdFrameMagic {
+			return fmt.Sprintf("zstd frame %d has a bad magic number", frames+1)
+		}
+		frames++
+
+		// Frame header: descriptor, then window size, dictionary ID and content size fields
+		if _, err := io.ReadFull(r, buf[:1]); err != nil {
+			return fmt.Sprintf("zstd frame %d header truncated", frames)
+		}
+		fhd := buf[0]
+		if fhd&0x08 != 0 {
+			return fmt.Sprintf("zstd frame %d header invalid", frames)
+		}
+		singleSegment := fhd&0x20 != 0
+		headerLen := []int{0, 1, 2, 4}[fhd&0x03] + []int{0, 2, 4, 8}[fhd>>6]
+		if !singleSegment {
+			headerLen++ // window descriptor
+		} else if fhd>>6 == 0 {
+			headerLen++ // one-byte content size
+		}
+		if _, err := io.ReadFull(r, buf[:headerLen]); err != nil {
+			return fmt.Sprintf("zstd frame %d header truncated", frames)
+		}
+
+		for blocks := 0; ; blocks++ {
+			if _, err := io.ReadFull(r, buf[:3]); err != nil {
+				return fmt.Sprintf("zstd frame %d truncated after %d blocks", frames, blocks)
+			}
+			bh := uint32(buf[0]) | uint32(buf[1])<<8 | uint32(buf[2])<<16
+			last, kind, blockSize := bh&1 != 0, (bh>>1)&3, int64(bh>>3)
+			switch {
+			case kind == 3:
+				return fmt.Sprintf("zstd frame %d has a reserved block type", frames)
+			case kind == 1:
+				blockSize = 1 // RLE: one byte repeated blockSize times
+			case blockSize > zstdMaxBlockSize:
+				return fmt.Sprintf("zstd frame %d has an oversized block", frames)
+			}
+			if _, err := io.CopyN(io.Discard, r, blockSize); err != nil {
+				return fmt.Sprintf("zstd frame %d truncated after %d blocks", frames, blocks)
+			}
+			if last {
+				break
+			}
+		}
+		if fhd&0x04 != 0 {
+			if _, err := io.ReadFull(r, buf[:4]); err != nil {
+				return fmt.Sprintf("zstd frame %d checksum missing", frames)
+			}
+		}
+	}
+}
+
+// checkZip validates a .zip/.appx/.msix or bundle: the central directory must be readable,
+// every entry must decompress with a matching CRC and it must contain a root filesystem or
+// an inner package
+func checkZip(ctx context.Context, f *os.File, size int64) string {
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return "zip structure invalid: " + err.Error()
+	}
+	if kind := zipPackageKind(zr); kind == FormatZip {
+		return "package contains neither install.tar.gz nor an .appx/.msix"
+	}
+	for _, zf := range zr.File {
+		if ctx.Err() != nil {
+			return ""
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return fmt.Sprintf("zip entry %s unreadable: %v", zf.Name, err)
+		}
+		_, err = io.Copy(io.Discard, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Sprintf("zip entry %s corrupt: %v", zf.Name, err)
+		}
+	}
+	return ""
+}
+
+// progressReader reports read progress as stage and stops on cancellation
+type progressReader struct {
+	r     io.Reader
+	ctx   context.Context
+	stage string
+	total int64
+	read  int64
+	last  int
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	if err := p.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	if p.total > 0 {
+		if pct := int(p.read * 100 / p.total); pct > p.last {
+			p.last = pct
+			ReportProgress(p.ctx, ProgressEvent{Stage: p.stage, Percent: float64(pct)})
+		}
+	}
+	return n, err
+}
+
+// MarkCachedPackage records the verification result in the cache index; problem is ""
+// for an intact package. Paths outside the store are ignored.
+func MarkCachedPackage(cacheDir, path, problem string) error {
+	hash := cacheHashOf(cacheDir, path)
+	if hash == "" {
+		return nil
+	}
+	cacheIndexMu.Lock()
+	defer cacheIndexMu.Unlock()
+	index, err := readCacheIndex(cacheDir)
+	if err != nil {
+		return err
+	}
+	entry := index[hash]
+	if entry.Filename == "" {
+		entry.Filename = filepath.Base(path)
+	}
+	entry.Corrupt = problem
+	entry.VerifiedAt = time.Now().UTC()
+	index[hash] = entry
+	return writeCacheIndex(cacheDir, index)
+}
+
+// CorruptPackages returns the stored packages marked corrupt, by hash
+func CorruptPackages(cacheDir string) (map[string]string, error) {
+	cacheIndexMu.Lock()
+	defer cacheIndexMu.Unlock()
+	index, err := readCacheIndex(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	corrupt := make(map[string]string)
+	for hash, e := range index {
+		if e.Corrupt != "" {
+			corrupt[hash] = e.Corrupt
+		}
+	}
+	return corrupt, nil
+}
+
+// DiscardCachedPackage deletes a (corrupt) package and drops every reference to it, so the
+// next download fetches a fresh copy. distros and custom are updated in place; the caller saves them.
+func DiscardCachedPackage(cacheDir string, distros map[string]model.DistroConfig, custom []model.CustomPackage, family, version string) error {
+	fam, ok := distros[family]
+	if !ok {
+		return fmt.Errorf("unknown family %s", family)
+	}
+	ver, ok := fam.Versions[version]
+	if !ok {
+		return fmt.Errorf("unknown version %s", version)
+	}
+	path, hash := ver.LocalPath, ver.Sha256
+	if path == "" {
+		return nil
+	}
+	for famKey, f := range distros {
+		for verKey, v := range f.Versions {
+			if (hash != "" && v.Sha256 == hash) || strings.EqualFold(v.LocalPath, path) {
+				unlinkVersion(distros, famKey, verKey)
+			}
+		}
+	}
+	for i, cp := range custom {
+		if hash != "" && cp.Sha256 == hash {
+			unlinkCustom(custom, i)
+		}
+	}
+	if hash != "" {
+		return removeBlob(cacheDir, hash)
+	}
+	if isInsideDir(cacheDir, path) {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
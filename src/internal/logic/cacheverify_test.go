@@ -0,0 +1,218 @@
+package logic
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testdata for formats the standard library cannot write: a tar holding hello.txt,
+// compressed with bzip2 -9 and zstd -19
+const (
+	testTarBz2Hex = "425a68393141592653597c285acb0000817b90ca14004040017780008063469e40040000082000741a102069a313434c4125134d1a006807a83e6564284208d29091742f71de194aa4087219e0dc33f4888146c8c829f44d9705ca818e3819b5c64627527a0c771161131d0085f5a73d55768c7a8cbe38891101f8bb9229c28483e142d658"
+	testTarZstHex = "28b52ffd640027ed020072440e12a0bb01008a6bf22750d5dcedd6d6f7dd9b29070de1498d8b19c23733cb3c33dddc9ff8ddb29a57a15a76901d7ab299792148be5f0756eaa40c00e6e531493f86038400244701f82130140e80f0f3eee42e02605000b09b4017cc7bc60a"
+	// zstdBlockHeader is the offset of the first block header in testTarZstHex
+	zstdBlockHeader = 7
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func testTar(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		content := files[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Format: tar.FormatUSTAR}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testGzip(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type testZipEntry struct {
+	name   string
+	data   []byte
+	method uint16
+}
+
+func testZip(t *testing.T, entries ...testZipEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Method: e.method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(e.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// flip returns a copy of data with the byte at i inverted
+func flip(data []byte, i int) []byte {
+	out := bytes.Clone(data)
+	if i < 0 {
+		i += len(out)
+	}
+	out[i] ^= 0xff
+	return out
+}
+
+func TestVerifyCachedPackage(t *testing.T) {
+	tarData := testTar(t, map[string]string{"etc/os-release": "ID=test\n", "bin/sh": strings.Repeat("x", 3000)})
+	gzData := testGzip(t, tarData)
+	bz2Data := mustHex(t, testTarBz2Hex)
+	zstData := mustHex(t, testTarZstHex)
+	zipData := testZip(t, testZipEntry{"install.tar.gz", gzData, zip.Deflate}, testZipEntry{"AppxManifest.xml", []byte("<Package/>"), zip.Deflate})
+	storedZip := testZip(t, testZipEntry{"install.tar.gz", gzData, zip.Store})
+
+	reservedBlock := bytes.Clone(zstData)
+	reservedBlock[zstdBlockHeader] |= 0x06
+
+	tests := []struct {
+		name    string
+		data    []byte
+		format  string
+		problem string // "" when the package is intact
+	}{
+		{"tar", tarData, FormatTar, ""},
+		{"tar truncated", tarData[:1200], FormatTar, "tar archive truncated after 0 entries"},
+		{"tar bad header checksum", flip(tarData, 0), FormatTar, "tar archive broken after 0 entries"},
+		{"tar empty", testTar(t, nil), FormatUnknown, "unrecognised package format"},
+
+		{"gzip", gzData, FormatGzip, ""},
+		{"gzip truncated", gzData[:len(gzData)/2], FormatGzip, "gzip archive"},
+		{"gzip missing trailer", gzData[:len(gzData)-4], FormatGzip, "gzip stream corrupt"},
+		{"gzip bad crc", flip(gzData, -8), FormatGzip, "gzip stream corrupt"},
+		{"gzip bad header", flip(gzData, 3), FormatGzip, "gzip header invalid"},
+		{"gzip no entries", testGzip(t, testTar(t, nil)), FormatGzip, "gzip archive has no entries"},
+
+		{"bzip2", bz2Data, FormatBzip2, ""},
+		{"bzip2 truncated", bz2Data[:len(bz2Data)-10], FormatBzip2, "bzip2"},
+		{"bzip2 bad stream crc", flip(bz2Data, -3), FormatBzip2, "bzip2"},
+
+		{"zstd", zstData, FormatZstd, ""},
+		{"zstd truncated block", zstData[:len(zstData)-30], FormatZstd, "zstd frame 1 truncated after 0 blocks"},
+		{"zstd missing checksum", zstData[:len(zstData)-2], FormatZstd, "zstd frame 1 checksum missing"},
+		{"zstd header truncated", zstData[:6], FormatZstd, "zstd frame 1 header truncated"},
+		{"zstd reserved block", reservedBlock, FormatZstd, "reserved block type"},
+		{"zstd trailing garbage", append(bytes.Clone(zstData), "junk"...), FormatZstd, "zstd frame 2 has a bad magic number"},
+		{"zstd two frames", append(bytes.Clone(zstData), zstData...), FormatZstd, ""},
+
+		{"zip", zipData, FormatZip, ""},
+		{"zip stored", storedZip, FormatZip, ""},
+		{"zip truncated", zipData[:len(zipData)/2], FormatZip, "zip structure invalid"},
+		{"zip bad entry crc", flip(storedZip, 30+len("install.tar.gz")+20), FormatZip, "zip entry install.tar.gz corrupt"},
+		{"zip without rootfs", testZip(t, testZipEntry{"readme.txt", []byte("hi"), zip.Deflate}), FormatZip, "neither install.tar.gz nor"},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-"))
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			sum := sha256.Sum256(tt.data)
+			check, err := VerifyCachedPackage(context.Background(), path, hex.EncodeToString(sum[:]), "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if check.Format != tt.format {
+				t.Errorf("format = %s, want %s", check.Format, tt.format)
+			}
+			if tt.problem == "" {
+				if !check.OK() {
+					t.Fatalf("unexpected problems: %v", check.Problems)
+				}
+				return
+			}
+			if len(check.Problems) != 1 || !strings.Contains(check.Problems[0], tt.problem) {
+				t.Fatalf("problems = %q, want one containing %q", check.Problems, tt.problem)
+			}
+		})
+	}
+}
+
+func TestVerifyCachedPackageChecksum(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rootfs.tar")
+	data := testTar(t, map[string]string{"etc/hostname": "test\n"})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	check, err := VerifyCachedPackage(context.Background(), path, strings.Repeat("0", 64), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(check.Problems) != 1 || !strings.Contains(check.Problems[0], "does not match recorded") {
+		t.Fatalf("problems = %q, want a checksum mismatch", check.Problems)
+	}
+
+	// The hash covers the whole file, not just the part the format check read
+	sum := sha256.Sum256(data)
+	if check, err := VerifyCachedPackage(context.Background(), path, strings.ToUpper(hex.EncodeToString(sum[:])), "", nil); err != nil || !check.OK() {
+		t.Fatalf("matching hash: problems %q, err %v", check.Problems, err)
+	}
+
+	for name, want := range map[string]string{"missing.tar": "file is missing", "empty.tar": "file is empty"} {
+		p := filepath.Join(dir, name)
+		if name == "empty.tar" {
+			if err := os.WriteFile(p, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		check, err := VerifyCachedPackage(context.Background(), p, "", "", nil)
+		if err != nil || len(check.Problems) != 1 || check.Problems[0] != want {
+			t.Errorf("%s: problems %q, err %v, want %q", name, check.Problems, err, want)
+		}
+	}
+}
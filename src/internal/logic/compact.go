@@ -0,0 +1,249 @@
+package logic
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// HostToolRunner compacts a VHDX file with a Windows host tool
+type HostToolRunner interface {
+	// Name identifies the tool in logs and history ("Optimize-VHD", "diskpart")
+	Name() string
+	// Available reports whether the tool can be used on this machine
+	Available(ctx context.Context) bool
+	// Compact shrinks the (detached) VHDX at path
+	Compact(ctx context.Context, vhdxPath string, onOutput func(string)) error
+}
+
+// DefaultHostToolRunners lists the supported tools in order of preference.
+// Optimize-VHD needs the Hyper-V PowerShell module; diskpart ships with every Windows.
+func DefaultHostToolRunners() []HostToolRunner {
+	return []HostToolRunner{optimizeVHDRunner{}, diskpartRunner{}}
+}
+
+// SelectHostToolRunner returns the first available runner
+func SelectHostToolRunner(ctx context.Context) (HostToolRunner, error) {
+	for _, r := range DefaultHostToolRunners() {
+		if r.Available(ctx) {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("no VHDX compaction tool available (need Optimize-VHD or diskpart)")
+}
+
+// CompactEstimate is the dry-run result: how much a compaction could reclaim
+type CompactEstimate struct {
+	VhdxBytes int64
+	UsedBytes int64
+	// Reclaimable is VhdxBytes - UsedBytes; the real gain is usually a little lower
+	Reclaimable int64
+}
+
+// CompactResult reports the sizes around a compaction
+type CompactResult struct {
+	Tool        string
+	BeforeBytes int64
+	AfterBytes  int64
+}
+
+// Saved returns the bytes reclaimed
+func (r CompactResult) Saved() int64 {
+	return r.BeforeBytes - r.AfterBytes
+}
+
+// EstimateCompaction compares the VHDX file size with the space used inside the
+// instance's filesystem. The instance is started if needed to run df.
+func EstimateCompaction(ctx context.Context, name, basePath string, onOutput func(string)) (CompactEstimate, error) {
+	var est CompactEstimate
+	size, err := GetDistroSizeBytes(basePath)
+	if err != nil {
+		return est, fmt.Errorf("cannot read VHDX size: %w", err)
+	}
+	est.VhdxBytes = size
+
+	out, err := runWsl(ctx, nil, "-d", name, "-u", "root", "--", "df", "-B1", "--output=used", "/")
+	if err != nil {
+		return est, fmt.Errorf("cannot measure used space inside '%s': %w", name, err)
+	}
+	used, err := parseDfUsed(out)
+	if err != nil {
+		return est, err
+	}
+	est.UsedBytes = used
+	if est.VhdxBytes > used {
+		est.Reclaimable = est.VhdxBytes - used
+	}
+	if onOutput != nil {
+		onOutput(fmt.Sprintf("VHDX file: %s, used inside: %s, estimated reclaimable: ~%s\n",
+			FormatBytes(est.VhdxBytes), FormatBytes(est.UsedBytes), FormatBytes(est.Reclaimable)))
+	}
+	return est, nil
+}
+
+// parseDfUsed reads the byte count from `df -B1 --output=used` (a header line then the value)
+func parseDfUsed(out string) (int64, error) {
+	lines := strings.Fields(out)
+	if len(lines) == 0 {
+		return 0, fmt.Errorf("unexpected df output")
+	}
+	used, err := strconv.ParseInt(lines[len(lines)-1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected df output %q", out)
+	}
+	return used, nil
+}
+
+// CompactDistro trims the filesystem inside the instance, stops it and compacts its VHDX
+// with runner (chosen automatically when nil)
+func CompactDistro(ctx context.Context, projectRoot, name, basePath string, runner HostToolRunner, onOutput func(string)) (res CompactResult, err error) {
+	// Filled in as the sizes become known; recorded when the operation ends
+	params := map[string]string{}
+	defer trackOperation(projectRoot, OpCompact, name, params)(&err)
+
+	if runner == nil {
+		if runner, err = SelectHostToolRunner(ctx); err != nil {
+			return res, err
+		}
+	}
+	res.Tool = runner.Name()
+	params["Tool"] = res.Tool
+	log := func(s string) {
+		if onOutput != nil {
+			onOutput(s)
+		}
+	}
+
+	vhdx := filepath.Join(basePath, "ext4.vhdx")
+	if res.BeforeBytes, err = GetDistroSizeBytes(basePath); err != nil {
+		return res, fmt.Errorf("cannot read VHDX size: %w", err)
+	}
+	params["Before"] = FormatBytes(res.BeforeBytes)
+	stages := []string{StageTrim, StageStop, StageCompact}
+
+	// 1. Let the filesystem tell the virtual disk which blocks are free
+	ReportProgress(ctx, ProgressEvent{Stage: StageTrim, Percent: -1, Message: "Running fstrim inside " + name, Stages: stages})
+	log("Running fstrim inside the instance...\n")
+	if _, err := runWsl(ctx, onOutput, "-d", name, "-u", "root", "--", "fstrim", "-v", "/"); err != nil {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		// Not fatal: compaction still reclaims whatever was already discarded
+		log(fmt.Sprintf("WARNING: fstrim failed: %v\n", err))
+	}
+
+	// 2. The VHDX must not be attached while compacting
+	ReportProgress(ctx, ProgressEvent{Stage: StageStop, Percent: -1, Message: "Stopping " + name})
+	log("Stopping the instance...\n")
+	if _, err = runWsl(ctx, onOutput, "--terminate", name); err != nil {
+		return res, err
+	}
+
+	// 3. Compact
+	ReportProgress(ctx, ProgressEvent{Stage: StageCompact, Percent: -1, Message: "Compacting with " + runner.Name()})
+	log(fmt.Sprintf("Compacting %s with %s...\n", vhdx, runner.Name()))
+	if err = runner.Compact(ctx, vhdx, onOutput); err != nil {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		return res, fmt.Errorf("%s failed: %w (if the disk is still in use, run 'Shut down all WSL' and retry)", runner.Name(), err)
+	}
+
+	if res.AfterBytes, err = GetDistroSizeBytes(basePath); err != nil {
+		return res, fmt.Errorf("cannot read VHDX size: %w", err)
+	}
+	params["After"] = FormatBytes(res.AfterBytes)
+	ReportProgress(ctx, ProgressEvent{Stage: StageCompact, Percent: 100, Message: "Compaction complete"})
+	log(fmt.Sprintf("Before: %s, after: %s, reclaimed: %s\n", FormatBytes(res.BeforeBytes), FormatBytes(res.AfterBytes), FormatBytes(res.Saved())))
+	return res, nil
+}
+
+// optimizeVHDRunner uses the Hyper-V Optimize-VHD cmdlet
+type optimizeVHDRunner struct{}
+
+func (optimizeVHDRunner) Name() string { return "Optimize-VHD" }
+
+func (optimizeVHDRunner) Available(ctx context.Context) bool {
+	return runHostPowerShell(ctx, "if (Get-Command Optimize-VHD -ErrorAction SilentlyContinue) { exit 0 } else { exit 1 }", nil) == nil
+}
+
+func (optimizeVHDRunner) Compact(ctx context.Context, vhdxPath string, onOutput func(string)) error {
+	return runElevated(ctx, fmt.Sprintf("Optimize-VHD -Path '%s' -Mode Full", psQuote(vhdxPath)), onOutput)
+}
+
+// diskpartRunner uses diskpart's compact vdisk through a generated script
+type diskpartRunner struct{}
+
+func (diskpartRunner) Name() string { return "diskpart" }
+
+func (diskpartRunner) Available(ctx context.Context) bool {
+	_, err := exec.LookPath("diskpart.exe")
+	return err == nil
+}
+
+func (diskpartRunner) Compact(ctx context.Context, vhdxPath string, onOutput func(string)) error {
+	script, err := os.CreateTemp("", "distronexus-compact-*.txt")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(script.Name())
+	fmt.Fprintf(script, "select vdisk file=\"%s\"\r\nattach vdisk readonly\r\ncompact vdisk\r\ndetach vdisk\r\n", vhdxPath)
+	if err := script.Close(); err != nil {
+		return err
+	}
+	return runElevated(ctx, fmt.Sprintf("diskpart /s '%s'", psQuote(script.Name())), onOutput)
+}
+
+// runElevated runs a PowerShell command as administrator (UAC prompt when not elevated)
+// and fails if it exits non-zero. Elevated output cannot be captured, only the exit code.
+func runElevated(ctx context.Context, command string, onOutput func(string)) error {
+	wrapper := fmt.Sprintf(`$ErrorActionPreference = 'Stop'
+$isAdmin = ([Security.Principal.WindowsPrincipal][Security.Principal.WindowsIdentity]::GetCurrent()).IsInRole([Security.Principal.WindowsBuiltInRole]::Administrator)
+if ($isAdmin) {
+    %s
+    if ($LASTEXITCODE) { exit $LASTEXITCODE }
+    exit 0
+}
+$p = Start-Process powershell.exe -Verb RunAs -Wait -PassThru -WindowStyle Hidden -ArgumentList @('-NoProfile', '-Command', '$ErrorActionPreference = ''Stop''; %s; exit $LASTEXITCODE')
+exit $p.ExitCode`, command, psQuote(command))
+	return runHostPowerShell(ctx, wrapper, onOutput)
+}
+
+// runHostPowerShell runs an inline PowerShell command, streaming its output
+func runHostPowerShell(ctx context.Context, command string, onOutput func(string)) error {
+	cmd := exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-ExecutionPolicy", "Bypass", "-Command", command)
+	prepareCmd(cmd)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if onOutput != nil {
+			onOutput(scanner.Text() + "\n")
+		}
+	}
+	io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// psQuote escapes a value for use inside a single-quoted PowerShell string
+func psQuote(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
@@ -0,0 +1,136 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"distronexus-gui/internal/model"
+)
+
+// IsPackageUrl reports whether a custom package source is an http(s) URL rather than a local file
+func IsPackageUrl(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// CheckPackageSource verifies that a custom package source can be used: a local path must be
+// an existing file, a URL must answer a HEAD request
+func CheckPackageSource(ctx context.Context, source string) error {
+	if !IsPackageUrl(source) {
+		info, err := os.Stat(source)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("file %s does not exist", source)
+			}
+			return err
+		}
+		if info.IsDir() {
+			return fmt.Errorf("%s is a folder, not a package file", source)
+		}
+		return nil
+	}
+	if _, err := remoteContentLength(ctx, source); err != nil {
+		return fmt.Errorf("%s is not reachable: %w", source, err)
+	}
+	return nil
+}
+
+// CustomPackageSource returns what to install a custom package from: the cached copy when
+// there is one, otherwise its configured path or URL
+func CustomPackageSource(pkg model.CustomPackage) string {
+	if pkg.LocalPath != "" {
+		if _, err := os.Stat(pkg.LocalPath); err == nil {
+			return pkg.LocalPath
+		}
+	}
+	return pkg.PathOrUrl
+}
+
+// DownloadCustomPackage downloads a custom package's URL into the content-addressed store.
+// pkg.LocalPath and pkg.Sha256 are set on success; the caller saves the settings.
+// Local files are used where they are and need no download.
+func DownloadCustomPackage(ctx context.Context, projectRoot, cacheDir string, pkg *model.CustomPackage, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpDownload, "", map[string]string{"Custom": pkg.Name + " " + pkg.Version, "Source": pkg.PathOrUrl})(&err)
+
+	if !IsPackageUrl(pkg.PathOrUrl) {
+		return fmt.Errorf("%s is a local file; only URLs are downloaded", pkg.PathOrUrl)
+	}
+	log := func(s string) {
+		if onOutput != nil {
+			onOutput(s)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pkg.PathOrUrl, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed: HTTP %s", resp.Status)
+	}
+
+	// Download next to the store so adopting it is a rename on the same volume
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp(cacheDir, "custom-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	target := filepath.Join(tmpDir, packageFileName(pkg.PathOrUrl))
+	partial := target + ".partial"
+	f, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+	log(fmt.Sprintf("Downloading %s...\n", pkg.PathOrUrl))
+	ReportProgress(ctx, ProgressEvent{Stage: StageDownload, Percent: 0, Message: "Downloading " + pkg.Name})
+	_, err = io.Copy(f, &progressReader{r: resp.Body, ctx: ctx, total: resp.ContentLength, stage: StageDownload})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(partial, target); err != nil {
+		return err
+	}
+
+	hash, err := adoptIntoCache(cacheDir, target, pkg.PathOrUrl)
+	if err != nil {
+		return err
+	}
+	files, err := os.ReadDir(filepath.Join(cacheDir, cacheBlobDir, hash))
+	if err != nil || len(files) == 0 {
+		return fmt.Errorf("cache entry %s missing after download", hash)
+	}
+	pkg.LocalPath = filepath.Join(cacheDir, cacheBlobDir, hash, files[0].Name())
+	pkg.Sha256 = hash
+	ReportProgress(ctx, ProgressEvent{Stage: StageDownload, Percent: 100, Message: "Package ready"})
+	log(fmt.Sprintf("Stored as %s\n", pkg.LocalPath))
+	return nil
+}
+
+// packageFileName derives the cached file name from a URL's last path segment
+func packageFileName(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err == nil {
+		if name := path.Base(u.Path); name != "" && name != "." && name != "/" {
+			return name
+		}
+	}
+	return "package.tar.gz"
+}
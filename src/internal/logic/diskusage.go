@@ -0,0 +1,325 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiskSampleInterval is how often RunDiskSampler records instance and cache sizes
+const DiskSampleInterval = time.Hour
+
+// diskSampleRetention bounds the time series file; older samples are dropped
+const diskSampleRetention = 90 * 24 * time.Hour
+
+// DiskSample is one point of the disk usage time series
+type DiskSample struct {
+	Time       time.Time           `json:"Time"`
+	Instances  []InstanceDiskUsage `json:"Instances"`
+	CachePath  string              `json:"CachePath,omitempty"`
+	CacheBytes int64               `json:"CacheBytes"`
+}
+
+// InstanceDiskUsage is the ext4.vhdx size of one instance at sample time
+type InstanceDiskUsage struct {
+	Name     string `json:"Name"`
+	BasePath string `json:"BasePath"`
+	Bytes    int64  `json:"Bytes"`
+}
+
+// Total returns the bytes used by all instances plus the cache
+func (s DiskSample) Total() int64 {
+	total := s.CacheBytes
+	for _, inst := range s.Instances {
+		total += inst.Bytes
+	}
+	return total
+}
+
+var diskUsageMu sync.Mutex
+
+func diskUsagePath(projectRoot string) string {
+	return filepath.Join(projectRoot, "config", "disk_usage.jsonl")
+}
+
+// SampleDiskUsage measures every instance's VHDX and the cache directory
+func SampleDiskUsage(projectRoot, cachePath string) (DiskSample, error) {
+	sample := DiskSample{Time: time.Now(), CachePath: cachePath}
+	distros, err := ListDistros(projectRoot, false)
+	if err != nil {
+		return sample, err
+	}
+	for _, d := range distros {
+		size, err := GetDistroSizeBytes(d.BasePath)
+		if err != nil {
+			continue
+		}
+		sample.Instances = append(sample.Instances, InstanceDiskUsage{Name: d.Name, BasePath: d.BasePath, Bytes: size})
+	}
+	if cachePath != "" {
+		if size, err := DirSize(cachePath); err == nil {
+			sample.CacheBytes = size
+		}
+	}
+	return sample, nil
+}
+
+// RecordDiskSample appends a sample to config/disk_usage.jsonl, dropping samples past retention
+func RecordDiskSample(projectRoot string, sample DiskSample) error {
+	// Held from read to write so concurrent samplers don't rewrite over each other
+	diskUsageMu.Lock()
+	defer diskUsageMu.Unlock()
+
+	samples, err := readDiskSamples(projectRoot)
+	if err != nil {
+		return err
+	}
+
+	path := diskUsagePath(projectRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	cutoff := sample.Time.Add(-diskSampleRetention)
+	if len(samples) > 0 && samples[0].Time.Before(cutoff) {
+		// Rewrite without the expired samples
+		var buf bytes.Buffer
+		for _, s := range samples {
+			if s.Time.Before(cutoff) {
+				continue
+			}
+			line, err := json.Marshal(s)
+			if err != nil {
+				return err
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		line, err := json.Marshal(sample)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+		return os.Rename(tmp, path)
+	}
+
+	line, err := json.Marshal(sample)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// ReadDiskSamples loads the time series, oldest first. Malformed lines are skipped.
+func ReadDiskSamples(projectRoot string) ([]DiskSample, error) {
+	diskUsageMu.Lock()
+	defer diskUsageMu.Unlock()
+	return readDiskSamples(projectRoot)
+}
+
+// readDiskSamples is ReadDiskSamples for callers holding diskUsageMu
+func readDiskSamples(projectRoot string) ([]DiskSample, error) {
+	data, err := os.ReadFile(diskUsagePath(projectRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read disk usage history: %w", err)
+	}
+
+	var samples []DiskSample
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var s DiskSample
+		if err := json.Unmarshal(line, &s); err != nil {
+			continue
+		}
+		samples = append(samples, s)
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	return samples, nil
+}
+
+// RunDiskSampler records a sample every DiskSampleInterval until ctx is cancelled.
+// The first sample is taken right away unless a recent one already exists.
+// cachePath is called for each sample so settings changes are picked up.
+func RunDiskSampler(ctx context.Context, projectRoot string, cachePath func() string) {
+	wait := time.Duration(0)
+	if samples, err := ReadDiskSamples(projectRoot); err == nil && len(samples) > 0 {
+		if since := time.Since(samples[len(samples)-1].Time); since < DiskSampleInterval {
+			wait = DiskSampleInterval - since
+		}
+	}
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		wait = DiskSampleInterval
+
+		sample, err := SampleDiskUsage(projectRoot, cachePath())
+		if err != nil {
+			slog.Debug("disk usage sample failed", "error", err)
+			continue
+		}
+		if err := RecordDiskSample(projectRoot, sample); err != nil {
+			slog.Warn("could not write disk usage sample", "error", err)
+		}
+	}
+}
+
+// DriveOf returns the drive a path lives on ("D:", a \\server\share prefix, or "/" otherwise)
+func DriveOf(path string) string {
+	if len(path) >= 2 && path[1] == ':' {
+		return strings.ToUpper(path[:2])
+	}
+	if strings.HasPrefix(path, `\\`) {
+		parts := strings.SplitN(strings.TrimPrefix(path, `\\`), `\`, 3)
+		if len(parts) >= 2 {
+			return `\\` + parts[0] + `\` + parts[1]
+		}
+	}
+	return "/"
+}
+
+// DriveUsage is the space DistroNexus manages on one drive
+type DriveUsage struct {
+	Drive     string
+	Bytes     int64
+	Instances int
+	HasCache  bool
+}
+
+// UsageByDrive totals a sample per drive, largest first
+func UsageByDrive(s DiskSample) []DriveUsage {
+	byDrive := make(map[string]*DriveUsage)
+	get := func(drive string) *DriveUsage {
+		if d, ok := byDrive[drive]; ok {
+			return d
+		}
+		d := &DriveUsage{Drive: drive}
+		byDrive[drive] = d
+		return d
+	}
+	for _, inst := range s.Instances {
+		d := get(DriveOf(inst.BasePath))
+		d.Bytes += inst.Bytes
+		d.Instances++
+	}
+	if s.CachePath != "" {
+		d := get(DriveOf(s.CachePath))
+		d.Bytes += s.CacheBytes
+		d.HasCache = true
+	}
+
+	list := make([]DriveUsage, 0, len(byDrive))
+	for _, d := range byDrive {
+		list = append(list, *d)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Bytes != list[j].Bytes {
+			return list[i].Bytes > list[j].Bytes
+		}
+		return list[i].Drive < list[j].Drive
+	})
+	return list
+}
+
+// DiskConsumer is an instance (or the cache) with its current size and growth over a window
+type DiskConsumer struct {
+	Name   string
+	Bytes  int64
+	Growth int64
+	// New is true when the consumer has no sample at the start of the window
+	New bool
+}
+
+// CacheConsumerName labels the package cache in TopConsumers
+const CacheConsumerName = "Package cache"
+
+// TopConsumers ranks the latest sample by size and reports growth since the first sample
+// at or after since
+func TopConsumers(samples []DiskSample, since time.Time) []DiskConsumer {
+	if len(samples) == 0 {
+		return nil
+	}
+	latest := samples[len(samples)-1]
+	base := baselineSample(samples, since)
+
+	baseSizes := make(map[string]int64)
+	if base != nil {
+		for _, inst := range base.Instances {
+			baseSizes[inst.Name] = inst.Bytes
+		}
+	}
+
+	var list []DiskConsumer
+	for _, inst := range latest.Instances {
+		c := DiskConsumer{Name: inst.Name, Bytes: inst.Bytes}
+		if b, ok := baseSizes[inst.Name]; ok {
+			c.Growth = inst.Bytes - b
+		} else {
+			c.New = true
+		}
+		list = append(list, c)
+	}
+	if latest.CachePath != "" {
+		c := DiskConsumer{Name: CacheConsumerName, Bytes: latest.CacheBytes}
+		if base != nil {
+			c.Growth = latest.CacheBytes - base.CacheBytes
+		}
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Bytes > list[j].Bytes })
+	return list
+}
+
+// TotalGrowth reports how much the overall total changed since the window start
+func TotalGrowth(samples []DiskSample, since time.Time) (int64, bool) {
+	if len(samples) == 0 {
+		return 0, false
+	}
+	base := baselineSample(samples, since)
+	if base == nil {
+		return 0, false
+	}
+	return samples[len(samples)-1].Total() - base.Total(), true
+}
+
+// baselineSample is the earliest sample inside the window
+func baselineSample(samples []DiskSample, since time.Time) *DiskSample {
+	for i := range samples {
+		if !samples[i].Time.Before(since) {
+			return &samples[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package logic
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecordDiskSampleConcurrent(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	// An expired sample makes every writer take the rewrite path
+	if err := RecordDiskSample(root, DiskSample{Time: now.Add(-2 * diskSampleRetention)}); err != nil {
+		t.Fatal(err)
+	}
+
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := RecordDiskSample(root, DiskSample{Time: now.Add(time.Duration(i) * time.Second), CacheBytes: int64(i)}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	samples, err := ReadDiskSamples(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != writers {
+		t.Fatalf("%d samples kept, want %d", len(samples), writers)
+	}
+	for i, s := range samples {
+		if s.CacheBytes != int64(i) {
+			t.Fatalf("sample %d has CacheBytes %d; samples not sorted or lost", i, s.CacheBytes)
+		}
+	}
+}
+
+func TestRecordDiskSampleDropsExpired(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	for _, age := range []time.Duration{diskSampleRetention + time.Hour, diskSampleRetention - time.Hour} {
+		if err := RecordDiskSample(root, DiskSample{Time: now.Add(-age)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RecordDiskSample(root, DiskSample{Time: now}); err != nil {
+		t.Fatal(err)
+	}
+	samples, err := ReadDiskSamples(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 2 || samples[0].Time.Before(now.Add(-diskSampleRetention)) {
+		t.Fatalf("unexpected samples %+v", samples)
+	}
+}
@@ -0,0 +1,328 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Operation types recorded in the history log
+const (
+	OpInstall        = "install"
+	OpUninstall      = "uninstall"
+	OpDeleteFiles    = "delete_files"
+	OpMove           = "move"
+	OpRename         = "rename"
+	OpSetCredentials = "set_credentials"
+	OpSetMeta        = "set_metadata"
+	OpStart          = "start"
+	OpStop           = "stop"
+	OpShutdown       = "shutdown"
+	OpUpdatePackages = "update_packages"
+	OpBackup         = "backup"
+	OpExportImage    = "export_image"
+	OpCompact        = "compact"
+	OpSetSparse      = "set_sparse"
+	OpResize         = "resize"
+	OpReRegister     = "reregister"
+	OpCleanRegistry  = "clean_registry"
+	OpDownload       = "download"
+	OpUpdateSources  = "update_sources"
+	OpScan           = "scan"
+)
+
+// Operation results recorded in the history log
+const (
+	ResultSuccess  = "success"
+	ResultFailed   = "failed"
+	ResultCanceled = "canceled"
+)
+
+// HistoryEntry is one line of config/history.jsonl
+type HistoryEntry struct {
+	Time       time.Time         `json:"Time"`
+	Operation  string            `json:"Operation"`
+	Instance   string            `json:"Instance,omitempty"`
+	Params     map[string]string `json:"Params,omitempty"`
+	User       string            `json:"User"`
+	Host       string            `json:"Host"`
+	DurationMs int64             `json:"DurationMs"`
+	Result     string            `json:"Result"`
+	Error      string            `json:"Error,omitempty"`
+}
+
+// HistoryFilter selects entries in FilterHistory. Empty fields match everything.
+type HistoryFilter struct {
+	Operation string
+	Result    string
+	Text      string // Case-insensitive match on instance, parameters and error
+	Since     time.Time
+}
+
+const redacted = "***"
+
+var historyMu sync.Mutex
+
+func historyPath(projectRoot string) string {
+	return filepath.Join(projectRoot, "config", "history.jsonl")
+}
+
+// trackOperation records a history entry when the returned function is called with the
+// operation's final error. Typical use:
+//
+//	defer trackOperation(projectRoot, OpStop, name, nil)(&err)
+func trackOperation(projectRoot, op, instance string, params map[string]string) func(*error) {
+	started := time.Now()
+	return func(errp *error) {
+		var err error
+		if errp != nil {
+			err = *errp
+		}
+		recordOperation(projectRoot, op, instance, params, started, err)
+	}
+}
+
+// recordOperation appends an entry to the history log. Failures to write are not fatal
+// for the operation itself and are only logged.
+func recordOperation(projectRoot, op, instance string, params map[string]string, started time.Time, opErr error) {
+	entry := HistoryEntry{
+		Time:       started,
+		Operation:  op,
+		Instance:   instance,
+		Params:     redactParams(params),
+		User:       currentUserName(),
+		Host:       hostName(),
+		DurationMs: time.Since(started).Milliseconds(),
+		Result:     ResultSuccess,
+	}
+	slog.Info("operation finished", "operation", op, "instance", instance, "durationMs", entry.DurationMs, "error", opErr)
+	if opErr != nil {
+		entry.Result = ResultFailed
+		if errors.Is(opErr, context.Canceled) {
+			entry.Result = ResultCanceled
+		}
+		entry.Error = opErr.Error()
+	}
+	observeOperation(op, entry.Result, time.Duration(entry.DurationMs)*time.Millisecond)
+
+	if err := appendHistory(projectRoot, entry); err != nil {
+		slog.Warn("could not write history", "operation", op, "error", err)
+	}
+}
+
+func appendHistory(projectRoot string, entry HistoryEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	path := historyPath(projectRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(line)
+	return err
+}
+
+// ReadHistory loads every entry, newest first. Malformed lines are skipped.
+func ReadHistory(projectRoot string) ([]HistoryEntry, error) {
+	historyMu.Lock()
+	data, err := os.ReadFile(historyPath(projectRoot))
+	historyMu.Unlock()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var e HistoryEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+	return entries, nil
+}
+
+// FilterHistory returns the entries matching every non-empty field of f
+func FilterHistory(entries []HistoryEntry, f HistoryFilter) []HistoryEntry {
+	text := strings.ToLower(strings.TrimSpace(f.Text))
+	var out []HistoryEntry
+	for _, e := range entries {
+		if f.Operation != "" && e.Operation != f.Operation {
+			continue
+		}
+		if f.Result != "" && e.Result != f.Result {
+			continue
+		}
+		if !f.Since.IsZero() && e.Time.Before(f.Since) {
+			continue
+		}
+		if text != "" && !strings.Contains(strings.ToLower(historySearchText(e)), text) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func historySearchText(e HistoryEntry) string {
+	parts := []string{e.Instance, e.Operation, e.User, e.Error, FormatHistoryParams(e.Params)}
+	return strings.Join(parts, " ")
+}
+
+// ExportHistoryCSV writes entries as CSV with a header row
+func ExportHistoryCSV(w io.Writer, entries []HistoryEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"Time", "Operation", "Instance", "Parameters", "User", "Host", "DurationMs", "Result", "Error"}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		rec := []string{
+			e.Time.Format(time.RFC3339),
+			e.Operation,
+			e.Instance,
+			FormatHistoryParams(e.Params),
+			e.User,
+			e.Host,
+			strconv.FormatInt(e.DurationMs, 10),
+			e.Result,
+			e.Error,
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// FormatHistoryParams renders parameters as "key=value; key=value" in key order
+func FormatHistoryParams(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		if params[k] != "" {
+			parts = append(parts, k+"="+params[k])
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// redactParams masks values whose key looks like a secret
+func redactParams(params map[string]string) map[string]string {
+	if len(params) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(params))
+	for k, v := range params {
+		if isSecretKey(k) && v != "" {
+			v = redacted
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// secretWords are the words of a parameter name that mark its value as a credential
+var secretWords = map[string]bool{
+	"password": true, "passwd": true, "pass": true, "passphrase": true, "pwd": true,
+	"secret": true, "token": true, "key": true, "apikey": true, "credential": true, "credentials": true,
+}
+
+// isSecretKey reports whether a parameter name contains a secret word as a whole word,
+// so "RootPassword" and "api_key" are masked but "Monkey" and "Keyboard" are not
+func isSecretKey(key string) bool {
+	for _, w := range keyWords(key) {
+		if secretWords[w] {
+			return true
+		}
+	}
+	return false
+}
+
+// keyWords splits a parameter name into lower-case words at case changes and separators
+// ("RootPassword" -> root, password; "APIKey" -> api, key; "x-auth-token" -> x, auth, token)
+func keyWords(key string) []string {
+	var words []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, strings.ToLower(string(cur)))
+			cur = nil
+		}
+	}
+	runes := []rune(key)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		cur = append(cur, r)
+	}
+	flush()
+	return words
+}
+
+func currentUserName() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USERNAME"); name != "" {
+		return name
+	}
+	return os.Getenv("USER")
+}
+
+func hostName() string {
+	h, _ := os.Hostname()
+	return h
+}
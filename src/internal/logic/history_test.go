@@ -0,0 +1,87 @@
+package logic
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRedactParams(t *testing.T) {
+	tests := []struct {
+		key    string
+		masked bool
+	}{
+		// Parameters the operations record today
+		{"Password", true},
+		{"UserName", false},
+		{"OutputFile", false},
+		{"Reference", false},
+		{"Source", false},
+		{"SourceUrl", false},
+		{"StartPath", false},
+		{"RegistryId", false},
+		// Credential words, as whole words of the name in any case or separator style
+		{"password", true},
+		{"RootPass", true},
+		{"RootPassword", true},
+		{"ClientSecret", true},
+		{"ApiToken", true},
+		{"ApiKey", true},
+		{"APIKey", true},
+		{"api_key", true},
+		{"x-auth-token", true},
+		{"SshKey", true},
+		{"KEY", true},
+		{"Passphrase", true},
+		{"Credentials", true},
+		// Names that merely contain one of those words stay visible
+		{"Monkey", false},
+		{"Keyboard", false},
+		{"KeyboardLayout", false},
+		{"KEYFILE", false},
+		{"Passenger", false},
+		{"Tokenizer", false},
+		{"SecretaryName", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got := redactParams(map[string]string{tt.key: "value"})[tt.key]
+			if masked := got == redacted; masked != tt.masked {
+				t.Fatalf("%s: got %q, masked want %v", tt.key, got, tt.masked)
+			}
+		})
+	}
+}
+
+func TestKeyWords(t *testing.T) {
+	tests := map[string][]string{
+		"RootPassword": {"root", "password"},
+		"APIKey":       {"api", "key"},
+		"api_key":      {"api", "key"},
+		"x-auth-token": {"x", "auth", "token"},
+		"Sha256Sum":    {"sha256", "sum"},
+		"KEY":          {"key"},
+		"":             nil,
+	}
+	for key, want := range tests {
+		if got := keyWords(key); !slices.Equal(got, want) {
+			t.Errorf("keyWords(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestRedactParamsKeepsEmptyValues(t *testing.T) {
+	got := redactParams(map[string]string{"Password": "", "Path": "C:\\WSL"})
+	if got["Password"] != "" || got["Path"] != "C:\\WSL" {
+		t.Fatalf("unexpected %v", got)
+	}
+	if redactParams(nil) != nil {
+		t.Fatal("nil params must stay nil")
+	}
+}
+
+func TestFormatHistoryParams(t *testing.T) {
+	got := FormatHistoryParams(map[string]string{"Path": "D:\\WSL", "Force": "", "Family": "ubuntu"})
+	if want := "Family=ubuntu; Path=D:\\WSL"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -3,15 +3,60 @@ package logic
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"syscall"
+
+	"distronexus-gui/internal/model"
 )
 
-// RunInstallScript executes the PowerShell installation script
-func RunInstallScript(ctx context.Context, projectRoot string, familyName string, versionName string, distroName string, installPath string, user string, pass string, onLog func(string), onFinish func(error)) {
+// RunInstallScript executes the PowerShell installation script. packagePath is the cached
+// package of the version, if any; without it the script downloads the package itself.
+func RunInstallScript(ctx context.Context, projectRoot string, familyName string, versionName string, packagePath string, distroName string, installPath string, user string, pass string, prov *model.ProvisioningSettings, onLog func(string), onFinish func(error)) {
+	var selectArgs []string
+	if familyName != "" {
+		selectArgs = append(selectArgs, "-SelectFamily", familyName)
+	}
+	if versionName != "" {
+		selectArgs = append(selectArgs, "-SelectVersion", versionName)
+	}
+	// A stale cache reference is left to the script, which downloads the package again
+	if _, err := os.Stat(packagePath); err != nil {
+		packagePath = ""
+	}
+	runInstall(ctx, projectRoot, map[string]string{
+		"Family":  familyName,
+		"Version": versionName,
+	}, selectArgs, packagePath, distroName, installPath, user, pass, prov, onLog, onFinish)
+}
+
+// RunInstallFromSource installs a package outside the catalog: a local file or an http(s) URL
+func RunInstallFromSource(ctx context.Context, projectRoot string, source string, distroName string, installPath string, user string, pass string, prov *model.ProvisioningSettings, onLog func(string), onFinish func(error)) {
+	runInstall(ctx, projectRoot, map[string]string{
+		"Source": source,
+	}, nil, source, distroName, installPath, user, pass, prov, onLog, onFinish)
+}
+
+// runInstall runs install_wsl_custom.ps1 with the catalog selection in selectArgs. A local
+// packagePath is unpacked here first so every supported format reaches wsl --import, and
+// the user and provisioning settings are written into it before import where possible;
+// otherwise the script sets the user up after the first boot.
+func runInstall(ctx context.Context, projectRoot string, params map[string]string, selectArgs []string, packagePath string, distroName string, installPath string, user string, pass string, prov *model.ProvisioningSettings, onLog func(string), onFinish func(error)) {
+	params["InstallPath"] = installPath
+	params["User"] = user
+	params["Password"] = pass
+	record := trackOperation(projectRoot, OpInstall, distroName, params)
+	finish := onFinish
+	onFinish = func(err error) {
+		record(&err)
+		finish(err)
+	}
+
 	go func() {
 		scriptPath := filepath.Join(projectRoot, "scripts", "install_wsl_custom.ps1")
 
@@ -22,13 +67,47 @@ func RunInstallScript(ctx context.Context, projectRoot string, familyName string
 			"-ExecutionPolicy", "Bypass",
 			"-File", scriptPath,
 		}
+		args = append(args, selectArgs...)
 
-		if familyName != "" {
-			args = append(args, "-SelectFamily", familyName)
-		}
-		if versionName != "" {
-			args = append(args, "-SelectVersion", versionName)
+		if packagePath != "" && !IsPackageUrl(packagePath) {
+			workDir, err := os.MkdirTemp("", "distronexus-install-*")
+			if err != nil {
+				onFinish(err)
+				return
+			}
+			defer os.RemoveAll(workDir)
+			ReportProgress(ctx, ProgressEvent{Stage: StageExtract, Percent: -1, Message: "Detecting package format..."})
+			prep, err := PreparePackage(ctx, packagePath, workDir, onLog)
+			if err != nil {
+				onFinish(err)
+				return
+			}
+			source := prep.Path
+			if setup := NewRootfsSetup(user, pass, prov); !setup.IsEmpty() {
+				customized := filepath.Join(workDir, "rootfs-custom.tar")
+				err := ErrRootfsFormat
+				if !prep.Vhd {
+					err = CustomizeRootfs(ctx, prep.Path, customized, setup, onLog)
+				}
+				switch {
+				case err == nil:
+					source = customized
+					args = append(args, "-Preconfigured")
+				case errors.Is(err, ErrRootfsFormat):
+					onLog(fmt.Sprintf("%v (%s); the user is set up after the first boot\n", ErrRootfsFormat, prep.Format))
+				default:
+					onFinish(err)
+					return
+				}
+			}
+			args = append(args, "-PackageSource", source)
+			if prep.Vhd {
+				args = append(args, "-Vhd")
+			}
+		} else if packagePath != "" {
+			args = append(args, "-PackageSource", packagePath)
 		}
+
 		if distroName != "" {
 			args = append(args, "-DistroName", distroName)
 		}
@@ -71,17 +150,22 @@ func RunInstallScript(ctx context.Context, projectRoot string, familyName string
 		}
 
 		onLog(fmt.Sprintf("--- Starting Installation: %s ---\n", distroName))
-		onLog(fmt.Sprintf("Command: %s %s\n", cmd.Path, strings.Join(args, " ")))
+		onLog(fmt.Sprintf("Command: %s %s\n", cmd.Path, strings.Join(redactArgs(args), " ")))
+		slog.Info("install started", "distro", distroName, "source", strings.Join(selectArgs, " "), "path", installPath)
 
-		// Read output asynchronously
-		go scanOutput(stdout, onLog)
-		go scanOutput(stderr, onLog)
+		// Read output asynchronously, separating progress events from log lines
+		emit := splitProgress(ctx, onLog)
+		go scanOutput(stdout, emit)
+		go scanOutput(stderr, emit)
 
 		// Wait for completion
 		err = cmd.Wait()
 
 		// Check exit code
-		if err != nil {
+		if err != nil && ctx.Err() != nil {
+			onLog("\n--- Installation Canceled ---\n")
+			onFinish(ctx.Err())
+		} else if err != nil {
 			if exitErr, ok := err.(*exec.ExitError); ok {
 				// The program has exited with an exit code != 0
 				if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
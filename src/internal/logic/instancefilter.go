@@ -0,0 +1,133 @@
+package logic
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sort orders offered by the home and package views
+const (
+	SortByName        = "Name"
+	SortBySize        = "Size"
+	SortByInstallTime = "Install Time"
+)
+
+// InstanceFilter selects instances on the home view. Empty fields match everything.
+type InstanceFilter struct {
+	// Query matches name, release, path, user, tags, owner and note, case-insensitively
+	Query   string
+	State   string
+	WslVer  string
+	Release string
+	Drive   string
+	Tag     string
+}
+
+// Match reports whether inst passes every set criterion
+func (f InstanceFilter) Match(inst WslInstance) bool {
+	if f.State != "" && !strings.EqualFold(inst.State, f.State) {
+		return false
+	}
+	if f.WslVer != "" && inst.WslVer != f.WslVer {
+		return false
+	}
+	if f.Release != "" && inst.Release != f.Release {
+		return false
+	}
+	if f.Drive != "" && !strings.EqualFold(InstanceDrive(inst), f.Drive) {
+		return false
+	}
+	if f.Tag != "" && !HasTag(inst, f.Tag) {
+		return false
+	}
+	fields := append([]string{inst.Name, inst.Release, inst.BasePath, inst.User, inst.Owner, inst.Note}, inst.Tags...)
+	return MatchQuery(f.Query, fields...)
+}
+
+// MatchQuery reports whether every word of query occurs in one of fields (case-insensitive)
+func MatchQuery(query string, fields ...string) bool {
+	hay := strings.ToLower(strings.Join(fields, "\n"))
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(hay, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// InstanceDrive is the drive ("D:") or share (\\server\share) an instance lives on
+func InstanceDrive(inst WslInstance) string {
+	p := strings.TrimPrefix(inst.BasePath, `\\?\`)
+	if len(p) >= 2 && p[1] == ':' {
+		return strings.ToUpper(p[:2])
+	}
+	if rest, ok := strings.CutPrefix(p, `UNC\`); ok {
+		p = `\\` + rest
+	}
+	if share, ok := strings.CutPrefix(p, `\\`); ok {
+		parts := strings.SplitN(share, `\`, 3)
+		if len(parts) >= 2 {
+			return `\\` + strings.ToUpper(parts[0]+`\`+parts[1])
+		}
+	}
+	return ""
+}
+
+// SortInstances orders list by SortByName, SortBySize or SortByInstallTime; ties fall back to the name
+func SortInstances(list []WslInstance, by string, descending bool) {
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		var less, greater bool
+		switch by {
+		case SortBySize:
+			sa, sb := ParseDiskSize(a.DiskSize), ParseDiskSize(b.DiskSize)
+			less, greater = sa < sb, sa > sb
+		case SortByInstallTime:
+			// "yyyy-MM-dd HH:mm:ss" sorts as text
+			less, greater = a.InstallTime < b.InstallTime, a.InstallTime > b.InstallTime
+		}
+		if !less && !greater {
+			la, lb := strings.ToLower(a.Name), strings.ToLower(b.Name)
+			less, greater = la < lb, la > lb
+		}
+		if descending {
+			return greater
+		}
+		return less
+	})
+}
+
+// ParseDiskSize reads the size list_distros.ps1 reports ("1,234.56 GB", or "1.234,56 GB"
+// in locales with a decimal comma). Unknown sizes are -1 so they sort first.
+func ParseDiskSize(s string) int64 {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return -1
+	}
+	mult := map[string]float64{"B": 1, "KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30, "TB": 1 << 40}[strings.ToUpper(fields[len(fields)-1])]
+	if mult == 0 {
+		return -1
+	}
+	// Group separators vary by locale (",", ".", "'", spaces); the separator closest to
+	// the end with at most two digits after it is the decimal one
+	num := strings.Join(fields[:len(fields)-1], "")
+	dec := strings.LastIndexAny(num, ".,")
+	if dec >= 0 && len(num)-dec-1 > 2 {
+		dec = -1
+	}
+	var b strings.Builder
+	for i, r := range num {
+		switch {
+		case i == dec:
+			b.WriteByte('.')
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		}
+	}
+	v, err := strconv.ParseFloat(b.String(), 64)
+	if err != nil {
+		return -1
+	}
+	return int64(v * mult)
+}
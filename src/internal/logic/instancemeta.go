@@ -0,0 +1,153 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// UntaggedGroup is the home view section for instances without tags
+const UntaggedGroup = "Untagged"
+
+// InstanceMeta is the user-maintained part of an instances.json entry
+type InstanceMeta struct {
+	Tags  []string
+	Note  string
+	Owner string
+}
+
+// ParseTags splits comma-separated input into tags, dropping blanks and case-insensitive duplicates
+func ParseTags(text string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	for _, t := range strings.Split(text, ",") {
+		t = strings.Join(strings.Fields(t), " ")
+		if t == "" || seen[strings.ToLower(t)] {
+			continue
+		}
+		seen[strings.ToLower(t)] = true
+		tags = append(tags, t)
+	}
+	return tags
+}
+
+// HasTag reports whether inst carries tag (case-insensitive)
+func HasTag(inst WslInstance, tag string) bool {
+	for _, t := range inst.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// InstanceGroup is one tag section of the home view
+type InstanceGroup struct {
+	Tag       string
+	Instances []WslInstance
+}
+
+// GroupByTag puts every instance under each of its tags, keeping the order of list.
+// Groups are sorted by tag with UntaggedGroup last.
+func GroupByTag(list []WslInstance) []InstanceGroup {
+	index := map[string]int{}
+	var groups []InstanceGroup
+	add := func(tag string, inst WslInstance) {
+		key := strings.ToLower(tag)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, InstanceGroup{Tag: tag})
+		}
+		groups[i].Instances = append(groups[i].Instances, inst)
+	}
+	var untagged []WslInstance
+	for _, inst := range list {
+		if len(inst.Tags) == 0 {
+			untagged = append(untagged, inst)
+			continue
+		}
+		for _, t := range inst.Tags {
+			add(t, inst)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return strings.ToLower(groups[i].Tag) < strings.ToLower(groups[j].Tag)
+	})
+	if len(untagged) > 0 {
+		groups = append(groups, InstanceGroup{Tag: UntaggedGroup, Instances: untagged})
+	}
+	return groups
+}
+
+// SetInstanceMeta stores tags, note and owner of an instance in config/instances.json.
+// Other fields of the file are kept as the scripts wrote them; an instance the file
+// doesn't list yet gets a stub entry that list_distros.ps1 completes.
+func SetInstanceMeta(projectRoot, name string, meta InstanceMeta) (err error) {
+	defer trackOperation(projectRoot, OpSetMeta, name, map[string]string{
+		"Tags":  strings.Join(meta.Tags, ", "),
+		"Owner": meta.Owner,
+	})(&err)
+
+	path := filepath.Join(projectRoot, "config", "instances.json")
+	var entries []map[string]any
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if data = bytes.TrimPrefix(data, []byte("\ufeff")); len(bytes.TrimSpace(data)) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&entries); err != nil {
+			// PowerShell writes a bare object when there is only one instance
+			var single map[string]any
+			dec = json.NewDecoder(bytes.NewReader(data))
+			dec.UseNumber()
+			if err2 := dec.Decode(&single); err2 != nil {
+				return fmt.Errorf("failed to parse instances.json: %w", err)
+			}
+			entries = []map[string]any{single}
+		}
+	}
+
+	var entry map[string]any
+	for _, e := range entries {
+		if n, _ := e["Name"].(string); strings.EqualFold(n, name) {
+			entry = e
+			break
+		}
+	}
+	if entry == nil {
+		entry = map[string]any{"Name": name}
+		entries = append(entries, entry)
+	}
+	set := func(key string, value any, empty bool) {
+		if empty {
+			delete(entry, key)
+		} else {
+			entry[key] = value
+		}
+	}
+	set("Tags", meta.Tags, len(meta.Tags) == 0)
+	set("Note", meta.Note, meta.Note == "")
+	set("Owner", meta.Owner, meta.Owner == "")
+
+	out, err := json.MarshalIndent(entries, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Windows PowerShell reads BOM-less files as ANSI, so keep the BOM the scripts write
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append([]byte("\ufeff"), out...), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
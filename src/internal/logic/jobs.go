@@ -0,0 +1,357 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobStatus is the lifecycle state of a background job
+type JobStatus string
+
+const (
+	JobRunning   JobStatus = "Running"
+	JobSucceeded JobStatus = "Succeeded"
+	JobFailed    JobStatus = "Failed"
+	JobCanceled  JobStatus = "Canceled"
+)
+
+// JobEventType identifies what changed in a JobEvent
+type JobEventType string
+
+const (
+	JobStarted  JobEventType = "started"
+	JobLogLine  JobEventType = "log"
+	JobProgress JobEventType = "progress"
+	JobFinished JobEventType = "finished"
+)
+
+// JobEvent is published to JobManager subscribers
+type JobEvent struct {
+	Type     JobEventType
+	Job      *Job
+	Line     string        // Only set for JobLogLine
+	Progress ProgressEvent // Only set for JobProgress
+}
+
+// JobFunc is the body of a job. It must honour ctx and report output through log.
+type JobFunc func(ctx context.Context, log func(string)) error
+
+const (
+	maxJobLogLines    = 2000
+	maxFinishedJobs   = 50
+	jobLogTruncatedAt = "... earlier output truncated ...\n"
+)
+
+// Job is a tracked, cancellable operation
+type Job struct {
+	ID    string
+	Title string
+	// Instance is the WSL instance the job acts on, if any
+	Instance string
+	Started  time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu           sync.Mutex
+	status       JobStatus
+	finished     time.Time
+	err          error
+	logs         []string
+	truncated    bool
+	followers    map[int]func(string)
+	nextFollower int
+	progress     ProgressEvent
+	hasProgress  bool
+}
+
+// Status returns the current state of the job
+func (j *Job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// Err returns the error the job finished with (nil while running or on success)
+func (j *Job) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// Finished returns when the job ended, or the zero time while it is running
+func (j *Job) Finished() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.finished
+}
+
+// Duration returns the run time so far (or total run time once finished)
+func (j *Job) Duration() time.Duration {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.finished.IsZero() {
+		return time.Since(j.Started)
+	}
+	return j.finished.Sub(j.Started)
+}
+
+// Progress returns the latest progress event, with the stage list announced earlier filled in
+func (j *Job) Progress() (ProgressEvent, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.progress, j.hasProgress
+}
+
+func (j *Job) setProgress(ev ProgressEvent) ProgressEvent {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(ev.Stages) == 0 {
+		ev.Stages = j.progress.Stages
+	}
+	j.progress = ev
+	j.hasProgress = true
+	return ev
+}
+
+// Done is closed when the job has finished
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Cancel requests cancellation; running processes are killed through the job context
+func (j *Job) Cancel() {
+	j.cancel()
+}
+
+// Logs returns the captured output
+func (j *Job) Logs() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var sb strings.Builder
+	if j.truncated {
+		sb.WriteString(jobLogTruncatedAt)
+	}
+	for _, l := range j.logs {
+		sb.WriteString(l)
+	}
+	return sb.String()
+}
+
+// Follow replays the captured output to fn and then forwards every new line until stop is called.
+// fn is called with the job lock held and must not call back into the job.
+func (j *Job) Follow(fn func(string)) (stop func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.truncated {
+		fn(jobLogTruncatedAt)
+	}
+	for _, l := range j.logs {
+		fn(l)
+	}
+	id := j.nextFollower
+	j.nextFollower++
+	j.followers[id] = fn
+	return func() {
+		j.mu.Lock()
+		delete(j.followers, id)
+		j.mu.Unlock()
+	}
+}
+
+func (j *Job) appendLog(line string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.logs = append(j.logs, line)
+	if len(j.logs) > maxJobLogLines {
+		j.logs = j.logs[len(j.logs)-maxJobLogLines:]
+		j.truncated = true
+	}
+	for _, fn := range j.followers {
+		fn(line)
+	}
+}
+
+func (j *Job) finish(err error) {
+	j.mu.Lock()
+	j.finished = time.Now()
+	switch {
+	case err == nil:
+		j.status = JobSucceeded
+	case errors.Is(j.ctx.Err(), context.Canceled):
+		j.status = JobCanceled
+		j.err = fmt.Errorf("canceled: %w", err)
+	default:
+		j.status = JobFailed
+		j.err = err
+	}
+	j.followers = map[int]func(string){}
+	j.mu.Unlock()
+	j.cancel() // release context resources
+	close(j.done)
+}
+
+// JobManager runs and tracks background operations
+type JobManager struct {
+	mu           sync.Mutex
+	seq          int
+	jobs         []*Job
+	subs         map[int]func(JobEvent)
+	nextSub      int
+	keepFinished int
+}
+
+// NewJobManager creates an empty job manager
+func NewJobManager() *JobManager {
+	return &JobManager{
+		subs:         make(map[int]func(JobEvent)),
+		keepFinished: maxFinishedJobs,
+	}
+}
+
+// Start runs fn in a new goroutine under a cancellable context and returns the tracking Job
+func (m *JobManager) Start(title string, fn JobFunc) *Job {
+	return m.StartFor(title, "", fn)
+}
+
+// StartFor is like Start but records the instance the job acts on
+func (m *JobManager) StartFor(title, instance string, fn JobFunc) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	var job *Job
+	ctx = WithProgress(ctx, func(ev ProgressEvent) {
+		ev = job.setProgress(ev)
+		m.publish(JobEvent{Type: JobProgress, Job: job, Progress: ev})
+	})
+
+	m.mu.Lock()
+	m.seq++
+	job = &Job{
+		ID:        fmt.Sprintf("%s-%03d", time.Now().Format("20060102-150405"), m.seq),
+		Title:     title,
+		Instance:  instance,
+		Started:   time.Now(),
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		status:    JobRunning,
+		followers: make(map[int]func(string)),
+	}
+	m.jobs = append(m.jobs, job)
+	m.pruneLocked()
+	m.mu.Unlock()
+
+	slog.Debug("job started", "id", job.ID, "title", title, "instance", instance)
+	m.publish(JobEvent{Type: JobStarted, Job: job})
+
+	go func() {
+		var err error
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("job panicked: %v", r)
+				}
+			}()
+			err = fn(ctx, func(line string) {
+				job.appendLog(line)
+				m.publish(JobEvent{Type: JobLogLine, Job: job, Line: line})
+			})
+		}()
+		job.finish(err)
+		slog.Debug("job finished", "id", job.ID, "status", job.Status(), "error", job.Err())
+		m.publish(JobEvent{Type: JobFinished, Job: job})
+	}()
+
+	return job
+}
+
+// Get looks a job up by ID
+func (m *JobManager) Get(id string) *Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, j := range m.jobs {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// Jobs returns running jobs first, then finished ones, newest first within each group
+func (m *JobManager) Jobs() []*Job {
+	m.mu.Lock()
+	list := append([]*Job(nil), m.jobs...)
+	m.mu.Unlock()
+
+	sort.SliceStable(list, func(a, b int) bool {
+		ra, rb := list[a].Status() == JobRunning, list[b].Status() == JobRunning
+		if ra != rb {
+			return ra
+		}
+		return list[a].Started.After(list[b].Started)
+	})
+	return list
+}
+
+// Active returns only the running jobs
+func (m *JobManager) Active() []*Job {
+	var active []*Job
+	for _, j := range m.Jobs() {
+		if j.Status() == JobRunning {
+			active = append(active, j)
+		}
+	}
+	return active
+}
+
+// CancelAll cancels every running job
+func (m *JobManager) CancelAll() {
+	for _, j := range m.Active() {
+		j.Cancel()
+	}
+}
+
+// Subscribe registers fn for every job event. Events are delivered from the job goroutines.
+func (m *JobManager) Subscribe(fn func(JobEvent)) (unsubscribe func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := m.nextSub
+	m.nextSub++
+	m.subs[id] = fn
+	return func() {
+		m.mu.Lock()
+		delete(m.subs, id)
+		m.mu.Unlock()
+	}
+}
+
+func (m *JobManager) publish(ev JobEvent) {
+	m.mu.Lock()
+	subs := make([]func(JobEvent), 0, len(m.subs))
+	for _, fn := range m.subs {
+		subs = append(subs, fn)
+	}
+	m.mu.Unlock()
+	for _, fn := range subs {
+		fn(ev)
+	}
+}
+
+// pruneLocked drops the oldest finished jobs beyond the retention limit
+func (m *JobManager) pruneLocked() {
+	finished := 0
+	for i := len(m.jobs) - 1; i >= 0; i-- {
+		if m.jobs[i].Status() != JobRunning {
+			finished++
+			if finished > m.keepFinished {
+				m.jobs = append(m.jobs[:i], m.jobs[i+1:]...)
+			}
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitJob fails the test if j does not finish in time
+func waitJob(t *testing.T, j *Job) {
+	t.Helper()
+	select {
+	case <-j.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("job %q did not finish", j.Title)
+	}
+}
+
+// blockingJob runs until its context is cancelled or release is closed
+func blockingJob(release <-chan struct{}) JobFunc {
+	return func(ctx context.Context, log func(string)) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-release:
+			return nil
+		}
+	}
+}
+
+func TestJobCancelPropagates(t *testing.T) {
+	m := NewJobManager()
+	var mu sync.Mutex
+	var finished []JobStatus
+	m.Subscribe(func(ev JobEvent) {
+		if ev.Type == JobFinished {
+			mu.Lock()
+			finished = append(finished, ev.Job.Status())
+			mu.Unlock()
+		}
+	})
+
+	release := make(chan struct{})
+	defer close(release)
+	j := m.Start("blocked", blockingJob(release))
+	if got := j.Status(); got != JobRunning {
+		t.Fatalf("status %q before cancel, want Running", got)
+	}
+	j.Cancel()
+	waitJob(t, j)
+
+	if got := j.Status(); got != JobCanceled {
+		t.Fatalf("status %q, want Canceled", got)
+	}
+	if err := j.Err(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err %v, want context.Canceled", err)
+	}
+	if j.Finished().IsZero() {
+		t.Fatal("finish time not recorded")
+	}
+	// The finished event is published after Done is closed
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		got := append([]JobStatus(nil), finished...)
+		mu.Unlock()
+		if len(got) == 1 {
+			if got[0] != JobCanceled {
+				t.Fatalf("finished event status %q, want Canceled", got[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("finished events %v", got)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestJobCancelAllSkipsFinished(t *testing.T) {
+	m := NewJobManager()
+	done := m.Start("done", func(ctx context.Context, log func(string)) error { return nil })
+	waitJob(t, done)
+	failed := m.Start("failed", func(ctx context.Context, log func(string)) error { return errors.New("boom") })
+	waitJob(t, failed)
+
+	release := make(chan struct{})
+	defer close(release)
+	a := m.Start("a", blockingJob(release))
+	b := m.StartFor("b", "Ubuntu", blockingJob(release))
+	if got := len(m.Active()); got != 2 {
+		t.Fatalf("%d active jobs, want 2", got)
+	}
+
+	m.CancelAll()
+	waitJob(t, a)
+	waitJob(t, b)
+	for _, j := range []*Job{a, b} {
+		if got := j.Status(); got != JobCanceled {
+			t.Fatalf("%s: status %q, want Canceled", j.Title, got)
+		}
+	}
+	if got := done.Status(); got != JobSucceeded {
+		t.Fatalf("finished job changed to %q", got)
+	}
+	if got := failed.Status(); got != JobFailed || failed.Err() == nil || errors.Is(failed.Err(), context.Canceled) {
+		t.Fatalf("failed job: status %q, err %v", got, failed.Err())
+	}
+	if len(m.Active()) != 0 {
+		t.Fatalf("active jobs left: %v", m.Active())
+	}
+}
+
+func TestJobPanicFails(t *testing.T) {
+	m := NewJobManager()
+	j := m.Start("panics", func(ctx context.Context, log func(string)) error { panic("oops") })
+	waitJob(t, j)
+	if j.Status() != JobFailed || j.Err() == nil {
+		t.Fatalf("status %q, err %v", j.Status(), j.Err())
+	}
+}
+
+func TestJobFollow(t *testing.T) {
+	m := NewJobManager()
+	step := make(chan struct{})
+	logged := make(chan struct{})
+	j := m.Start("follow", func(ctx context.Context, log func(string)) error {
+		log("one\n")
+		log("two\n")
+		logged <- struct{}{}
+		<-step
+		log("three\n")
+		logged <- struct{}{}
+		<-step
+		log("four\n")
+		return nil
+	})
+	<-logged
+
+	var mu sync.Mutex
+	var got []string
+	stop := j.Follow(func(line string) {
+		mu.Lock()
+		got = append(got, line)
+		mu.Unlock()
+	})
+	lines := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), got...)
+	}
+	if want := []string{"one\n", "two\n"}; !reflect.DeepEqual(lines(), want) {
+		t.Fatalf("replay %q, want %q", lines(), want)
+	}
+
+	step <- struct{}{}
+	<-logged
+	if want := []string{"one\n", "two\n", "three\n"}; !reflect.DeepEqual(lines(), want) {
+		t.Fatalf("followed %q, want %q", lines(), want)
+	}
+
+	stop()
+	step <- struct{}{}
+	waitJob(t, j)
+	if got := len(lines()); got != 3 {
+		t.Fatalf("stopped follower still received lines: %q", lines())
+	}
+	if got := j.Logs(); got != "one\ntwo\nthree\nfour\n" {
+		t.Fatalf("logs %q", got)
+	}
+}
+
+func TestJobLogTruncation(t *testing.T) {
+	m := NewJobManager()
+	j := m.Start("chatty", func(ctx context.Context, log func(string)) error {
+		for i := 0; i < maxJobLogLines+5; i++ {
+			log("x\n")
+		}
+		return nil
+	})
+	waitJob(t, j)
+
+	var replay []string
+	j.Follow(func(line string) { replay = append(replay, line) })()
+	if len(replay) != maxJobLogLines+1 || replay[0] != jobLogTruncatedAt {
+		t.Fatalf("replayed %d lines starting %q", len(replay), replay[0])
+	}
+}
+
+func TestJobEvents(t *testing.T) {
+	m := NewJobManager()
+	var mu sync.Mutex
+	var types []JobEventType
+	unsubscribe := m.Subscribe(func(ev JobEvent) {
+		mu.Lock()
+		types = append(types, ev.Type)
+		mu.Unlock()
+	})
+
+	j := m.Start("events", func(ctx context.Context, log func(string)) error {
+		ReportProgress(ctx, ProgressEvent{Stage: StageDownload, Percent: 0, Stages: []string{StageDownload, StageImport}})
+		log("working\n")
+		ReportProgress(ctx, ProgressEvent{Stage: StageImport, Percent: 50})
+		return nil
+	})
+	waitJob(t, j)
+	// JobFinished is published just after Done closes
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		mu.Lock()
+		n := len(types)
+		mu.Unlock()
+		if n == 5 || time.Now().After(deadline) {
+			break
+		}
+	}
+
+	want := []JobEventType{JobStarted, JobProgress, JobLogLine, JobProgress, JobFinished}
+	mu.Lock()
+	got := append([]JobEventType(nil), types...)
+	mu.Unlock()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("events %v, want %v", got, want)
+	}
+	// Later progress keeps the stage list announced first
+	ev, ok := j.Progress()
+	if !ok || ev.Stage != StageImport || !reflect.DeepEqual(ev.Stages, []string{StageDownload, StageImport}) {
+		t.Fatalf("progress %+v (ok %v)", ev, ok)
+	}
+
+	unsubscribe()
+	waitJob(t, m.Start("quiet", func(ctx context.Context, log func(string)) error { return nil }))
+	mu.Lock()
+	defer mu.Unlock()
+	if len(types) != len(want) {
+		t.Fatalf("unsubscribed callback still called: %v", types[len(want):])
+	}
+}
+
+func TestJobPruneKeepsRunning(t *testing.T) {
+	m := NewJobManager()
+	m.keepFinished = 2
+
+	release := make(chan struct{})
+	defer close(release)
+	running := m.Start("running", blockingJob(release))
+
+	var finished []*Job
+	for _, title := range []string{"f1", "f2", "f3", "f4"} {
+		j := m.Start(title, func(ctx context.Context, log func(string)) error { return nil })
+		waitJob(t, j)
+		finished = append(finished, j)
+	}
+	// Pruning happens when a job starts; the oldest running job must survive it
+	last := m.Start("last", blockingJob(release))
+
+	if m.Get(running.ID) != running {
+		t.Fatal("running job was pruned")
+	}
+	if m.Get(last.ID) != last {
+		t.Fatal("new job missing")
+	}
+	for i, j := range finished {
+		kept := m.Get(j.ID) != nil
+		if wantKept := i >= len(finished)-2; kept != wantKept {
+			t.Fatalf("%s: kept=%v, want %v", j.Title, kept, wantKept)
+		}
+	}
+	if got := len(m.Jobs()); got != 4 {
+		t.Fatalf("%d jobs tracked, want 4", got)
+	}
+	// Running jobs are listed first
+	if jobs := m.Jobs(); jobs[0].Status() != JobRunning || jobs[1].Status() != JobRunning {
+		t.Fatalf("running jobs not first: %s, %s", jobs[0].Title, jobs[1].Title)
+	}
+}
@@ -0,0 +1,395 @@
+package logic
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// OCI media types written by ExportDistroImage
+const (
+	mediaTypeImageIndex    = "application/vnd.oci.image.index.v1+json"
+	mediaTypeImageManifest = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeImageConfig   = "application/vnd.oci.image.config.v1+json"
+	mediaTypeLayerGzip     = "application/vnd.oci.image.layer.v1.tar+gzip"
+)
+
+// Labels recording where an exported image came from
+const (
+	LabelSourceDistro  = "io.distronexus.source.distro"
+	LabelSourceRelease = "io.distronexus.source.release"
+)
+
+// ImageExportOptions describes the image written by ExportDistroImage
+type ImageExportOptions struct {
+	// Reference names the image ("myenv:1.0"); ":latest" is added when there is no tag
+	Reference  string
+	Entrypoint []string
+	Cmd        []string
+	// Env holds KEY=VALUE pairs
+	Env    []string
+	Labels map[string]string
+	// Layout writes an OCI image layout folder instead of a tarball for docker/podman load
+	Layout bool
+}
+
+// imageReference is repository[:tag] with lower-case path components (registry ports allowed)
+var imageReference = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*(?::[0-9]+)?(?:/[a-z0-9]+(?:[._-][a-z0-9]+)*)*(?::[A-Za-z0-9_][A-Za-z0-9_.-]{0,127})?$`)
+
+// ValidateImageReference checks an image name such as "team/devbox:2024.1"
+func ValidateImageReference(ref string) error {
+	if ref == "" {
+		return fmt.Errorf("image name is required")
+	}
+	if !imageReference.MatchString(ref) {
+		return fmt.Errorf("use lower-case letters, digits and . _ - separated by /, with an optional :tag")
+	}
+	return nil
+}
+
+// normalizeReference appends ":latest" when ref has no tag
+func normalizeReference(ref string) string {
+	last := ref[strings.LastIndex(ref, "/")+1:]
+	if !strings.Contains(last, ":") {
+		return ref + ":latest"
+	}
+	return ref
+}
+
+type ociContent struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ExportDistroImage streams `wsl --export` into a single gzip layer and wraps it into an
+// OCI image with the given configuration. The result is a tarball that docker load and
+// podman load accept (it carries both the OCI index and docker's manifest.json), or an
+// OCI layout folder when opts.Layout is set.
+func ExportDistroImage(ctx context.Context, projectRoot, name, output string, opts ImageExportOptions, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpExportImage, name, map[string]string{"Output": output, "Reference": opts.Reference})(&err)
+
+	log := func(s string) {
+		if onOutput != nil {
+			onOutput(s)
+		}
+	}
+	if err := ValidateImageReference(opts.Reference); err != nil {
+		return err
+	}
+	ref := normalizeReference(opts.Reference)
+
+	release := ""
+	if cached, err := readInstancesCache(projectRoot); err == nil {
+		for _, inst := range cached {
+			if strings.EqualFold(inst.Name, name) {
+				release = inst.Release
+			}
+		}
+	}
+
+	// 1. Layer: the export stream, gzip-compressed into a temporary blob
+	blobDir := filepath.Dir(output)
+	if opts.Layout {
+		blobDir = filepath.Join(output, "blobs", "sha256")
+	}
+	if err := os.MkdirAll(blobDir, 0755); err != nil {
+		return err
+	}
+	layerFile, err := os.CreateTemp(blobDir, ".layer-*.partial")
+	if err != nil {
+		return err
+	}
+	layerTmp := layerFile.Name()
+	defer os.Remove(layerTmp)
+
+	ReportProgress(ctx, ProgressEvent{Stage: StageExport, Percent: -1, Message: "Exporting " + name + "...", Stages: []string{StageExport, StagePackage}})
+	log(fmt.Sprintf("Exporting %s...\n", name))
+	diffID, layer, err := exportLayer(ctx, name, layerFile)
+	if cerr := layerFile.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	log(fmt.Sprintf("Layer: %s compressed\n", FormatBytes(layer.Size)))
+
+	// 2. Config, manifests and the image itself
+	now := time.Now().UTC()
+	labels := map[string]string{
+		"org.opencontainers.image.title":   name,
+		"org.opencontainers.image.created": now.Format(time.RFC3339),
+		LabelSourceDistro:                  name,
+	}
+	if release != "" {
+		labels[LabelSourceRelease] = release
+	}
+	for k, v := range opts.Labels {
+		labels[k] = v
+	}
+	spec := imageSpec{ref: ref, opts: opts, labels: labels, created: now, createdBy: "wsl --export " + name}
+	if err := writeImage(ctx, output, spec, diffID, layer, layerTmp); err != nil {
+		return err
+	}
+	log(fmt.Sprintf("Image %s written to %s\n", ref, output))
+	return nil
+}
+
+// imageSpec is what goes into the config and manifests of an exported image
+type imageSpec struct {
+	ref       string
+	opts      ImageExportOptions
+	labels    map[string]string
+	created   time.Time
+	createdBy string
+}
+
+// writeImage wraps the compressed layer in layerTmp into an image at output: an OCI layout
+// folder when spec.opts.Layout is set (the layer file is moved into it), otherwise a tarball
+// carrying both the OCI index and docker's manifest.json
+func writeImage(ctx context.Context, output string, spec imageSpec, diffID string, layer ociContent, layerTmp string) error {
+	created := spec.created.Format(time.RFC3339)
+	config := map[string]any{
+		"created":      created,
+		"architecture": ImageArch(),
+		"os":           "linux",
+		"config": map[string]any{
+			"Entrypoint": spec.opts.Entrypoint,
+			"Cmd":        spec.opts.Cmd,
+			"Env":        spec.opts.Env,
+			"Labels":     spec.labels,
+		},
+		"rootfs": map[string]any{
+			"type":     "layers",
+			"diff_ids": []string{diffID},
+		},
+		"history": []map[string]string{
+			{"created": created, "created_by": spec.createdBy},
+		},
+	}
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	configDesc := describe(mediaTypeImageConfig, configJSON)
+
+	manifestJSON, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     mediaTypeImageManifest,
+		"config":        configDesc,
+		"layers":        []ociContent{layer},
+	})
+	if err != nil {
+		return err
+	}
+	manifestDesc := describe(mediaTypeImageManifest, manifestJSON)
+	manifestDesc.Annotations = map[string]string{
+		"io.containerd.image.name":          spec.ref,
+		"org.opencontainers.image.ref.name": spec.ref[strings.LastIndex(spec.ref, ":")+1:],
+	}
+	indexJSON, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     mediaTypeImageIndex,
+		"manifests":     []ociContent{manifestDesc},
+	})
+	if err != nil {
+		return err
+	}
+	dockerJSON, err := json.Marshal([]map[string]any{{
+		"Config":   blobPath(configDesc.Digest),
+		"RepoTags": []string{spec.ref},
+		"Layers":   []string{blobPath(layer.Digest)},
+	}})
+	if err != nil {
+		return err
+	}
+	layoutJSON := []byte(`{"imageLayoutVersion":"1.0.0"}`)
+
+	ReportProgress(ctx, ProgressEvent{Stage: StagePackage, Percent: -1, Message: "Writing image..."})
+	small := []struct {
+		name string
+		data []byte
+	}{
+		{"oci-layout", layoutJSON},
+		{"index.json", indexJSON},
+		{"manifest.json", dockerJSON},
+		{blobPath(configDesc.Digest), configJSON},
+		{blobPath(manifestDesc.Digest), manifestJSON},
+	}
+	if spec.opts.Layout {
+		if err := os.MkdirAll(filepath.Join(output, "blobs", "sha256"), 0755); err != nil {
+			return err
+		}
+		for _, f := range small {
+			if err := os.WriteFile(filepath.Join(output, filepath.FromSlash(f.name)), f.data, 0644); err != nil {
+				return err
+			}
+		}
+		if err := os.Rename(layerTmp, filepath.Join(output, filepath.FromSlash(blobPath(layer.Digest)))); err != nil {
+			return err
+		}
+	} else if err := writeImageTar(output, small, blobPath(layer.Digest), layerTmp, layer.Size); err != nil {
+		os.Remove(output)
+		return err
+	}
+	ReportProgress(ctx, ProgressEvent{Stage: StagePackage, Percent: 100, Message: "Image written"})
+	return nil
+}
+
+// exportLayer runs wsl --export to stdout and gzips the stream into w. It returns the
+// uncompressed digest (diff_id) and the descriptor of the compressed layer.
+func exportLayer(ctx context.Context, name string, w io.Writer) (string, ociContent, error) {
+	cmd := exec.CommandContext(ctx, "wsl.exe", "--export", name, "-")
+	cmd.Env = append(os.Environ(), "WSL_UTF8=1")
+	prepareCmd(cmd)
+	return streamLayer(ctx, cmd, w)
+}
+
+// streamLayer runs cmd and compresses its stdout into w. When the layer cannot be written
+// the process is killed: nothing drains the pipe any more, so it would never exit.
+func streamLayer(ctx context.Context, cmd *exec.Cmd, w io.Writer) (string, ociContent, error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", ociContent{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", ociContent{}, fmt.Errorf("failed to start wsl --export: %w", err)
+	}
+
+	diffID, layer, copyErr := compressLayer(ctx, stdout, w)
+	if copyErr != nil {
+		cmd.Process.Kill()
+	}
+	waitErr := cmd.Wait()
+	if ctx.Err() != nil {
+		return "", ociContent{}, ctx.Err()
+	}
+	if copyErr != nil {
+		return "", ociContent{}, copyErr
+	}
+	if waitErr != nil {
+		if text := strings.TrimSpace(decodeWslOutput(stderr.Bytes())); text != "" {
+			return "", ociContent{}, fmt.Errorf("wsl --export: %w: %s", waitErr, text)
+		}
+		return "", ociContent{}, fmt.Errorf("wsl --export: %w", waitErr)
+	}
+	return diffID, layer, nil
+}
+
+// compressLayer gzips the uncompressed layer r into w, returning its diff_id and the
+// descriptor of the compressed blob
+func compressLayer(ctx context.Context, r io.Reader, w io.Writer) (string, ociContent, error) {
+	diffHash := sha256.New()
+	blob := &hashingWriter{w: w, h: sha256.New()}
+	gz := gzip.NewWriter(blob)
+	read := &exportProgress{ctx: ctx}
+	_, err := io.Copy(io.MultiWriter(gz, diffHash, read), r)
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		return "", ociContent{}, err
+	}
+	return "sha256:" + hex.EncodeToString(diffHash.Sum(nil)), ociContent{
+		MediaType: mediaTypeLayerGzip,
+		Digest:    "sha256:" + hex.EncodeToString(blob.h.Sum(nil)),
+		Size:      blob.n,
+	}, nil
+}
+
+// hashingWriter hashes and counts what passes through to w
+type hashingWriter struct {
+	w io.Writer
+	h hash.Hash
+	n int64
+}
+
+func (hw *hashingWriter) Write(b []byte) (int, error) {
+	n, err := hw.w.Write(b)
+	hw.h.Write(b[:n])
+	hw.n += int64(n)
+	return n, err
+}
+
+// exportProgress reports the exported size; the total is unknown in advance
+type exportProgress struct {
+	ctx      context.Context
+	n        int64
+	reported int64
+}
+
+func (p *exportProgress) Write(b []byte) (int, error) {
+	p.n += int64(len(b))
+	if p.n-p.reported >= 64<<20 {
+		p.reported = p.n
+		ReportProgress(p.ctx, ProgressEvent{Stage: StageExport, Percent: -1, Message: "Exported " + FormatBytes(p.n)})
+	}
+	return len(b), nil
+}
+
+// describe returns the descriptor of a JSON blob
+func describe(mediaType string, data []byte) ociContent {
+	sum := sha256.Sum256(data)
+	return ociContent{MediaType: mediaType, Digest: "sha256:" + hex.EncodeToString(sum[:]), Size: int64(len(data))}
+}
+
+// writeImageTar writes the small files followed by the layer blob into an image tarball
+func writeImageTar(output string, small []struct {
+	name string
+	data []byte
+}, layerName, layerFile string, layerSize int64) error {
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(out)
+	now := time.Now()
+	writeDir := func(name string) error {
+		return tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name, Mode: 0755, ModTime: now})
+	}
+	err = writeDir("blobs/")
+	if err == nil {
+		err = writeDir("blobs/sha256/")
+	}
+	for _, f := range small {
+		if err != nil {
+			break
+		}
+		if err = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: f.name, Mode: 0644, Size: int64(len(f.data)), ModTime: now}); err == nil {
+			_, err = tw.Write(f.data)
+		}
+	}
+	if err == nil {
+		err = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: layerName, Mode: 0644, Size: layerSize, ModTime: now})
+	}
+	if err == nil {
+		var lf *os.File
+		if lf, err = os.Open(layerFile); err == nil {
+			_, err = io.Copy(tw, lf)
+			lf.Close()
+		}
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,271 @@
+package logic
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sha256Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// readImageFile reads name from an image opened with openImage's helpers
+func readImageFile(t *testing.T, img imageFiles, name string) []byte {
+	t.Helper()
+	rc, err := img.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestWriteImage(t *testing.T) {
+	rootfs := testLayer(t, dirEntry("etc/"), regFile("etc/os-release", "ID=test\n"), regFile("usr/bin/tool", strings.Repeat("t", 5000)))
+
+	for _, layout := range []bool{false, true} {
+		name := "tarball"
+		if layout {
+			name = "layout"
+		}
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			layerTmp := filepath.Join(dir, ".layer.partial")
+			f, err := os.Create(layerTmp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			diffID, layer, err := compressLayer(context.Background(), strings.NewReader(string(rootfs)), f)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			blob, err := os.ReadFile(layerTmp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diffID != sha256Digest(rootfs) {
+				t.Errorf("diff_id = %s, want the digest of the uncompressed stream", diffID)
+			}
+			if layer.Digest != sha256Digest(blob) || layer.Size != int64(len(blob)) || layer.MediaType != mediaTypeLayerGzip {
+				t.Errorf("layer descriptor = %+v, blob is %d bytes", layer, len(blob))
+			}
+
+			output := filepath.Join(dir, "image.tar")
+			if layout {
+				output = filepath.Join(dir, "image")
+			}
+			spec := imageSpec{
+				ref:       "team/devbox:1.0",
+				opts:      ImageExportOptions{Entrypoint: []string{"/bin/bash"}, Env: []string{"A=1"}, Layout: layout},
+				labels:    map[string]string{LabelSourceDistro: "Ubuntu"},
+				created:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+				createdBy: "wsl --export Ubuntu",
+			}
+			if err := writeImage(context.Background(), output, spec, diffID, layer, layerTmp); err != nil {
+				t.Fatal(err)
+			}
+
+			var img imageFiles = dirImage(output)
+			if !layout {
+				tarImg, err := openImageTar(output)
+				if err != nil {
+					t.Fatal(err)
+				}
+				img = tarImg
+			}
+			defer img.Close()
+
+			var index struct {
+				MediaType string       `json:"mediaType"`
+				Manifests []ociContent `json:"manifests"`
+			}
+			if err := json.Unmarshal(readImageFile(t, img, "index.json"), &index); err != nil {
+				t.Fatal(err)
+			}
+			if index.MediaType != mediaTypeImageIndex || len(index.Manifests) != 1 {
+				t.Fatalf("index.json = %+v", index)
+			}
+			manifestDesc := index.Manifests[0]
+			if manifestDesc.Annotations["org.opencontainers.image.ref.name"] != "1.0" || manifestDesc.Annotations["io.containerd.image.name"] != "team/devbox:1.0" {
+				t.Errorf("manifest annotations = %v", manifestDesc.Annotations)
+			}
+
+			// Every descriptor must match the blob it points at
+			checkBlob := func(desc ociContent) []byte {
+				t.Helper()
+				data := readImageFile(t, img, blobPath(desc.Digest))
+				if sha256Digest(data) != desc.Digest || int64(len(data)) != desc.Size {
+					t.Errorf("blob %s: digest %s, %d bytes; descriptor says %d", desc.Digest, sha256Digest(data), len(data), desc.Size)
+				}
+				return data
+			}
+			var manifest struct {
+				MediaType string       `json:"mediaType"`
+				Config    ociContent   `json:"config"`
+				Layers    []ociContent `json:"layers"`
+			}
+			if err := json.Unmarshal(checkBlob(manifestDesc), &manifest); err != nil {
+				t.Fatal(err)
+			}
+			if manifest.MediaType != mediaTypeImageManifest || len(manifest.Layers) != 1 || manifest.Layers[0].Digest != layer.Digest {
+				t.Fatalf("manifest = %+v", manifest)
+			}
+			checkBlob(manifest.Layers[0])
+			var config struct {
+				Architecture string `json:"architecture"`
+				OS           string `json:"os"`
+				Created      string `json:"created"`
+				Config       struct {
+					Entrypoint []string          `json:"Entrypoint"`
+					Env        []string          `json:"Env"`
+					Labels     map[string]string `json:"Labels"`
+				} `json:"config"`
+				Rootfs struct {
+					Type    string   `json:"type"`
+					DiffIDs []string `json:"diff_ids"`
+				} `json:"rootfs"`
+			}
+			if err := json.Unmarshal(checkBlob(manifest.Config), &config); err != nil {
+				t.Fatal(err)
+			}
+			if config.OS != "linux" || config.Architecture != ImageArch() || config.Created != "2026-01-02T03:04:05Z" {
+				t.Errorf("config platform = %s/%s created %s", config.OS, config.Architecture, config.Created)
+			}
+			if config.Rootfs.Type != "layers" || !slices.Equal(config.Rootfs.DiffIDs, []string{diffID}) {
+				t.Errorf("config rootfs = %+v, want diff_id %s", config.Rootfs, diffID)
+			}
+			if !slices.Equal(config.Config.Entrypoint, []string{"/bin/bash"}) || !slices.Equal(config.Config.Env, []string{"A=1"}) || config.Config.Labels[LabelSourceDistro] != "Ubuntu" {
+				t.Errorf("config = %+v", config.Config)
+			}
+
+			var docker []dockerManifest
+			if err := json.Unmarshal(readImageFile(t, img, "manifest.json"), &docker); err != nil {
+				t.Fatal(err)
+			}
+			if len(docker) != 1 || docker[0].Config != blobPath(manifest.Config.Digest) ||
+				!slices.Equal(docker[0].Layers, []string{blobPath(layer.Digest)}) || !slices.Equal(docker[0].RepoTags, []string{"team/devbox:1.0"}) {
+				t.Errorf("manifest.json = %+v", docker)
+			}
+			if string(readImageFile(t, img, "oci-layout")) != `{"imageLayoutVersion":"1.0.0"}` {
+				t.Errorf("oci-layout missing")
+			}
+
+			// The image reads back as the exported root filesystem
+			got, _ := flattenTest(t, output)
+			if got["etc/os-release"].body != "ID=test\n" || got["usr/bin/tool"].body != strings.Repeat("t", 5000) {
+				t.Errorf("flattened image = %v", keys(got))
+			}
+		})
+	}
+}
+
+func TestWriteImageTarLayout(t *testing.T) {
+	dir := t.TempDir()
+	layerTmp := filepath.Join(dir, "layer")
+	if err := os.WriteFile(layerTmp, []byte("layer bytes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "image.tar")
+	small := []struct {
+		name string
+		data []byte
+	}{{"oci-layout", []byte("{}")}, {"index.json", []byte("[]")}}
+	if err := writeImageTar(output, small, "blobs/sha256/abc", layerTmp, int64(len("layer bytes"))); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var names []string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+		if hdr.Name == "blobs/sha256/abc" {
+			if data, _ := io.ReadAll(tr); string(data) != "layer bytes" {
+				t.Errorf("layer member = %q", data)
+			}
+		}
+	}
+	want := []string{"blobs/", "blobs/sha256/", "oci-layout", "index.json", "blobs/sha256/abc"}
+	if !slices.Equal(names, want) {
+		t.Errorf("members = %v, want %v", names, want)
+	}
+}
+
+var errLayerWrite = errors.New("disk full")
+
+// failingWriter accepts limit bytes and then fails
+type failingWriter struct {
+	limit int
+}
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	if len(b) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, errLayerWrite
+	}
+	w.limit -= len(b)
+	return len(b), nil
+}
+
+func TestStreamLayerWriteErrorStopsProcess(t *testing.T) {
+	yes, err := exec.LookPath("yes")
+	if err != nil {
+		t.Skip("needs yes for an endless output stream")
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := streamLayer(context.Background(), exec.Command(yes), &failingWriter{limit: 1 << 10})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, errLayerWrite) {
+			t.Fatalf("err = %v, want the write error", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("streamLayer still waiting for the process after the layer write failed")
+	}
+}
+
+func TestStreamLayerExitError(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("needs sh")
+	}
+	_, _, err = streamLayer(context.Background(), exec.Command(sh, "-c", "echo 'no such distribution' >&2; exit 3"), io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "no such distribution") {
+		t.Fatalf("err = %v, want the process's error output", err)
+	}
+}
@@ -0,0 +1,618 @@
+package logic
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"distronexus-gui/internal/model"
+)
+
+// Image archives read by FlattenImage:
+//
+//	OCI image layout   oci-layout, index.json, blobs/<alg>/<hex> (as a folder or a tar)
+//	docker save        manifest.json listing each image's config and layer files
+//
+// Layers are applied bottom-up with the OCI whiteout rules: ".wh.<name>" deletes <name>
+// from the layers below, ".wh..wh..opq" hides everything the lower layers put in its folder.
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// imageFiles opens files of an image by their path inside the layout
+type imageFiles interface {
+	Open(name string) (io.ReadCloser, error)
+	Close() error
+}
+
+// dirImage is an OCI layout unpacked in a folder
+type dirImage string
+
+func (d dirImage) Open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+func (d dirImage) Close() error { return nil }
+
+// tarImage reads members of an uncompressed image tarball in place
+type tarImage struct {
+	f       *os.File
+	entries map[string]tarMember
+}
+
+type tarMember struct {
+	offset, size int64
+}
+
+func (t *tarImage) Open(name string) (io.ReadCloser, error) {
+	m, ok := t.entries[path.Clean(strings.TrimPrefix(name, "./"))]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in image archive", name)
+	}
+	return io.NopCloser(io.NewSectionReader(t.f, m.offset, m.size)), nil
+}
+
+func (t *tarImage) Close() error { return t.f.Close() }
+
+// countingReader tracks the read position, which after tar.Reader.Next is where the member's data starts
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// openImageTar indexes the members of an image tarball
+func openImageTar(file string) (*tarImage, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	img := &tarImage{f: f, entries: make(map[string]tarMember)}
+	cr := &countingReader{r: f}
+	tr := tar.NewReader(cr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("reading image archive: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			img.entries[path.Clean(strings.TrimPrefix(hdr.Name, "./"))] = tarMember{offset: cr.n, size: hdr.Size}
+		}
+	}
+	return img, nil
+}
+
+// imageLayer is one layer file; its compression is sniffed when it is opened
+type imageLayer struct {
+	name string
+}
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Platform  *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"platform,omitempty"`
+}
+
+type ociIndex struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+type dockerManifest struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+// ImageArch returns the OCI architecture name of this machine (amd64, arm64, 386)
+func ImageArch() string {
+	switch HostPackageArch() {
+	case "x64":
+		return "amd64"
+	case "x86":
+		return "386"
+	}
+	return HostPackageArch()
+}
+
+// imageLayers finds the layer list: docker save's manifest.json, or the OCI index
+// followed down to the image manifest for this platform
+func imageLayers(img imageFiles, log func(string)) ([]imageLayer, error) {
+	if rc, err := img.Open("manifest.json"); err == nil {
+		var manifests []dockerManifest
+		err := json.NewDecoder(rc).Decode(&manifests)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("manifest.json: %w", err)
+		}
+		if len(manifests) == 0 {
+			return nil, fmt.Errorf("manifest.json lists no image")
+		}
+		m := manifests[0]
+		if len(manifests) > 1 {
+			log(fmt.Sprintf("Archive holds %d images; using the first (%s)\n", len(manifests), strings.Join(m.RepoTags, ", ")))
+		} else if len(m.RepoTags) > 0 {
+			log(fmt.Sprintf("Image: %s\n", strings.Join(m.RepoTags, ", ")))
+		}
+		var layers []imageLayer
+		for _, l := range m.Layers {
+			layers = append(layers, imageLayer{name: l})
+		}
+		return layers, nil
+	}
+
+	rc, err := img.Open("index.json")
+	if err != nil {
+		return nil, fmt.Errorf("not an OCI layout or docker save archive (no index.json or manifest.json)")
+	}
+	var index ociIndex
+	err = json.NewDecoder(rc).Decode(&index)
+	rc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("index.json: %w", err)
+	}
+
+	// Indexes may nest (index -> platform index -> manifest)
+	for depth := 0; depth < 4; depth++ {
+		if len(index.Layers) > 0 {
+			var layers []imageLayer
+			for _, l := range index.Layers {
+				layers = append(layers, imageLayer{name: blobPath(l.Digest)})
+			}
+			return layers, nil
+		}
+		desc, err := pickManifest(index.Manifests)
+		if err != nil {
+			return nil, err
+		}
+		rc, err := img.Open(blobPath(desc.Digest))
+		if err != nil {
+			return nil, err
+		}
+		index = ociIndex{}
+		err = json.NewDecoder(rc).Decode(&index)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("manifest %s: %w", desc.Digest, err)
+		}
+	}
+	return nil, fmt.Errorf("image index nests too deeply")
+}
+
+// pickManifest chooses the linux manifest for this machine's architecture
+func pickManifest(manifests []ociDescriptor) (ociDescriptor, error) {
+	if len(manifests) == 0 {
+		return ociDescriptor{}, fmt.Errorf("image index lists no manifest")
+	}
+	arch := ImageArch()
+	var available []string
+	for _, m := range manifests {
+		if m.Platform == nil {
+			continue
+		}
+		if m.Platform.OS == "linux" && m.Platform.Architecture == arch {
+			return m, nil
+		}
+		available = append(available, m.Platform.OS+"/"+m.Platform.Architecture)
+	}
+	if len(available) == 0 {
+		// No platform information (single-image layout)
+		return manifests[0], nil
+	}
+	return ociDescriptor{}, fmt.Errorf("image has no linux/%s variant (contains: %s)", arch, strings.Join(available, ", "))
+}
+
+// blobPath maps a digest ("sha256:ab..") to its file in the layout
+func blobPath(digest string) string {
+	alg, hex, _ := strings.Cut(digest, ":")
+	return "blobs/" + alg + "/" + hex
+}
+
+// openLayer returns the uncompressed tar stream of a layer
+func openLayer(img imageFiles, layer imageLayer) (io.ReadCloser, error) {
+	rc, err := img.Open(layer.name)
+	if err != nil {
+		return nil, err
+	}
+	br := &peekReader{r: rc}
+	head, err := br.peek(512)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	switch SniffFormat(head) {
+	case FormatGzip:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			rc.Close()
+			return nil, fmt.Errorf("layer %s: %w", layer.name, err)
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{gz, rc}, nil
+	case FormatZstd:
+		rc.Close()
+		return nil, fmt.Errorf("layer %s is zstd-compressed, which is not supported; re-save the image with gzip layers", layer.name)
+	}
+	// Plain tar (docker save) or an empty layer
+	return struct {
+		io.Reader
+		io.Closer
+	}{br, rc}, nil
+}
+
+// peekReader lets the first bytes be inspected and then read again
+type peekReader struct {
+	r   io.Reader
+	buf []byte
+}
+
+func (p *peekReader) peek(n int) ([]byte, error) {
+	p.buf = make([]byte, n)
+	m, err := io.ReadFull(p.r, p.buf)
+	p.buf = p.buf[:m]
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return p.buf, err
+}
+
+func (p *peekReader) Read(b []byte) (int, error) {
+	if len(p.buf) > 0 {
+		n := copy(b, p.buf)
+		p.buf = p.buf[n:]
+		return n, nil
+	}
+	return p.r.Read(b)
+}
+
+// layerPath normalises a member name ("./usr/bin/", "/etc") to "usr/bin", "etc"
+func layerPath(name string) string {
+	p := path.Clean("/" + name)
+	return strings.TrimPrefix(p, "/")
+}
+
+// whiteouts holds what upper layers removed from the layers below them
+type whiteouts struct {
+	deleted map[string]bool
+	opaque  map[string]bool
+	// nonDir holds paths an upper layer turned into a file or symlink; nothing from the
+	// layers below may appear under them
+	nonDir map[string]bool
+}
+
+func newWhiteouts() whiteouts {
+	return whiteouts{deleted: make(map[string]bool), opaque: make(map[string]bool), nonDir: make(map[string]bool)}
+}
+
+// merge adds the whiteouts of the layer above
+func (w whiteouts) merge(upper whiteouts) {
+	for p := range upper.deleted {
+		w.deleted[p] = true
+	}
+	for p := range upper.opaque {
+		w.opaque[p] = true
+	}
+	for p := range upper.nonDir {
+		w.nonDir[p] = true
+	}
+}
+
+// hides reports whether p from a lower layer is removed by a whiteout above, or sits
+// under a path an upper layer replaced with something that is not a directory
+func (w whiteouts) hides(p string) bool {
+	if w.opaque[""] {
+		return true
+	}
+	for dir := p; dir != "."; dir = path.Dir(dir) {
+		if w.deleted[dir] || (dir != p && (w.opaque[dir] || w.nonDir[dir])) {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingLink is a hard link held back until the rest of the image has been written
+type pendingLink struct {
+	hdr    tar.Header
+	layer  int
+	target string
+}
+
+// FlattenImage merges the layers of an OCI image layout (folder or tar) or a docker save
+// tarball into one root filesystem tar at target. The top-most version of every path wins
+// and whiteouts are honoured; the whiteout markers themselves are not copied.
+func FlattenImage(ctx context.Context, src, target string, onOutput func(string)) error {
+	log := func(s string) {
+		if onOutput != nil {
+			onOutput(s)
+		}
+	}
+
+	img, err := openImage(ctx, src, filepath.Dir(target), log)
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+
+	layers, err := imageLayers(img, log)
+	if err != nil {
+		return err
+	}
+	if len(layers) == 0 {
+		return fmt.Errorf("image has no layers")
+	}
+	log(fmt.Sprintf("Flattening %d layer(s)...\n", len(layers)))
+
+	// Pass 1, top-down: the layer that provides the visible version of each path
+	owner := make(map[string]int)
+	wo := newWhiteouts()
+	for i := len(layers) - 1; i >= 0; i-- {
+		layerWo := newWhiteouts()
+		err := walkLayer(ctx, img, layers[i], func(hdr *tar.Header, _ io.Reader) error {
+			p := layerPath(hdr.Name)
+			dir, base := path.Dir(p), path.Base(p)
+			if dir == "." {
+				dir = ""
+			}
+			switch {
+			case base == whiteoutOpaque:
+				layerWo.opaque[dir] = true
+			case strings.HasPrefix(base, whiteoutPrefix):
+				layerWo.deleted[path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix))] = true
+			case p == "":
+				// The layer's root folder
+			default:
+				if _, seen := owner[p]; !seen && !wo.hides(p) {
+					owner[p] = i
+					if hdr.Typeflag != tar.TypeDir {
+						layerWo.nonDir[p] = true
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		wo.merge(layerWo)
+	}
+
+	// Pass 2, bottom-up: write each visible entry from its owning layer. Hard links are
+	// held back so their targets exist by the time they are written.
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(out)
+	written := 0
+	var links []pendingLink
+	for i, layer := range layers {
+		ReportProgress(ctx, ProgressEvent{Stage: StageExtract, Percent: float64(i * 100 / len(layers)), Message: fmt.Sprintf("Layer %d of %d", i+1, len(layers))})
+		err = walkLayer(ctx, img, layer, func(hdr *tar.Header, r io.Reader) error {
+			p := layerPath(hdr.Name)
+			if o, ok := owner[p]; !ok || o != i || strings.HasPrefix(path.Base(p), whiteoutPrefix) {
+				return nil
+			}
+			out := *hdr
+			out.Name = p
+			if hdr.Typeflag == tar.TypeDir {
+				out.Name += "/"
+			}
+			if hdr.Typeflag == tar.TypeLink {
+				out.Linkname = layerPath(hdr.Linkname)
+				links = append(links, pendingLink{hdr: out, layer: i, target: out.Linkname})
+				return nil
+			}
+			if err := tw.WriteHeader(&out); err != nil {
+				return err
+			}
+			written++
+			if hdr.Typeflag == tar.TypeReg {
+				_, err := io.Copy(tw, r)
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		var n int
+		n, err = writeHardLinks(ctx, img, layers, owner, links, tw, log)
+		written += n
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(target)
+		return err
+	}
+	ReportProgress(ctx, ProgressEvent{Stage: StageExtract, Percent: 100, Message: "Root filesystem ready"})
+	log(fmt.Sprintf("Wrote %d entries to %s\n", written, target))
+	return nil
+}
+
+// writeHardLinks writes the held-back hard links. A link whose target is visible from the
+// link's layer or one below stays a link. When an upper layer rewrote or removed the target,
+// the link keeps the content it had in its own layer: the first such link becomes a regular
+// file with that content and further links to the same target point at it.
+func writeHardLinks(ctx context.Context, img imageFiles, layers []imageLayer, owner map[string]int, links []pendingLink, tw *tar.Writer, log func(string)) (int, error) {
+	written := 0
+	var copies []pendingLink
+	for _, l := range links {
+		if o, ok := owner[l.target]; !ok || o > l.layer {
+			copies = append(copies, l)
+			continue
+		}
+		if err := tw.WriteHeader(&l.hdr); err != nil {
+			return written, err
+		}
+		written++
+	}
+
+	// The content is the top-most regular file at the target path at or below the link's layer
+	for j := len(layers) - 1; j >= 0 && len(copies) > 0; j-- {
+		wanted := make(map[string][]pendingLink)
+		var later []pendingLink
+		for _, l := range copies {
+			if l.layer >= j {
+				wanted[l.target] = append(wanted[l.target], l)
+			} else {
+				later = append(later, l)
+			}
+		}
+		if len(wanted) == 0 {
+			continue
+		}
+		err := walkLayer(ctx, img, layers[j], func(hdr *tar.Header, r io.Reader) error {
+			p := layerPath(hdr.Name)
+			group, ok := wanted[p]
+			if !ok || hdr.Typeflag != tar.TypeReg {
+				return nil
+			}
+			delete(wanted, p)
+			file := group[0].hdr
+			file.Typeflag = tar.TypeReg
+			file.Linkname = ""
+			file.Size = hdr.Size
+			if err := tw.WriteHeader(&file); err != nil {
+				return err
+			}
+			if _, err := io.Copy(tw, r); err != nil {
+				return err
+			}
+			written++
+			for _, l := range group[1:] {
+				l.hdr.Linkname = file.Name
+				if err := tw.WriteHeader(&l.hdr); err != nil {
+					return err
+				}
+				written++
+			}
+			return nil
+		})
+		if err != nil {
+			return written, err
+		}
+		for _, group := range wanted {
+			later = append(later, group...)
+		}
+		copies = later
+	}
+	for _, l := range copies {
+		log(fmt.Sprintf("Skipping hard link %s: its target %s is not in the image\n", l.hdr.Name, l.target))
+	}
+	return written, nil
+}
+
+// openImage opens an OCI layout folder, or indexes an image tarball. Compressed tarballs
+// (docker save | gzip) are unpacked to workDir first since members are read in place.
+func openImage(ctx context.Context, src, workDir string, log func(string)) (imageFiles, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return dirImage(src), nil
+	}
+	format, err := sniffFile(src)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatTar:
+		return openImageTar(src)
+	case FormatGzip:
+		log("Decompressing image archive...\n")
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		gz, err := gzip.NewReader(&progressReader{r: f, ctx: ctx, total: info.Size(), stage: StageExtract})
+		if err != nil {
+			return nil, err
+		}
+		plain := filepath.Join(workDir, "image.tar")
+		if err := copyToFile(ctx, gz, plain); err != nil {
+			return nil, err
+		}
+		return openImageTar(plain)
+	}
+	return nil, fmt.Errorf("%s is not an image archive (found %s); expected an OCI layout or docker save tar", filepath.Base(src), format)
+}
+
+// walkLayer calls fn for every member of a layer
+func walkLayer(ctx context.Context, img imageFiles, layer imageLayer, fn func(*tar.Header, io.Reader) error) error {
+	rc, err := openLayer(img, layer)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	tr := tar.NewReader(rc)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("layer %s: %w", layer.name, err)
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// InstallImage flattens a container image (OCI layout or docker save tarball) and installs
+// the root filesystem as distroName. user is created when set. It blocks until the
+// installation has finished.
+func InstallImage(ctx context.Context, projectRoot, src, distroName, installPath, user, pass string, prov *model.ProvisioningSettings, onLog func(string)) error {
+	workDir, err := os.MkdirTemp("", "distronexus-image-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	rootfs := filepath.Join(workDir, "rootfs.tar")
+	if err := FlattenImage(ctx, src, rootfs, onLog); err != nil {
+		return err
+	}
+	resCh := make(chan error, 1)
+	runInstall(ctx, projectRoot, map[string]string{"Image": src}, nil, rootfs, distroName, installPath, user, pass, prov, onLog, func(e error) {
+		resCh <- e
+	})
+	return <-resCh
+}
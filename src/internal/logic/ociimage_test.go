@@ -0,0 +1,429 @@
+package logic
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type layerEntry struct {
+	name, body, link string
+	typ              byte
+}
+
+func regFile(name, body string) layerEntry {
+	return layerEntry{name: name, body: body, typ: tar.TypeReg}
+}
+
+func dirEntry(name string) layerEntry {
+	return layerEntry{name: name, typ: tar.TypeDir}
+}
+
+func hardLink(name, target string) layerEntry {
+	return layerEntry{name: name, link: target, typ: tar.TypeLink}
+}
+
+func symLink(name, target string) layerEntry {
+	return layerEntry{name: name, link: target, typ: tar.TypeSymlink}
+}
+
+// testLayer writes entries, in order, as an uncompressed layer tar
+func testLayer(t *testing.T, entries ...layerEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typ, Linkname: e.link, Mode: 0644, Size: int64(len(e.body))}
+		if e.typ == tar.TypeDir {
+			hdr.Mode = 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// ociLayout builds an OCI image layout folder
+type ociLayout struct {
+	t   *testing.T
+	dir string
+}
+
+func newOCILayout(t *testing.T) ociLayout {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return ociLayout{t: t, dir: dir}
+}
+
+// blob stores data under its digest and returns a descriptor for it
+func (l ociLayout) blob(mediaType string, data []byte) map[string]any {
+	l.t.Helper()
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	dir := filepath.Join(l.dir, "blobs", "sha256")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		l.t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, digest), data, 0644); err != nil {
+		l.t.Fatal(err)
+	}
+	return map[string]any{"mediaType": mediaType, "digest": "sha256:" + digest, "size": len(data)}
+}
+
+func (l ociLayout) jsonBlob(mediaType string, v any) map[string]any {
+	l.t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	return l.blob(mediaType, data)
+}
+
+// manifest stores an image manifest over layers (gzip-compressed) and returns its descriptor
+func (l ociLayout) manifest(layers ...[]byte) map[string]any {
+	var descs []map[string]any
+	for _, layer := range layers {
+		descs = append(descs, l.blob("application/vnd.oci.image.layer.v1.tar+gzip", testGzip(l.t, layer)))
+	}
+	config := l.jsonBlob("application/vnd.oci.image.config.v1+json", map[string]any{"architecture": ImageArch(), "os": "linux"})
+	return l.jsonBlob("application/vnd.oci.image.manifest.v1+json", map[string]any{
+		"schemaVersion": 2, "config": config, "layers": descs,
+	})
+}
+
+func withPlatform(desc map[string]any, os, arch string) map[string]any {
+	desc["platform"] = map[string]any{"os": os, "architecture": arch}
+	return desc
+}
+
+func (l ociLayout) writeIndex(manifests ...map[string]any) {
+	l.t.Helper()
+	data, err := json.Marshal(map[string]any{"schemaVersion": 2, "manifests": manifests})
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(l.dir, "index.json"), data, 0644); err != nil {
+		l.t.Fatal(err)
+	}
+}
+
+type flatEntry struct {
+	typ        byte
+	body, link string
+}
+
+// flattenTest runs FlattenImage on src and returns the entries of the result and their order
+func flattenTest(t *testing.T, src string) (map[string]flatEntry, []string) {
+	t.Helper()
+	target := filepath.Join(t.TempDir(), "rootfs.tar")
+	if err := FlattenImage(context.Background(), src, target, nil); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries := make(map[string]flatEntry)
+	var order []string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, dup := entries[hdr.Name]; dup {
+			t.Errorf("%s written twice", hdr.Name)
+		}
+		entries[hdr.Name] = flatEntry{typ: hdr.Typeflag, body: string(body), link: hdr.Linkname}
+		order = append(order, hdr.Name)
+	}
+	return entries, order
+}
+
+func TestFlattenImageWhiteouts(t *testing.T) {
+	l := newOCILayout(t)
+	base := testLayer(t,
+		dirEntry("etc/"), regFile("etc/a", "a"), regFile("etc/b", "old b"),
+		dirEntry("var/"), dirEntry("var/cache/"), regFile("var/cache/x", "x"), dirEntry("var/cache/sub/"), regFile("var/cache/sub/z", "z"),
+		dirEntry("opt/"), regFile("opt/keep", "keep"), dirEntry("opt/tool/"), regFile("opt/tool/bin", "bin"),
+	)
+	upper := testLayer(t,
+		dirEntry("./etc/"), regFile("./etc/.wh.a", ""), regFile("./etc/b", "new b"),
+		dirEntry("var/cache/"), regFile("var/cache/.wh..wh..opq", ""), regFile("var/cache/y", "y"),
+		regFile("opt/.wh.tool", ""),
+	)
+	l.writeIndex(l.manifest(base, upper))
+
+	got, _ := flattenTest(t, l.dir)
+	want := map[string]flatEntry{
+		"etc/":        {typ: tar.TypeDir},
+		"etc/b":       {typ: tar.TypeReg, body: "new b"},
+		"var/":        {typ: tar.TypeDir},
+		"var/cache/":  {typ: tar.TypeDir},
+		"var/cache/y": {typ: tar.TypeReg, body: "y"},
+		"opt/":        {typ: tar.TypeDir},
+		"opt/keep":    {typ: tar.TypeReg, body: "keep"},
+	}
+	if len(got) != len(want) {
+		t.Errorf("got entries %v, want %v", keys(got), keys(want))
+	}
+	for name, w := range want {
+		if g, ok := got[name]; !ok || g != w {
+			t.Errorf("%s = %+v (present %v), want %+v", name, g, ok, w)
+		}
+	}
+}
+
+func TestFlattenImageHardLinks(t *testing.T) {
+	l := newOCILayout(t)
+	base := testLayer(t,
+		dirEntry("bin/"), regFile("bin/a", "old a"), hardLink("bin/b", "bin/a"), hardLink("bin/c", "./bin/a"),
+		dirEntry("lib/"), regFile("lib/x", "x"), hardLink("lib/y", "lib/x"),
+		regFile("lib/gone", "gone"), hardLink("lib/keep", "lib/gone"),
+	)
+	// The upper layer rewrites bin/a, deletes lib/gone and links to a file from the base layer
+	upper := testLayer(t,
+		dirEntry("bin/"), regFile("bin/a", "new a"),
+		regFile("lib/.wh.gone", ""), hardLink("lib/z", "lib/x"),
+	)
+	l.writeIndex(l.manifest(base, upper))
+
+	got, order := flattenTest(t, l.dir)
+	pos := make(map[string]int)
+	for i, name := range order {
+		pos[name] = i
+	}
+	for name, e := range got {
+		if e.typ == tar.TypeLink && pos[e.link] >= pos[name] {
+			t.Errorf("hard link %s written before its target %s", name, e.link)
+		}
+	}
+
+	checks := map[string]flatEntry{
+		"bin/a": {typ: tar.TypeReg, body: "new a"},
+		// b and c linked the old bin/a: b carries its content, c links to b
+		"bin/b":    {typ: tar.TypeReg, body: "old a"},
+		"bin/c":    {typ: tar.TypeLink, link: "bin/b"},
+		"lib/x":    {typ: tar.TypeReg, body: "x"},
+		"lib/y":    {typ: tar.TypeLink, link: "lib/x"},
+		"lib/z":    {typ: tar.TypeLink, link: "lib/x"},
+		"lib/keep": {typ: tar.TypeReg, body: "gone"},
+	}
+	for name, w := range checks {
+		if g, ok := got[name]; !ok || g != w {
+			t.Errorf("%s = %+v (present %v), want %+v", name, g, ok, w)
+		}
+	}
+	if _, ok := got["lib/gone"]; ok {
+		t.Errorf("deleted lib/gone written")
+	}
+}
+
+func TestFlattenImageReplacedDirectory(t *testing.T) {
+	l := newOCILayout(t)
+	base := testLayer(t,
+		dirEntry("lib/"), regFile("lib/x", "x"), dirEntry("lib/sub/"), regFile("lib/sub/y", "y"),
+		dirEntry("data/"), regFile("data/z", "z"),
+	)
+	upper := testLayer(t, symLink("lib", "usr/lib"), regFile("data", "now a file"))
+	top := testLayer(t, dirEntry("usr/"), dirEntry("usr/lib/"), regFile("usr/lib/x", "x2"))
+	l.writeIndex(l.manifest(base, upper, top))
+
+	got, _ := flattenTest(t, l.dir)
+	for name := range got {
+		if strings.HasPrefix(name, "lib/") || strings.HasPrefix(name, "data/") {
+			t.Errorf("%s written under a replaced directory", name)
+		}
+	}
+	if g := got["lib"]; g.typ != tar.TypeSymlink || g.link != "usr/lib" {
+		t.Errorf("lib = %+v, want a symlink to usr/lib", g)
+	}
+	if g := got["data"]; g.typ != tar.TypeReg || g.body != "now a file" {
+		t.Errorf("data = %+v", g)
+	}
+	if g := got["usr/lib/x"]; g.body != "x2" {
+		t.Errorf("usr/lib/x = %+v", g)
+	}
+}
+
+func TestFlattenImageNestedIndex(t *testing.T) {
+	l := newOCILayout(t)
+	other := "s390x"
+	if ImageArch() == other {
+		other = "ppc64le"
+	}
+	wrong := withPlatform(l.manifest(testLayer(t, regFile("arch", other))), "linux", other)
+	right := withPlatform(l.manifest(testLayer(t, regFile("arch", ImageArch()))), "linux", ImageArch())
+	windows := withPlatform(l.manifest(testLayer(t, regFile("arch", "windows"))), "windows", ImageArch())
+	platforms := l.jsonBlob("application/vnd.oci.image.index.v1+json", map[string]any{
+		"schemaVersion": 2, "manifests": []any{wrong, windows, right},
+	})
+	l.writeIndex(platforms)
+
+	got, _ := flattenTest(t, l.dir)
+	if g := got["arch"]; g.body != ImageArch() {
+		t.Errorf("picked the %q variant, want %s", g.body, ImageArch())
+	}
+}
+
+// dockerSave writes a docker save tarball holding manifest.json and the layers, with
+// "./"-prefixed member names and a directory entry between the files
+func dockerSave(t *testing.T, layers ...[]byte) string {
+	t.Helper()
+	var manifestLayers []string
+	var members []layerEntry
+	for i, layer := range layers {
+		name := strings.Repeat("a", i+1) + "/layer.tar"
+		manifestLayers = append(manifestLayers, name)
+		members = append(members, dirEntry("./"+strings.Repeat("a", i+1)+"/"), regFile("./"+name, string(layer)))
+	}
+	manifest, err := json.Marshal([]map[string]any{{"Config": "config.json", "RepoTags": []string{"test:latest"}, "Layers": manifestLayers}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	members = append(members, regFile("./config.json", "{}"), regFile("./manifest.json", string(manifest)))
+	path := filepath.Join(t.TempDir(), "image.tar")
+	if err := os.WriteFile(path, testLayer(t, members...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFlattenImageDockerSave(t *testing.T) {
+	base := testLayer(t, dirEntry("etc/"), regFile("etc/os-release", "ID=base\n"), regFile("etc/motd", strings.Repeat("m", 1500)))
+	upper := testLayer(t, regFile("etc/os-release", "ID=upper\n"))
+	src := dockerSave(t, base, upper)
+
+	check := func(t *testing.T, got map[string]flatEntry) {
+		t.Helper()
+		if g := got["etc/os-release"]; g.body != "ID=upper\n" {
+			t.Errorf("etc/os-release = %q", g.body)
+		}
+		if g := got["etc/motd"]; g.body != strings.Repeat("m", 1500) {
+			t.Errorf("etc/motd read from the wrong offset (%d bytes)", len(g.body))
+		}
+	}
+
+	t.Run("tar", func(t *testing.T) {
+		got, _ := flattenTest(t, src)
+		check(t, got)
+	})
+	t.Run("gzip", func(t *testing.T) {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz := filepath.Join(t.TempDir(), "image.tar.gz")
+		if err := os.WriteFile(gz, testGzip(t, data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := flattenTest(t, gz)
+		check(t, got)
+	})
+}
+
+func TestOpenImageTarOffsets(t *testing.T) {
+	src := dockerSave(t, testLayer(t, regFile("one", "1")), testLayer(t, regFile("two", "22")))
+	img, err := openImageTar(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer img.Close()
+	for _, name := range []string{"manifest.json", "./config.json", "a/layer.tar", "aa/layer.tar"} {
+		rc, err := img.Open(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name == "./config.json" && string(data) != "{}" {
+			t.Errorf("config.json = %q", data)
+		}
+		if strings.HasSuffix(name, "layer.tar") && SniffFormat(data) != FormatTar {
+			t.Errorf("%s does not start at a tar header", name)
+		}
+	}
+	if _, err := img.Open("a"); err == nil {
+		t.Errorf("directory member opened as a file")
+	}
+}
+
+func TestPickManifest(t *testing.T) {
+	arch := ImageArch()
+	desc := func(digest, os, arch string) ociDescriptor {
+		d := ociDescriptor{Digest: digest}
+		if os != "" {
+			d.Platform = &struct {
+				Architecture string `json:"architecture"`
+				OS           string `json:"os"`
+			}{Architecture: arch, OS: os}
+		}
+		return d
+	}
+	tests := []struct {
+		name      string
+		manifests []ociDescriptor
+		want      string // digest, or a substring of the error
+		wantErr   bool
+	}{
+		{"empty", nil, "lists no manifest", true},
+		{"no platform", []ociDescriptor{desc("sha256:only", "", ""), desc("sha256:second", "", "")}, "sha256:only", false},
+		{"host platform", []ociDescriptor{desc("sha256:s390x", "linux", "s390x"), desc("sha256:host", "linux", arch)}, "sha256:host", false},
+		{"windows skipped", []ociDescriptor{desc("sha256:win", "windows", arch), desc("sha256:host", "linux", arch)}, "sha256:host", false},
+		{"attestation without platform", []ociDescriptor{desc("sha256:host", "linux", arch), desc("sha256:att", "", "")}, "sha256:host", false},
+		{"no host variant", []ociDescriptor{desc("sha256:s390x", "linux", "s390x"), desc("sha256:win", "windows", arch)}, "no linux/" + arch + " variant (contains: linux/s390x, windows/" + arch + ")", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pickManifest(tt.manifests)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Fatalf("err = %v, want %q", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Digest != tt.want {
+				t.Errorf("picked %s, want %s", got.Digest, tt.want)
+			}
+		})
+	}
+}
+
+func keys[V any](m map[string]V) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -0,0 +1,68 @@
+package logic
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// OperationDurationBuckets are the histogram upper bounds (seconds) for operation durations.
+// Starts and stops land in the first buckets, installs and moves in the later ones.
+var OperationDurationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}
+
+// OperationStat aggregates finished operations of one type and result since startup
+type OperationStat struct {
+	Operation string
+	Result    string
+	Count     uint64
+	// Sum is the total duration in seconds
+	Sum float64
+	// Buckets holds cumulative counts per OperationDurationBuckets bound
+	Buckets []uint64
+}
+
+type opStatKey struct{ op, result string }
+
+var opStats = struct {
+	sync.Mutex
+	m map[opStatKey]*OperationStat
+}{m: make(map[opStatKey]*OperationStat)}
+
+// observeOperation is called for every operation recorded in the history log
+func observeOperation(op, result string, d time.Duration) {
+	opStats.Lock()
+	defer opStats.Unlock()
+	key := opStatKey{op, result}
+	st, ok := opStats.m[key]
+	if !ok {
+		st = &OperationStat{Operation: op, Result: result, Buckets: make([]uint64, len(OperationDurationBuckets))}
+		opStats.m[key] = st
+	}
+	secs := d.Seconds()
+	st.Count++
+	st.Sum += secs
+	for i, bound := range OperationDurationBuckets {
+		if secs <= bound {
+			st.Buckets[i]++
+		}
+	}
+}
+
+// OperationStats returns a snapshot of the operation counters, sorted by operation and result
+func OperationStats() []OperationStat {
+	opStats.Lock()
+	defer opStats.Unlock()
+	list := make([]OperationStat, 0, len(opStats.m))
+	for _, st := range opStats.m {
+		cp := *st
+		cp.Buckets = append([]uint64(nil), st.Buckets...)
+		list = append(list, cp)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Operation != list[j].Operation {
+			return list[i].Operation < list[j].Operation
+		}
+		return list[i].Result < list[j].Result
+	})
+	return list
+}
@@ -0,0 +1,51 @@
+package logic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestObserveOperationBucketsAreCumulative(t *testing.T) {
+	opStats.Lock()
+	saved := opStats.m
+	opStats.m = make(map[opStatKey]*OperationStat)
+	opStats.Unlock()
+	t.Cleanup(func() {
+		opStats.Lock()
+		opStats.m = saved
+		opStats.Unlock()
+	})
+
+	for _, d := range []time.Duration{500 * time.Millisecond, 5 * time.Second, 45 * time.Second, 2 * time.Hour} {
+		observeOperation(OpInstall, ResultSuccess, d)
+	}
+	observeOperation(OpStart, ResultSuccess, time.Second)
+
+	stats := OperationStats()
+	if len(stats) != 2 || stats[0].Operation != OpInstall || stats[1].Operation != OpStart {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	install := stats[0]
+	if install.Count != 4 {
+		t.Fatalf("count %d, want 4", install.Count)
+	}
+	// Bounds are inclusive: 5s lands in le=5, 2h only in +Inf
+	want := map[float64]uint64{1: 1, 5: 2, 15: 2, 30: 2, 60: 3, 3600: 3}
+	for i, bound := range OperationDurationBuckets {
+		if w, ok := want[bound]; ok && install.Buckets[i] != w {
+			t.Fatalf("le=%g: %d, want %d", bound, install.Buckets[i], w)
+		}
+		if i > 0 && install.Buckets[i] < install.Buckets[i-1] {
+			t.Fatalf("buckets not cumulative: %v", install.Buckets)
+		}
+	}
+	if sum := install.Sum; sum < 7250 || sum > 7251 {
+		t.Fatalf("sum %g", sum)
+	}
+
+	// Snapshots don't share bucket slices with the live counters
+	stats[0].Buckets[0] = 99
+	if OperationStats()[0].Buckets[0] != 1 {
+		t.Fatal("snapshot aliases live buckets")
+	}
+}
@@ -0,0 +1,227 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LxssEntry is one raw distribution key under HKCU\...\Lxss
+type LxssEntry struct {
+	Id          string `json:"Id"`
+	Name        string `json:"Name"`
+	BasePath    string `json:"BasePath"`
+	Version     int    `json:"Version"`
+	VhdFileName string `json:"VhdFileName"`
+}
+
+// OrphanKind tells what is left behind
+type OrphanKind string
+
+const (
+	// OrphanFolder is a directory with a virtual disk that no registered distro uses
+	OrphanFolder OrphanKind = "folder"
+	// OrphanRegistry is a registry entry whose BasePath or disk no longer exists
+	OrphanRegistry OrphanKind = "registry"
+)
+
+// Orphan is a leftover found by FindOrphans
+type Orphan struct {
+	Kind OrphanKind
+	// Name is the registered name (registry orphans) or a suggested name for re-registering
+	Name string
+	Path string
+	// RegistryId is the Lxss key name, registry orphans only
+	RegistryId string
+	Bytes      int64
+	// InCache is true when config/instances.json still lists the instance
+	InCache bool
+}
+
+// ListLxssEntries reads every distribution key from the registry, including broken ones
+// that wsl --list skips
+func ListLxssEntries(projectRoot string) ([]LxssEntry, error) {
+	scriptPath := filepath.Join(projectRoot, "scripts", "lxss_entries.ps1")
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-ExecutionPolicy", "Bypass", "-File", scriptPath)
+	prepareCmd(cmd)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WSL registry entries: %w", err)
+	}
+	var entries []LxssEntry
+	if len(strings.TrimSpace(string(output))) == 0 {
+		return entries, nil
+	}
+	if err := json.Unmarshal(output, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse registry entries: %w", err)
+	}
+	return entries, nil
+}
+
+// readInstancesCache loads config/instances.json as written by list_distros.ps1
+func readInstancesCache(projectRoot string) ([]WslInstance, error) {
+	data, err := os.ReadFile(filepath.Join(projectRoot, "config", "instances.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	// PowerShell writes a UTF-8 BOM and a bare object when there is only one instance
+	data = []byte(strings.TrimPrefix(string(data), "\ufeff"))
+	var list []WslInstance
+	if err := json.Unmarshal(data, &list); err != nil {
+		var single WslInstance
+		if err2 := json.Unmarshal(data, &single); err2 != nil {
+			return nil, fmt.Errorf("failed to parse instances.json: %w", err)
+		}
+		list = []WslInstance{single}
+	}
+	return list, nil
+}
+
+// normalizePath makes registry, cache and filesystem paths comparable
+func normalizePath(p string) string {
+	p = strings.TrimPrefix(p, `\\?\`)
+	p = strings.TrimRight(p, `\/`)
+	return strings.ToLower(p)
+}
+
+// FindOrphans cross-references the registry, instances.json and the folders under
+// installRoot. It reports folders holding an ext4.vhdx that no registered distro uses,
+// and registry entries whose files are gone.
+func FindOrphans(ctx context.Context, projectRoot, installRoot string) ([]Orphan, error) {
+	entries, err := ListLxssEntries(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	cached, err := readInstancesCache(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	registeredPaths := make(map[string]bool)
+	registeredNames := make(map[string]bool)
+	for _, e := range entries {
+		registeredPaths[normalizePath(e.BasePath)] = true
+		registeredNames[strings.ToLower(e.Name)] = true
+	}
+	cachedByPath := make(map[string]WslInstance)
+	cachedNames := make(map[string]bool)
+	for _, c := range cached {
+		cachedByPath[normalizePath(c.BasePath)] = c
+		cachedNames[strings.ToLower(c.Name)] = true
+	}
+
+	var orphans []Orphan
+
+	// 1. Registry entries pointing at missing files
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		base := strings.TrimPrefix(e.BasePath, `\\?\`)
+		missing := false
+		if fi, err := os.Stat(base); err != nil || !fi.IsDir() {
+			missing = true
+		} else if e.Version == 2 {
+			_, err := os.Stat(filepath.Join(base, e.VhdFileName))
+			missing = err != nil
+		}
+		if missing {
+			orphans = append(orphans, Orphan{
+				Kind:       OrphanRegistry,
+				Name:       e.Name,
+				Path:       base,
+				RegistryId: e.Id,
+				InCache:    cachedNames[strings.ToLower(e.Name)],
+			})
+		}
+	}
+
+	// 2. Folders with a virtual disk: children of installRoot plus paths instances.json remembers
+	candidates := make(map[string]string)
+	if installRoot != "" {
+		if dirEntries, err := os.ReadDir(installRoot); err == nil {
+			for _, de := range dirEntries {
+				if de.IsDir() {
+					p := filepath.Join(installRoot, de.Name())
+					candidates[normalizePath(p)] = p
+				}
+			}
+		}
+	}
+	for _, c := range cached {
+		if c.BasePath != "" {
+			candidates[normalizePath(c.BasePath)] = strings.TrimPrefix(c.BasePath, `\\?\`)
+		}
+	}
+
+	for key, dir := range candidates {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if registeredPaths[key] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, "ext4.vhdx")); err != nil {
+			continue
+		}
+		o := Orphan{Kind: OrphanFolder, Path: dir, Name: filepath.Base(dir)}
+		if c, ok := cachedByPath[key]; ok {
+			o.Name = c.Name
+			o.InCache = true
+		}
+		o.Name = uniqueDistroName(o.Name, registeredNames)
+		o.Bytes, _ = DirSize(dir)
+		orphans = append(orphans, o)
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].Kind != orphans[j].Kind {
+			return orphans[i].Kind < orphans[j].Kind
+		}
+		return strings.ToLower(orphans[i].Path) < strings.ToLower(orphans[j].Path)
+	})
+	return orphans, nil
+}
+
+// uniqueDistroName suffixes name until it doesn't clash with a registered distro
+func uniqueDistroName(name string, taken map[string]bool) string {
+	candidate := name
+	for i := 2; taken[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+	return candidate
+}
+
+// ReRegisterOrphan registers an orphaned folder's ext4.vhdx again under name,
+// using the disk where it is (wsl --import-in-place)
+func ReRegisterOrphan(ctx context.Context, projectRoot, name, dir string, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpReRegister, name, map[string]string{"Path": dir})(&err)
+
+	if err = ValidateDistroName(name); err != nil {
+		return err
+	}
+	vhdx := filepath.Join(dir, "ext4.vhdx")
+	if _, err = os.Stat(vhdx); err != nil {
+		return fmt.Errorf("no virtual disk in %s: %w", dir, err)
+	}
+	_, err = runWsl(ctx, onOutput, "--import-in-place", name, vhdx)
+	return err
+}
+
+// RemoveOrphanRegistryEntry deletes a stale Lxss key. Files are left alone.
+func RemoveOrphanRegistryEntry(ctx context.Context, projectRoot string, o Orphan, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpCleanRegistry, o.Name, map[string]string{"RegistryId": o.RegistryId})(&err)
+
+	if o.Kind != OrphanRegistry || o.RegistryId == "" {
+		return fmt.Errorf("'%s' is not a registry orphan", o.Name)
+	}
+	return RunPowerShellScript(ctx, projectRoot, "lxss_entries.ps1", []string{"-Remove", o.RegistryId}, onOutput)
+}
@@ -0,0 +1,304 @@
+package logic
+
+import (
+	"archive/zip"
+	"compress/bzip2"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Zip-based packages told apart by DetectPackage
+const (
+	FormatAppx   = "appx"   // .appx/.msix with the rootfs inside
+	FormatBundle = "bundle" // .appxbundle/.msixbundle holding one package per architecture
+)
+
+// rootfsNames are the root filesystem archives found inside Appx/Msix packages
+var rootfsNames = []string{"install.tar.gz", "install.tar.xz", "install.tar"}
+
+// PreparedPackage is a package reduced to something wsl --import accepts
+type PreparedPackage struct {
+	// Path is the file to import; it may be the source itself
+	Path string
+	// Format is the sniffed format of the source
+	Format string
+	// Vhd is set for VHDX disks, imported with --vhd
+	Vhd bool
+}
+
+// HostPackageArch returns this machine's architecture as Appx names it (x64, arm64, x86).
+// The native architecture wins over the one an emulated process reports.
+func HostPackageArch() string {
+	arch := os.Getenv("PROCESSOR_ARCHITEW6432")
+	if arch == "" {
+		arch = os.Getenv("PROCESSOR_ARCHITECTURE")
+	}
+	switch strings.ToUpper(arch) {
+	case "AMD64":
+		return "x64"
+	case "ARM64":
+		return "arm64"
+	case "X86":
+		return "x86"
+	}
+	switch runtime.GOARCH {
+	case "amd64":
+		return "x64"
+	case "386":
+		return "x86"
+	}
+	return runtime.GOARCH
+}
+
+// DetectPackage sniffs a package file. Zip archives are refined into FormatAppx or
+// FormatBundle by their contents; anything else is SniffFormat's answer.
+func DetectPackage(path string) (string, error) {
+	format, err := sniffFile(path)
+	if err != nil || format != FormatZip {
+		return format, err
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return FormatZip, nil
+	}
+	defer zr.Close()
+	return zipPackageKind(&zr.Reader), nil
+}
+
+func zipPackageKind(zr *zip.Reader) string {
+	for _, zf := range zr.File {
+		name := strings.ToLower(zf.Name)
+		if isRootfsEntry(name) || name == "appxmanifest.xml" {
+			return FormatAppx
+		}
+		if name == "appxmetadata/appxbundlemanifest.xml" || isInnerPackage(name) {
+			return FormatBundle
+		}
+	}
+	return FormatZip
+}
+
+// PreparePackage turns any supported package (.wsl, .tar, .tar.gz/.xz/.zst/.bz2, .vhdx,
+// .appx/.msix and their bundles) into a file for wsl --import. Files it has to create are
+// written to workDir, which the caller removes afterwards.
+func PreparePackage(ctx context.Context, src, workDir string, onOutput func(string)) (PreparedPackage, error) {
+	log := func(s string) {
+		if onOutput != nil {
+			onOutput(s)
+		}
+	}
+	format, err := DetectPackage(src)
+	if err != nil {
+		return PreparedPackage{}, err
+	}
+	prep := PreparedPackage{Path: src, Format: format}
+	log(fmt.Sprintf("Package format: %s (%s)\n", format, filepath.Base(src)))
+
+	switch format {
+	case FormatTar, FormatGzip, FormatXz, FormatZstd:
+		// wsl --import decompresses these itself
+		return prep, nil
+	case FormatVhdx:
+		prep.Vhd = true
+		return prep, nil
+	case FormatBzip2:
+		prep.Path, err = decompressBzip2(ctx, src, workDir)
+		return prep, err
+	case FormatAppx, FormatBundle:
+		f, err := os.Open(src)
+		if err != nil {
+			return prep, err
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return prep, err
+		}
+		zr, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			return prep, fmt.Errorf("cannot read %s: %w", filepath.Base(src), err)
+		}
+		prep.Path, err = extractRootfs(ctx, zr, f, workDir, HostPackageArch(), log, 0)
+		return prep, err
+	}
+	return prep, fmt.Errorf("unrecognised package format for %s; expected a tar archive, VHDX or Appx/Msix package", filepath.Base(src))
+}
+
+// extractRootfs writes the root filesystem of an Appx package to workDir. Bundles are
+// entered once, choosing the inner package for arch. ra is the reader zr was opened on.
+func extractRootfs(ctx context.Context, zr *zip.Reader, ra io.ReaderAt, workDir, arch string, log func(string), depth int) (string, error) {
+	for _, zf := range zr.File {
+		if isRootfsEntry(strings.ToLower(zf.Name)) {
+			log(fmt.Sprintf("Extracting %s...\n", zf.Name))
+			return extractZipFile(ctx, zf, filepath.Join(workDir, path.Base(zf.Name)))
+		}
+	}
+	if depth > 0 {
+		return "", fmt.Errorf("package contains no install.tar.gz")
+	}
+
+	inner, err := pickBundlePackage(zr, arch)
+	if err != nil {
+		return "", err
+	}
+	log(fmt.Sprintf("Using %s for %s\n", inner.Name, arch))
+
+	// Packages are normally stored uncompressed in the bundle: read them in place
+	size := int64(inner.UncompressedSize64)
+	var innerRA io.ReaderAt
+	if offset, err := inner.DataOffset(); err == nil && inner.Method == zip.Store {
+		innerRA = io.NewSectionReader(ra, offset, size)
+	} else {
+		tmp, err := extractZipFile(ctx, inner, filepath.Join(workDir, path.Base(inner.Name)))
+		if err != nil {
+			return "", err
+		}
+		f, err := os.Open(tmp)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		innerRA = f
+	}
+	innerZip, err := zip.NewReader(innerRA, size)
+	if err != nil {
+		return "", fmt.Errorf("cannot read %s: %w", inner.Name, err)
+	}
+	return extractRootfs(ctx, innerZip, innerRA, workDir, arch, log, depth+1)
+}
+
+// bundleManifest is the part of AppxMetadata/AppxBundleManifest.xml listing the packages
+type bundleManifest struct {
+	Packages []struct {
+		Type         string `xml:"Type,attr"`
+		Architecture string `xml:"Architecture,attr"`
+		FileName     string `xml:"FileName,attr"`
+	} `xml:"Packages>Package"`
+}
+
+// pickBundlePackage finds the inner package for arch: from the bundle manifest when there
+// is one, otherwise by the architecture in the file name (Ubuntu_2204.1.7.0_x64.appx)
+func pickBundlePackage(zr *zip.Reader, arch string) (*zip.File, error) {
+	var packages []*zip.File
+	byName := make(map[string]*zip.File)
+	for _, zf := range zr.File {
+		if isInnerPackage(strings.ToLower(zf.Name)) {
+			packages = append(packages, zf)
+			byName[strings.ToLower(zf.Name)] = zf
+		}
+	}
+	if len(packages) == 0 {
+		return nil, fmt.Errorf("package contains neither install.tar.gz nor an .appx/.msix")
+	}
+
+	var available []string
+	if manifest, err := readBundleManifest(zr); err == nil {
+		for _, p := range manifest.Packages {
+			if p.Type != "" && !strings.EqualFold(p.Type, "application") {
+				continue
+			}
+			available = append(available, p.Architecture)
+			if strings.EqualFold(p.Architecture, arch) || strings.EqualFold(p.Architecture, "neutral") {
+				if zf := byName[strings.ToLower(p.FileName)]; zf != nil {
+					return zf, nil
+				}
+			}
+		}
+	}
+
+	for _, zf := range packages {
+		name := strings.ToLower(path.Base(zf.Name))
+		if strings.Contains(name, "_"+strings.ToLower(arch)+".") || strings.Contains(name, "_"+strings.ToLower(arch)+"_") {
+			return zf, nil
+		}
+	}
+	if len(packages) == 1 && len(available) == 0 {
+		return packages[0], nil
+	}
+	if len(available) == 0 {
+		for _, zf := range packages {
+			available = append(available, path.Base(zf.Name))
+		}
+	}
+	return nil, fmt.Errorf("bundle has no package for %s (contains: %s)", arch, strings.Join(available, ", "))
+}
+
+func readBundleManifest(zr *zip.Reader) (*bundleManifest, error) {
+	for _, zf := range zr.File {
+		if !strings.EqualFold(zf.Name, "AppxMetadata/AppxBundleManifest.xml") {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		var m bundleManifest
+		if err := xml.NewDecoder(rc).Decode(&m); err != nil {
+			return nil, err
+		}
+		return &m, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func isRootfsEntry(lowerName string) bool {
+	for _, n := range rootfsNames {
+		if lowerName == n {
+			return true
+		}
+	}
+	return false
+}
+
+func isInnerPackage(lowerName string) bool {
+	return !strings.Contains(lowerName, "/") && (strings.HasSuffix(lowerName, ".appx") || strings.HasSuffix(lowerName, ".msix"))
+}
+
+// extractZipFile copies one archive member to target, reporting extract progress
+func extractZipFile(ctx context.Context, zf *zip.File, target string) (string, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	return target, copyToFile(ctx, &progressReader{r: rc, ctx: ctx, total: int64(zf.UncompressedSize64), stage: StageExtract}, target)
+}
+
+// decompressBzip2 unpacks a .tar.bz2 into a plain tar, which wsl --import does not need to decode
+func decompressBzip2(ctx context.Context, src, workDir string) (string, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(workDir, "rootfs.tar")
+	counted := &progressReader{r: f, ctx: ctx, total: info.Size(), stage: StageExtract}
+	return target, copyToFile(ctx, bzip2.NewReader(counted), target)
+}
+
+func copyToFile(ctx context.Context, r io.Reader, target string) error {
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, r)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	return err
+}
@@ -0,0 +1,235 @@
+package logic
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testBundleManifest renders AppxMetadata/AppxBundleManifest.xml; each package is
+// "type:architecture:filename"
+func testBundleManifest(packages ...string) []byte {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<Bundle xmlns="http://schemas.microsoft.com/appx/2013/bundle"><Packages>`)
+	for _, p := range packages {
+		parts := strings.SplitN(p, ":", 3)
+		fmt.Fprintf(&b, `<Package Type="%s" Architecture="%s" FileName="%s"/>`, parts[0], parts[1], parts[2])
+	}
+	b.WriteString(`</Packages></Bundle>`)
+	return []byte(b.String())
+}
+
+func openTestZip(t *testing.T, data []byte) *zip.Reader {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+func TestDetectPackage(t *testing.T) {
+	tarData := testTar(t, map[string]string{"etc/os-release": "ID=test\n"})
+	appx := testZip(t, testZipEntry{"install.tar.gz", testGzip(t, tarData), zip.Store})
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"tar", tarData, FormatTar},
+		{"gzip", testGzip(t, tarData), FormatGzip},
+		{"appx with rootfs", appx, FormatAppx},
+		{"appx manifest only", testZip(t, testZipEntry{"AppxManifest.xml", []byte("<Package/>"), zip.Deflate}), FormatAppx},
+		{"bundle manifest", testZip(t, testZipEntry{"AppxMetadata/AppxBundleManifest.xml", testBundleManifest(), zip.Deflate}), FormatBundle},
+		{"bundle by inner package", testZip(t, testZipEntry{"Distro_1.0_x64.msix", appx, zip.Store}), FormatBundle},
+		{"nested package is not a bundle", testZip(t, testZipEntry{"sub/Distro_1.0_x64.appx", appx, zip.Store}), FormatZip},
+		{"plain zip", testZip(t, testZipEntry{"readme.txt", []byte("hi"), zip.Deflate}), FormatZip},
+		{"unknown", []byte("not a package"), FormatUnknown},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-"))
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := DetectPackage(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("DetectPackage = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickBundlePackage(t *testing.T) {
+	inner := []byte("inner package")
+	manifest := func(packages ...string) testZipEntry {
+		return testZipEntry{"AppxMetadata/AppxBundleManifest.xml", testBundleManifest(packages...), zip.Deflate}
+	}
+	pkg := func(name string) testZipEntry {
+		return testZipEntry{name, inner, zip.Store}
+	}
+
+	tests := []struct {
+		name    string
+		entries []testZipEntry
+		arch    string
+		want    string // picked package, or a substring of the error when wantErr
+		wantErr bool
+	}{
+		{
+			name:    "manifest selects architecture",
+			entries: []testZipEntry{manifest("application:x64:DistroA.appx", "application:arm64:DistroB.appx"), pkg("DistroA.appx"), pkg("DistroB.appx")},
+			arch:    "arm64",
+			want:    "DistroB.appx",
+		},
+		{
+			name:    "manifest match is case-insensitive",
+			entries: []testZipEntry{manifest("application:X64:distro-main.APPX"), pkg("Distro-Main.appx")},
+			arch:    "x64",
+			want:    "Distro-Main.appx",
+		},
+		{
+			name:    "manifest skips resource packages",
+			entries: []testZipEntry{manifest("resource:x64:Distro_1.0_x64_scale-100.appx", "application:x64:Distro.appx"), pkg("Distro_1.0_x64_scale-100.appx"), pkg("Distro.appx")},
+			arch:    "x64",
+			want:    "Distro.appx",
+		},
+		{
+			name:    "manifest neutral package",
+			entries: []testZipEntry{manifest("application:neutral:Distro.msix"), pkg("Distro.msix")},
+			arch:    "arm64",
+			want:    "Distro.msix",
+		},
+		{
+			name:    "filename fallback",
+			entries: []testZipEntry{pkg("Ubuntu_2204.1.7.0_arm64.appx"), pkg("Ubuntu_2204.1.7.0_x64.appx")},
+			arch:    "x64",
+			want:    "Ubuntu_2204.1.7.0_x64.appx",
+		},
+		{
+			name:    "filename fallback when manifest names a missing file",
+			entries: []testZipEntry{manifest("application:x64:Gone.appx"), pkg("Distro_1.0_x64_release.msix")},
+			arch:    "x64",
+			want:    "Distro_1.0_x64_release.msix",
+		},
+		{
+			name:    "single package without architecture",
+			entries: []testZipEntry{pkg("Distro.appx")},
+			arch:    "arm64",
+			want:    "Distro.appx",
+		},
+		{
+			name:    "no package for host in manifest",
+			entries: []testZipEntry{manifest("application:x64:DistroA.appx", "application:x86:DistroB.appx"), pkg("DistroA.appx"), pkg("DistroB.appx")},
+			arch:    "arm64",
+			want:    "bundle has no package for arm64 (contains: x64, x86)",
+			wantErr: true,
+		},
+		{
+			name:    "no package for host by filename",
+			entries: []testZipEntry{pkg("Distro_1.0_x64.appx"), pkg("Distro_1.0_x86.appx")},
+			arch:    "arm64",
+			want:    "bundle has no package for arm64 (contains: Distro_1.0_x64.appx, Distro_1.0_x86.appx)",
+			wantErr: true,
+		},
+		{
+			name:    "no inner packages",
+			entries: []testZipEntry{manifest("application:x64:Distro.appx")},
+			arch:    "x64",
+			want:    "neither install.tar.gz nor an .appx/.msix",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zf, err := pickBundlePackage(openTestZip(t, testZip(t, tt.entries...)), tt.arch)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Fatalf("err = %v, want %q", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if zf.Name != tt.want {
+				t.Errorf("picked %s, want %s", zf.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractRootfs(t *testing.T) {
+	rootfs := testGzip(t, testTar(t, map[string]string{"etc/os-release": "ID=test\n"}))
+	appxFor := func(arch string) []byte {
+		return testZip(t,
+			testZipEntry{"AppxManifest.xml", []byte("<Package/>"), zip.Deflate},
+			testZipEntry{"install.tar.gz", append(bytes.Clone(rootfs), arch...), zip.Store},
+		)
+	}
+	bundle := func(method uint16) []byte {
+		return testZip(t,
+			testZipEntry{"AppxMetadata/AppxBundleManifest.xml", testBundleManifest("application:x64:DistroX.appx", "application:arm64:DistroA.appx"), zip.Deflate},
+			testZipEntry{"DistroX.appx", appxFor("x64"), method},
+			testZipEntry{"DistroA.appx", appxFor("arm64"), method},
+		)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		arch    string
+		want    []byte // extracted rootfs, nil when an error is expected
+		wantErr string
+	}{
+		{"appx", appxFor("x64"), "x64", append(bytes.Clone(rootfs), "x64"...), ""},
+		{"bundle stored", bundle(zip.Store), "arm64", append(bytes.Clone(rootfs), "arm64"...), ""},
+		{"bundle deflated", bundle(zip.Deflate), "x64", append(bytes.Clone(rootfs), "x64"...), ""},
+		{"bundle without host package", bundle(zip.Store), "x86", nil, "bundle has no package for x86"},
+		{
+			"inner package without rootfs",
+			testZip(t, testZipEntry{"Distro_x64.appx", testZip(t, testZipEntry{"AppxManifest.xml", []byte("<Package/>"), zip.Deflate}), zip.Store}),
+			"x64", nil, "package contains no install.tar.gz",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workDir := t.TempDir()
+			var logged []string
+			got, err := extractRootfs(context.Background(), openTestZip(t, tt.data), bytes.NewReader(tt.data), workDir, tt.arch, func(s string) { logged = append(logged, s) }, 0)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != filepath.Join(workDir, "install.tar.gz") {
+				t.Errorf("extracted to %s", got)
+			}
+			data, err := os.ReadFile(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, tt.want) {
+				t.Errorf("extracted rootfs does not match the %s package", tt.arch)
+			}
+			if len(logged) == 0 || !strings.Contains(logged[len(logged)-1], "Extracting install.tar.gz") {
+				t.Errorf("log = %q", logged)
+			}
+		})
+	}
+}
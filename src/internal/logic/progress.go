@@ -0,0 +1,111 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// ProgressPrefix marks a machine-readable progress line in script output:
+//
+//	::progress::{"stage":"download","percent":42,"message":"120 MB / 300 MB"}
+//
+// Scripts emit these through Write-ProgressEvent in pwsh_utils.ps1. Everything
+// else on stdout/stderr is treated as a plain log line.
+const ProgressPrefix = "::progress::"
+
+// Well-known stage names used by the install flow
+const (
+	StageDownload   = "download"
+	StageExtract    = "extract"
+	StageImport     = "import"
+	StageCreateUser = "create_user"
+	StageExport     = "export"
+	StageCleanup    = "cleanup"
+	StageTrim       = "trim"
+	StageStop       = "stop"
+	StageCompact    = "compact"
+	StageVerify     = "verify"
+	StagePackage    = "package"
+)
+
+// ProgressEvent reports how far an operation has got.
+// Percent is the completion of the current stage (0-100), or negative when unknown.
+// Stages optionally announces the full ordered list of stages for the operation.
+type ProgressEvent struct {
+	Stage   string   `json:"stage"`
+	Percent float64  `json:"percent"`
+	Message string   `json:"message,omitempty"`
+	Stages  []string `json:"stages,omitempty"`
+}
+
+// StageTitle turns a stage id into a display name ("create_user" -> "Create user")
+func StageTitle(stage string) string {
+	s := strings.ReplaceAll(stage, "_", " ")
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// ParseProgressLine recognises a progress line; ok is false for ordinary log output
+func ParseProgressLine(line string) (ev ProgressEvent, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, ProgressPrefix) {
+		return ev, false
+	}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(trimmed, ProgressPrefix)), &ev); err != nil {
+		return ev, false
+	}
+	if ev.Percent > 100 {
+		ev.Percent = 100
+	}
+	return ev, true
+}
+
+// FormatProgressLine renders an event in the wire format
+func FormatProgressLine(ev ProgressEvent) string {
+	data, _ := json.Marshal(ev)
+	return ProgressPrefix + string(data)
+}
+
+type progressKey struct{}
+
+// WithProgress returns a context whose operations report progress events to fn
+func WithProgress(ctx context.Context, fn func(ProgressEvent)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress sends ev to the progress sink attached to ctx, if any.
+// Native Go operations use this directly; script output is routed here by splitProgress.
+func ReportProgress(ctx context.Context, ev ProgressEvent) {
+	if fn, ok := ctx.Value(progressKey{}).(func(ProgressEvent)); ok && fn != nil {
+		fn(ev)
+	}
+}
+
+// splitProgress wraps a line callback so progress lines go to the context's sink
+// and only ordinary output reaches onOutput
+func splitProgress(ctx context.Context, onOutput func(string)) func(string) {
+	return func(line string) {
+		if ev, ok := ParseProgressLine(line); ok {
+			ReportProgress(ctx, ev)
+			return
+		}
+		if onOutput != nil {
+			onOutput(line)
+		}
+	}
+}
+
+// stripProgressLines removes progress lines from captured output (used in error messages)
+func stripProgressLines(output string) string {
+	lines := strings.Split(output, "\n")
+	kept := lines[:0]
+	for _, l := range lines {
+		if _, ok := ParseProgressLine(l); !ok {
+			kept = append(kept, l)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
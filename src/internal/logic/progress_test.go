@@ -0,0 +1,118 @@
+package logic
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseProgressLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want ProgressEvent
+		ok   bool
+	}{
+		{"well formed", `::progress::{"stage":"download","percent":42,"message":"120 MB / 300 MB"}`,
+			ProgressEvent{Stage: StageDownload, Percent: 42, Message: "120 MB / 300 MB"}, true},
+		{"stage list", `::progress::{"stage":"export","percent":-1,"stages":["stop","export","package"]}`,
+			ProgressEvent{Stage: StageExport, Percent: -1, Stages: []string{StageStop, StageExport, StagePackage}}, true},
+		{"surrounding whitespace", "  ::progress::{\"stage\":\"import\",\"percent\":0}\r",
+			ProgressEvent{Stage: StageImport}, true},
+		{"percent clamped", `::progress::{"stage":"extract","percent":250}`,
+			ProgressEvent{Stage: StageExtract, Percent: 100}, true},
+		{"plain log line", "Downloading rootfs...", ProgressEvent{}, false},
+		{"empty", "", ProgressEvent{}, false},
+		{"prefix only", "::progress::", ProgressEvent{}, false},
+		{"not json", "::progress::42%", ProgressEvent{}, false},
+		{"wrong type", `::progress::{"stage":"download","percent":"42"}`, ProgressEvent{}, false},
+		{"truncated", `::progress::{"stage":"download","perc`, ProgressEvent{}, false},
+		{"trailing garbage", `::progress::{"stage":"download","percent":1}{"stage":"import"}`, ProgressEvent{}, false},
+		{"prefix mid line", `Step 1 ::progress::{"stage":"download","percent":1}`, ProgressEvent{}, false},
+		{"prefix case", `::PROGRESS::{"stage":"download","percent":1}`, ProgressEvent{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseProgressLine(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatProgressLineRoundTrip(t *testing.T) {
+	ev := ProgressEvent{Stage: StageCreateUser, Percent: 12.5, Message: "adding user", Stages: []string{StageImport, StageCreateUser}}
+	got, ok := ParseProgressLine(FormatProgressLine(ev))
+	if !ok || !reflect.DeepEqual(got, ev) {
+		t.Fatalf("round trip: got %+v (ok %v), want %+v", got, ok, ev)
+	}
+}
+
+func TestSplitProgressInterleaved(t *testing.T) {
+	lines := []string{
+		"Starting install",
+		`::progress::{"stage":"download","percent":0,"stages":["download","import"]}`,
+		"Fetching https://example.invalid/rootfs.tar.gz",
+		`::progress::{"stage":"download","percent":50}`,
+		`::progress::{"stage":"download","perc`,
+		`::progress::{"stage":"download","percent":100}`,
+		"",
+		`::progress::{"stage":"import","percent":-1}`,
+		"Import complete",
+	}
+
+	var events []ProgressEvent
+	ctx := WithProgress(context.Background(), func(ev ProgressEvent) { events = append(events, ev) })
+	var output []string
+	emit := splitProgress(ctx, func(line string) { output = append(output, line) })
+	for _, l := range lines {
+		emit(l)
+	}
+
+	wantEvents := []ProgressEvent{
+		{Stage: StageDownload, Percent: 0, Stages: []string{StageDownload, StageImport}},
+		{Stage: StageDownload, Percent: 50},
+		{Stage: StageDownload, Percent: 100},
+		{Stage: StageImport, Percent: -1},
+	}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Fatalf("events %+v, want %+v", events, wantEvents)
+	}
+	// A partial progress line is not swallowed, so the log still shows what the script wrote
+	wantOutput := []string{
+		"Starting install",
+		"Fetching https://example.invalid/rootfs.tar.gz",
+		`::progress::{"stage":"download","perc`,
+		"",
+		"Import complete",
+	}
+	if !reflect.DeepEqual(output, wantOutput) {
+		t.Fatalf("output %q, want %q", output, wantOutput)
+	}
+}
+
+func TestSplitProgressWithoutSink(t *testing.T) {
+	// Without a sink progress lines are dropped rather than leaking into the log
+	var output []string
+	emit := splitProgress(context.Background(), func(line string) { output = append(output, line) })
+	emit(`::progress::{"stage":"trim","percent":10}`)
+	emit("fstrim done")
+	if !reflect.DeepEqual(output, []string{"fstrim done"}) {
+		t.Fatalf("output %q", output)
+	}
+
+	// A nil output callback discards plain lines instead of panicking
+	splitProgress(context.Background(), nil)("plain line")
+}
+
+func TestStripProgressLines(t *testing.T) {
+	in := "Export failed\n::progress::{\"stage\":\"export\",\"percent\":30}\nAccess is denied.\n::progress::{broken\n"
+	want := "Export failed\nAccess is denied.\n::progress::{broken\n"
+	if got := stripProgressLines(in); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
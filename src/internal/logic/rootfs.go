@@ -0,0 +1,653 @@
+package logic
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"distronexus-gui/internal/model"
+)
+
+// ErrRootfsFormat is returned by CustomizeRootfs for archives it cannot rewrite (xz, zstd).
+// The install script then configures the instance after its first boot instead.
+var ErrRootfsFormat = errors.New("root filesystem cannot be customized offline")
+
+// RootfsSetup is what CustomizeRootfs writes into a root filesystem
+type RootfsSetup struct {
+	// User is created (or updated) and made the default user; "" or "root" keeps root
+	User     string
+	Password string
+	Systemd  bool
+	// CACerts are PEM files on the host added to the trust store
+	CACerts []string
+	// Dotfiles is a host folder copied into the default user's home
+	Dotfiles string
+}
+
+// NewRootfsSetup combines the install dialog's user with the provisioning settings
+func NewRootfsSetup(user, pass string, prov *model.ProvisioningSettings) RootfsSetup {
+	s := RootfsSetup{User: user, Password: pass}
+	if prov != nil {
+		s.Systemd = prov.Systemd
+		s.CACerts = prov.CACertFiles
+		s.Dotfiles = prov.DotfilesDir
+	}
+	return s
+}
+
+// IsEmpty reports whether the setup changes nothing
+func (s RootfsSetup) IsEmpty() bool {
+	return (s.User == "" || s.User == "root") && s.Password == "" && !s.Systemd && len(s.CACerts) == 0 && s.Dotfiles == ""
+}
+
+// linuxUserName is what useradd accepts by default
+var linuxUserName = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+
+// ValidateLinuxUser checks a user name for the new instance
+func ValidateLinuxUser(name string) error {
+	if !linuxUserName.MatchString(name) {
+		return fmt.Errorf("user name must start with a lower-case letter or _ and contain only a-z, 0-9, _ and - (max 32)")
+	}
+	return nil
+}
+
+// Trust stores: the anchor folder of each distro family (first existing one wins) and
+// the bundles it generates, appended to directly so the certificates work on first boot
+var (
+	caAnchorDirs = []string{
+		"usr/local/share/ca-certificates",          // Debian, Ubuntu, Alpine
+		"etc/pki/ca-trust/source/anchors",          // Fedora, RHEL, Oracle
+		"etc/ca-certificates/trust-source/anchors", // Arch
+		"usr/share/pki/trust/anchors",              // openSUSE
+	}
+	caBundles = []string{
+		"etc/ssl/certs/ca-certificates.crt",
+		"etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+		"etc/ssl/ca-bundle.pem",
+		"etc/ssl/cert.pem",
+	}
+)
+
+// rootfsEdited are the existing files CustomizeRootfs may rewrite; their content is read up front
+var rootfsEdited = append([]string{"etc/passwd", "etc/shadow", "etc/group", "etc/gshadow", "etc/wsl.conf"}, caBundles...)
+
+// rootfsScan is what the first pass learns about the archive
+type rootfsScan struct {
+	types    map[string]byte   // entry name -> tar type flag
+	files    map[string][]byte // contents of rootfsEdited and /etc/skel
+	modes    map[string]int64  // modes of /etc/skel entries
+	dotSlash bool              // entries are named "./etc/..."
+}
+
+// rootfsFile is an entry appended to the archive
+type rootfsFile struct {
+	name     string
+	dir      bool
+	mode     int64
+	uid, gid int
+	data     []byte
+}
+
+// rootfsPlan collects the changes: replace holds new content for existing files, add new entries
+type rootfsPlan struct {
+	scan    *rootfsScan
+	replace map[string][]byte
+	add     []rootfsFile
+	added   map[string]bool
+	log     func(string)
+}
+
+// CustomizeRootfs copies the tar (optionally gzip) archive src to target with the setup applied:
+// the user in /etc/passwd, /etc/shadow (hashed password) and /etc/group, a sudoers drop-in,
+// the default user and systemd in /etc/wsl.conf, CA certificates and dotfiles. The result is
+// an uncompressed tar that wsl --import accepts, so the instance is configured on first boot.
+func CustomizeRootfs(ctx context.Context, src, target string, setup RootfsSetup, onOutput func(string)) error {
+	log := func(s string) {
+		if onOutput != nil {
+			onOutput(s)
+		}
+	}
+	format, err := sniffFile(src)
+	if err != nil {
+		return err
+	}
+	if format != FormatTar && format != FormatGzip {
+		return fmt.Errorf("%w (%s)", ErrRootfsFormat, format)
+	}
+	if setup.User != "" && setup.User != "root" {
+		if err := ValidateLinuxUser(setup.User); err != nil {
+			return err
+		}
+	}
+
+	ReportProgress(ctx, ProgressEvent{Stage: StageExtract, Percent: 0, Message: "Reading root filesystem..."})
+	scan := &rootfsScan{types: make(map[string]byte), files: make(map[string][]byte), modes: make(map[string]int64)}
+	if err := readRootfs(ctx, src, format, func(tr *tar.Reader, hdr *tar.Header, name string) error {
+		scan.types[name] = hdr.Typeflag
+		if len(scan.types) == 1 {
+			scan.dotSlash = strings.HasPrefix(hdr.Name, "./")
+		}
+		if hdr.Typeflag == tar.TypeReg && (isRootfsEdited(name) || strings.HasPrefix(name, "etc/skel/")) {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			scan.files[name] = data
+		}
+		if strings.HasPrefix(name, "etc/skel/") {
+			scan.modes[name] = hdr.Mode
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	plan := &rootfsPlan{scan: scan, replace: make(map[string][]byte), added: make(map[string]bool), log: log}
+	if err := plan.apply(setup); err != nil {
+		return err
+	}
+
+	ReportProgress(ctx, ProgressEvent{Stage: StageExtract, Percent: 0, Message: "Writing customized root filesystem..."})
+	return writeRootfs(ctx, src, format, target, plan)
+}
+
+func isRootfsEdited(name string) bool {
+	for _, n := range rootfsEdited {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rootfsName normalizes an entry name ("./etc/passwd", "/etc/passwd", "etc/" -> "etc/passwd", "etc")
+func rootfsName(name string) string {
+	name = strings.TrimPrefix(name, "./")
+	name = strings.TrimLeft(name, "/")
+	return strings.TrimSuffix(name, "/")
+}
+
+// readRootfs walks every entry of the archive
+func readRootfs(ctx context.Context, src, format string, visit func(tr *tar.Reader, hdr *tar.Header, name string) error) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	var r io.Reader = &progressReader{r: f, ctx: ctx, total: info.Size(), stage: StageExtract}
+	if format == FormatGzip {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", filepath.Base(src), err)
+		}
+		if err := visit(tr, hdr, rootfsName(hdr.Name)); err != nil {
+			return err
+		}
+	}
+}
+
+// writeRootfs copies the archive with the plan's replacements, then appends its new entries
+func writeRootfs(ctx context.Context, src, format, target string, plan *rootfsPlan) error {
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(out)
+	err = readRootfs(ctx, src, format, func(tr *tar.Reader, hdr *tar.Header, name string) error {
+		if data, ok := plan.replace[name]; ok && hdr.Typeflag == tar.TypeReg {
+			h := *hdr
+			h.Size = int64(len(data))
+			if err := tw.WriteHeader(&h); err != nil {
+				return err
+			}
+			_, err := tw.Write(data)
+			return err
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := io.Copy(tw, tr)
+		return err
+	})
+	now := time.Now()
+	prefix := ""
+	if plan.scan.dotSlash {
+		prefix = "./"
+	}
+	for _, f := range plan.add {
+		if err != nil {
+			break
+		}
+		h := &tar.Header{Name: prefix + f.name, Mode: f.mode, Uid: f.uid, Gid: f.gid, ModTime: now, Typeflag: tar.TypeReg, Size: int64(len(f.data))}
+		if f.dir {
+			h.Name += "/"
+			h.Typeflag = tar.TypeDir
+			h.Size = 0
+		}
+		if err = tw.WriteHeader(h); err == nil && !f.dir {
+			_, err = tw.Write(f.data)
+		}
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(target)
+	}
+	return err
+}
+
+// apply computes all file changes for setup
+func (p *rootfsPlan) apply(setup RootfsSetup) error {
+	user := setup.User
+	if user == "" {
+		user = "root"
+	}
+	uid, gid, home, err := p.configureUser(user, setup.Password)
+	if err != nil {
+		return err
+	}
+
+	conf := string(p.scan.files["etc/wsl.conf"])
+	if user != "root" {
+		conf = setIniValue(conf, "user", "default", user)
+	}
+	if setup.Systemd {
+		conf = setIniValue(conf, "boot", "systemd", "true")
+	}
+	if conf != string(p.scan.files["etc/wsl.conf"]) {
+		p.put("etc/wsl.conf", []byte(conf), 0644, 0, 0)
+	}
+
+	if err := p.addCACerts(setup.CACerts); err != nil {
+		return err
+	}
+	if setup.Dotfiles != "" {
+		if err := p.addDotfiles(setup.Dotfiles, home, uid, gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configureUser adds user to passwd/shadow/group (or updates an existing one) and returns
+// its ids and home folder. New users get /etc/skel, sudo/wheel membership and a sudoers drop-in.
+func (p *rootfsPlan) configureUser(user, password string) (uid, gid int, home string, err error) {
+	passwd := parseColonFile(p.scan.files["etc/passwd"])
+	if len(passwd) == 0 {
+		return 0, 0, "", fmt.Errorf("root filesystem has no /etc/passwd")
+	}
+	hash := ""
+	if password != "" {
+		if hash, err = HashPassword(password); err != nil {
+			return 0, 0, "", err
+		}
+	}
+
+	if existing := findColonEntry(passwd, user); existing != nil && len(existing) >= 6 {
+		uid, _ = strconv.Atoi(existing[2])
+		gid, _ = strconv.Atoi(existing[3])
+		home = strings.TrimLeft(existing[5], "/")
+		if hash != "" {
+			p.setShadow(user, hash)
+			p.log(fmt.Sprintf("Setting password of existing user '%s'\n", user))
+		}
+		return uid, gid, home, nil
+	}
+
+	groups := parseColonFile(p.scan.files["etc/group"])
+	uid = nextFreeId(passwd, 2, 1000)
+	gid = uid
+	if findColonId(groups, 2, gid) {
+		gid = nextFreeId(groups, 2, 1000)
+	}
+	home = "home/" + user
+	shell := "/bin/sh"
+	if _, ok := p.scan.types["bin/bash"]; ok {
+		shell = "/bin/bash"
+	} else if _, ok := p.scan.types["usr/bin/bash"]; ok {
+		shell = "/bin/bash"
+	}
+	p.log(fmt.Sprintf("Adding user '%s' (uid %d, shell %s)\n", user, uid, shell))
+
+	passwd = append(passwd, []string{user, "x", strconv.Itoa(uid), strconv.Itoa(gid), "", "/" + home, shell})
+	p.put("etc/passwd", joinColonFile(passwd), 0644, 0, 0)
+
+	groups = append(groups, []string{user, "x", strconv.Itoa(gid), ""})
+	var admin []string
+	for _, g := range groups {
+		if len(g) >= 4 && (g[0] == "sudo" || g[0] == "wheel") {
+			g[3] = appendMember(g[3], user)
+			admin = append(admin, g[0])
+		}
+	}
+	p.put("etc/group", joinColonFile(groups), 0644, 0, 0)
+	if _, ok := p.scan.files["etc/gshadow"]; ok {
+		gshadow := append(parseColonFile(p.scan.files["etc/gshadow"]), []string{user, "!", "", ""})
+		for _, g := range gshadow {
+			if len(g) >= 4 && (g[0] == "sudo" || g[0] == "wheel") {
+				g[3] = appendMember(g[3], user)
+			}
+		}
+		p.put("etc/gshadow", joinColonFile(gshadow), 0640, 0, shadowGroup(groups))
+	}
+	if hash == "" {
+		hash = "!"
+	}
+	p.setShadow(user, hash)
+	if len(admin) > 0 {
+		p.log(fmt.Sprintf("Adding '%s' to %s\n", user, strings.Join(admin, ", ")))
+	}
+	if p.scan.types["etc/sudoers.d"] == tar.TypeDir {
+		p.put("etc/sudoers.d/"+user, []byte(user+" ALL=(ALL:ALL) ALL\n"), 0440, 0, 0)
+	}
+
+	p.mkdirAll(path.Dir(home), 0755, 0, 0)
+	p.mkdir(home, 0750, uid, gid)
+	var skel []string
+	for name := range p.scan.modes {
+		skel = append(skel, name)
+	}
+	sort.Strings(skel)
+	for _, name := range skel {
+		dst := home + "/" + strings.TrimPrefix(name, "etc/skel/")
+		if p.scan.types[name] == tar.TypeDir {
+			p.mkdir(dst, p.scan.modes[name]&0777, uid, gid)
+		} else if data, ok := p.scan.files[name]; ok {
+			p.put(dst, data, p.scan.modes[name]&0777, uid, gid)
+		}
+	}
+	return uid, gid, home, nil
+}
+
+// setShadow sets the password hash of user, adding the shadow entry (and file) when missing
+func (p *rootfsPlan) setShadow(user, hash string) {
+	shadow := parseColonFile(p.currentFile("etc/shadow"))
+	days := strconv.FormatInt(time.Now().Unix()/86400, 10)
+	if entry := findColonEntry(shadow, user); entry != nil && len(entry) >= 3 {
+		entry[1], entry[2] = hash, days
+	} else {
+		shadow = append(shadow, []string{user, hash, days, "0", "99999", "7", "", "", ""})
+	}
+	p.put("etc/shadow", joinColonFile(shadow), 0640, 0, shadowGroup(parseColonFile(p.currentFile("etc/group"))))
+}
+
+// currentFile is the planned content of name, or its original content
+func (p *rootfsPlan) currentFile(name string) []byte {
+	if data, ok := p.replace[name]; ok {
+		return data
+	}
+	for _, f := range p.add {
+		if f.name == name {
+			return f.data
+		}
+	}
+	return p.scan.files[name]
+}
+
+// addCACerts installs the PEM certificates into the anchor folder and the generated bundles
+func (p *rootfsPlan) addCACerts(files []string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	var all []byte
+	certs := make(map[string][]byte)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if block, _ := pem.Decode(data); block == nil || block.Type != "CERTIFICATE" {
+			return fmt.Errorf("%s is not a PEM certificate", filepath.Base(file))
+		}
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			data = append(data, '\n')
+		}
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)) + ".crt"
+		certs[name] = data
+		all = append(all, data...)
+	}
+
+	trusted := false
+	for _, dir := range caAnchorDirs {
+		if p.scan.types[dir] != tar.TypeDir {
+			continue
+		}
+		for name, data := range certs {
+			p.put(dir+"/"+name, data, 0644, 0, 0)
+		}
+		trusted = true
+		break
+	}
+	for _, bundle := range caBundles {
+		if orig, ok := p.scan.files[bundle]; ok {
+			data := append([]byte(nil), orig...)
+			if len(data) > 0 && data[len(data)-1] != '\n' {
+				data = append(data, '\n')
+			}
+			p.put(bundle, append(data, all...), 0644, 0, 0)
+			trusted = true
+		}
+	}
+	if trusted {
+		p.log(fmt.Sprintf("Adding %d CA certificate(s)\n", len(certs)))
+	} else {
+		p.log("WARNING: no known CA trust store found; certificates were not added\n")
+	}
+	return nil
+}
+
+// addDotfiles copies the host folder dir into home
+func (p *rootfsPlan) addDotfiles(dir, home string, uid, gid int) error {
+	count := 0
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil || rel == "." {
+			return err
+		}
+		dst := home + "/" + filepath.ToSlash(rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			p.mkdir(dst, int64(info.Mode().Perm()|0700), uid, gid)
+		case d.Type().IsRegular():
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			p.put(dst, data, int64(info.Mode().Perm()|0600), uid, gid)
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("copying dotfiles: %w", err)
+	}
+	p.log(fmt.Sprintf("Adding %d dotfile(s) to /%s\n", count, home))
+	return nil
+}
+
+// put writes a regular file: existing files are replaced in place, new ones appended
+func (p *rootfsPlan) put(name string, data []byte, mode int64, uid, gid int) {
+	switch typ, exists := p.scan.types[name]; {
+	case exists && typ == tar.TypeReg:
+		p.replace[name] = data
+	case exists:
+		p.log(fmt.Sprintf("WARNING: /%s is not a regular file; left unchanged\n", name))
+	case p.added[name]:
+		for i := range p.add {
+			if p.add[i].name == name {
+				p.add[i].data = data
+			}
+		}
+	default:
+		p.mkdirAll(path.Dir(name), 0755, 0, 0)
+		p.add = append(p.add, rootfsFile{name: name, mode: mode, uid: uid, gid: gid, data: data})
+		p.added[name] = true
+	}
+}
+
+func (p *rootfsPlan) mkdir(name string, mode int64, uid, gid int) {
+	if _, exists := p.scan.types[name]; exists || p.added[name] {
+		return
+	}
+	p.mkdirAll(path.Dir(name), 0755, 0, 0)
+	p.add = append(p.add, rootfsFile{name: name, dir: true, mode: mode, uid: uid, gid: gid})
+	p.added[name] = true
+}
+
+func (p *rootfsPlan) mkdirAll(name string, mode int64, uid, gid int) {
+	if name == "." || name == "" {
+		return
+	}
+	p.mkdir(name, mode, uid, gid)
+}
+
+// shadowGroup is the gid of the "shadow" group, which owns /etc/shadow on Debian, or root
+func shadowGroup(groups [][]string) int {
+	if g := findColonEntry(groups, "shadow"); g != nil && len(g) >= 3 {
+		id, _ := strconv.Atoi(g[2])
+		return id
+	}
+	return 0
+}
+
+// parseColonFile splits a passwd-style file into fields, dropping blank lines
+func parseColonFile(data []byte) [][]string {
+	var out [][]string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) != "" {
+			out = append(out, strings.Split(line, ":"))
+		}
+	}
+	return out
+}
+
+func joinColonFile(entries [][]string) []byte {
+	var b strings.Builder
+	for _, e := range entries {
+		b.WriteString(strings.Join(e, ":"))
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
+func findColonEntry(entries [][]string, name string) []string {
+	for _, e := range entries {
+		if e[0] == name {
+			return e
+		}
+	}
+	return nil
+}
+
+func findColonId(entries [][]string, field, id int) bool {
+	for _, e := range entries {
+		if len(e) > field && e[field] == strconv.Itoa(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextFreeId returns the first regular id (from start, below nobody's 65534) above those in use
+func nextFreeId(entries [][]string, field, start int) int {
+	next := start
+	for _, e := range entries {
+		if len(e) <= field {
+			continue
+		}
+		if id, err := strconv.Atoi(e[field]); err == nil && id >= next && id < 60000 {
+			next = id + 1
+		}
+	}
+	return next
+}
+
+func appendMember(members, user string) string {
+	for _, m := range strings.Split(members, ",") {
+		if m == user {
+			return members
+		}
+	}
+	if members == "" {
+		return user
+	}
+	return members + "," + user
+}
+
+// setIniValue sets key in [section] of an ini file such as wsl.conf, keeping everything else
+func setIniValue(content, section, key, value string) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+	inSection, sectionEnd := false, -1
+	for i, line := range lines {
+		t := strings.TrimSpace(line)
+		if strings.HasPrefix(t, "[") && strings.HasSuffix(t, "]") {
+			inSection = strings.EqualFold(strings.TrimSpace(t[1:len(t)-1]), section)
+			if inSection {
+				sectionEnd = i + 1
+			}
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if k, _, ok := strings.Cut(t, "="); ok && strings.EqualFold(strings.TrimSpace(k), key) {
+			lines[i] = key + "=" + value
+			return strings.Join(lines, "\n") + "\n"
+		}
+		if t != "" {
+			sectionEnd = i + 1
+		}
+	}
+	if sectionEnd < 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "["+section+"]", key+"="+value)
+	} else {
+		lines = append(lines[:sectionEnd], append([]string{key + "=" + value}, lines[sectionEnd:]...)...)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,283 @@
+package logic
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testRootfsEntries is a small Debian-like root filesystem with an existing user bob
+func testRootfsEntries(prefix string) []layerEntry {
+	entries := []layerEntry{
+		dirEntry("etc/"),
+		regFile("etc/passwd", "root:x:0:0:root:/root:/bin/bash\nbob:x:1000:1000::/home/bob:/bin/sh\nnobody:x:65534:65534:nobody:/nonexistent:/usr/sbin/nologin\n"),
+		regFile("etc/shadow", "root:*:19000:0:99999:7:::\nbob:!:19000:0:99999:7:::\n"),
+		regFile("etc/group", "root:x:0:\nshadow:x:42:\nsudo:x:27:bob\nbob:x:1000:\nnogroup:x:65534:\n"),
+		regFile("etc/gshadow", "root:*::\nsudo:*::bob\n"),
+		regFile("etc/wsl.conf", "[boot]\nsystemd=false\n"),
+		dirEntry("etc/sudoers.d/"),
+		dirEntry("etc/skel/"),
+		regFile("etc/skel/.bashrc", "alias ll='ls -l'\n"),
+		dirEntry("etc/skel/.config/"),
+		regFile("etc/skel/.config/app.conf", "x=1\n"),
+		dirEntry("bin/"),
+		regFile("bin/bash", "ELF"),
+		dirEntry("home/"),
+	}
+	for i := range entries {
+		entries[i].name = prefix + entries[i].name
+	}
+	return entries
+}
+
+type rootfsEntry struct {
+	hdr  tar.Header
+	body string
+}
+
+// customizeTest writes the archive, runs CustomizeRootfs and returns the result by normalized name
+func customizeTest(t *testing.T, archive []byte, setup RootfsSetup) (map[string]rootfsEntry, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "rootfs.tar")
+	if err := os.WriteFile(src, archive, 0644); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "custom.tar")
+	if err := CustomizeRootfs(context.Background(), src, target, setup, nil); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries := make(map[string]rootfsEntry)
+	var names []string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[rootfsName(hdr.Name)] = rootfsEntry{hdr: *hdr, body: string(body)}
+		names = append(names, hdr.Name)
+	}
+	return entries, names
+}
+
+func colonLine(file, name string) string {
+	for _, line := range strings.Split(file, "\n") {
+		if strings.HasPrefix(line, name+":") {
+			return line
+		}
+	}
+	return ""
+}
+
+func TestCustomizeRootfsNewUser(t *testing.T) {
+	for _, prefix := range []string{"", "./"} {
+		for _, compress := range []bool{false, true} {
+			name := "plain"
+			if prefix != "" {
+				name = "dot-slash"
+			}
+			if compress {
+				name += " gzip"
+			}
+			t.Run(name, func(t *testing.T) {
+				archive := testLayer(t, testRootfsEntries(prefix)...)
+				if compress {
+					archive = testGzip(t, archive)
+				}
+				got, names := customizeTest(t, archive, RootfsSetup{User: "alice", Password: "s3cret", Systemd: true})
+
+				for _, n := range names {
+					if !strings.HasPrefix(n, prefix) || (prefix == "" && strings.HasPrefix(n, "./")) {
+						t.Errorf("entry %s does not use the archive's %q prefix", n, prefix)
+					}
+				}
+				// Appended entries come after their parent folders
+				seen := map[string]bool{}
+				for _, n := range names {
+					p := rootfsName(n)
+					if dir := path.Dir(p); dir != "." && !seen[dir] {
+						t.Errorf("%s written before its folder %s", p, dir)
+					}
+					seen[p] = true
+				}
+
+				checkLine := func(file, user, want string) {
+					t.Helper()
+					if got := colonLine(got[file].body, user); got != want {
+						t.Errorf("%s: %s line = %q, want %q", file, user, got, want)
+					}
+				}
+				checkLine("etc/passwd", "alice", "alice:x:1001:1001::/home/alice:/bin/bash")
+				checkLine("etc/passwd", "bob", "bob:x:1000:1000::/home/bob:/bin/sh")
+				checkLine("etc/group", "alice", "alice:x:1001:")
+				checkLine("etc/group", "sudo", "sudo:x:27:bob,alice")
+				checkLine("etc/gshadow", "alice", "alice:!::")
+				checkLine("etc/gshadow", "sudo", "sudo:*::bob,alice")
+				checkLine("etc/shadow", "bob", "bob:!:19000:0:99999:7:::")
+
+				shadow := strings.Split(colonLine(got["etc/shadow"].body, "alice"), ":")
+				if len(shadow) != 9 || !checkCryptHash(shadow[1], "s3cret") {
+					t.Errorf("alice shadow entry = %q", shadow)
+				}
+
+				if e := got["etc/sudoers.d/alice"]; e.body != "alice ALL=(ALL:ALL) ALL\n" || e.hdr.Mode != 0440 {
+					t.Errorf("sudoers drop-in = %q mode %o", e.body, e.hdr.Mode)
+				}
+				if e := got["etc/wsl.conf"]; e.body != "[boot]\nsystemd=true\n\n[user]\ndefault=alice\n" {
+					t.Errorf("wsl.conf = %q", e.body)
+				}
+
+				home := got["home/alice"]
+				if home.hdr.Typeflag != tar.TypeDir || home.hdr.Uid != 1001 || home.hdr.Gid != 1001 || home.hdr.Mode != 0750 {
+					t.Errorf("home folder = %+v", home.hdr)
+				}
+				for file, body := range map[string]string{"home/alice/.bashrc": "alias ll='ls -l'\n", "home/alice/.config/app.conf": "x=1\n"} {
+					e, ok := got[file]
+					if !ok || e.body != body || e.hdr.Uid != 1001 || e.hdr.Gid != 1001 {
+						t.Errorf("%s = %q uid %d (present %v), want a copy of the skeleton owned by alice", file, e.body, e.hdr.Uid, ok)
+					}
+				}
+				if e := got["home/alice/.config"]; e.hdr.Typeflag != tar.TypeDir || e.hdr.Uid != 1001 {
+					t.Errorf("home/alice/.config = %+v", e.hdr)
+				}
+			})
+		}
+	}
+}
+
+func TestCustomizeRootfsExistingUser(t *testing.T) {
+	got, _ := customizeTest(t, testLayer(t, testRootfsEntries("")...), RootfsSetup{User: "bob", Password: "new"})
+
+	if got["etc/passwd"].body != testRootfsEntries("")[1].body {
+		t.Errorf("passwd changed for an existing user:\n%s", got["etc/passwd"].body)
+	}
+	if got["etc/group"].body != testRootfsEntries("")[3].body {
+		t.Errorf("group changed for an existing user:\n%s", got["etc/group"].body)
+	}
+	shadow := strings.Split(colonLine(got["etc/shadow"].body, "bob"), ":")
+	if len(shadow) != 9 || !checkCryptHash(shadow[1], "new") || shadow[2] == "19000" {
+		t.Errorf("bob shadow entry = %q, want a new hash and change date", shadow)
+	}
+	if colonLine(got["etc/shadow"].body, "root") != "root:*:19000:0:99999:7:::" {
+		t.Errorf("root shadow entry changed")
+	}
+	if _, ok := got["etc/sudoers.d/bob"]; ok {
+		t.Errorf("sudoers drop-in written for an existing user")
+	}
+	if _, ok := got["home/bob"]; ok {
+		t.Errorf("home folder created for an existing user")
+	}
+	if e := got["etc/wsl.conf"]; e.body != "[boot]\nsystemd=false\n\n[user]\ndefault=bob\n" {
+		t.Errorf("wsl.conf = %q", e.body)
+	}
+}
+
+func TestCustomizeRootfsMinimal(t *testing.T) {
+	// No shadow, gshadow, sudoers.d, skeleton or bash
+	archive := testLayer(t,
+		dirEntry("etc/"),
+		regFile("etc/passwd", "root:x:0:0:root:/root:/bin/sh\n"),
+		regFile("etc/group", "root:x:0:\nwheel:x:10:root\n"),
+	)
+	got, _ := customizeTest(t, archive, RootfsSetup{User: "alice"})
+
+	if line := colonLine(got["etc/passwd"].body, "alice"); line != "alice:x:1000:1000::/home/alice:/bin/sh" {
+		t.Errorf("passwd line = %q", line)
+	}
+	if line := colonLine(got["etc/group"].body, "wheel"); line != "wheel:x:10:root,alice" {
+		t.Errorf("wheel line = %q", line)
+	}
+	shadow := got["etc/shadow"]
+	if line := colonLine(shadow.body, "alice"); !strings.HasPrefix(line, "alice:!:") {
+		t.Errorf("shadow line = %q, want a locked password", line)
+	}
+	if shadow.hdr.Mode != 0640 || shadow.hdr.Gid != 0 {
+		t.Errorf("new shadow file mode %o gid %d", shadow.hdr.Mode, shadow.hdr.Gid)
+	}
+	for _, name := range []string{"etc/gshadow", "etc/sudoers.d/alice"} {
+		if _, ok := got[name]; ok {
+			t.Errorf("%s written although the distro has no such file", name)
+		}
+	}
+	if e := got["home"]; e.hdr.Typeflag != tar.TypeDir {
+		t.Errorf("missing /home not created")
+	}
+	if e := got["etc/wsl.conf"]; e.body != "[user]\ndefault=alice\n" || e.hdr.Mode != 0644 {
+		t.Errorf("wsl.conf = %q mode %o", e.body, e.hdr.Mode)
+	}
+}
+
+func TestCustomizeRootfsErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	target := filepath.Join(dir, "out.tar")
+
+	xz := write("rootfs.tar.xz", []byte{0xfd, '7', 'z', 'X', 'Z', 0x00, 0, 0})
+	if err := CustomizeRootfs(context.Background(), xz, target, RootfsSetup{User: "alice"}, nil); !errors.Is(err, ErrRootfsFormat) {
+		t.Errorf("xz: err = %v, want ErrRootfsFormat", err)
+	}
+
+	valid := write("rootfs.tar", testLayer(t, testRootfsEntries("")...))
+	if err := CustomizeRootfs(context.Background(), valid, target, RootfsSetup{User: "Bad User"}, nil); err == nil {
+		t.Errorf("invalid user name accepted")
+	}
+
+	noPasswd := write("nopasswd.tar", testLayer(t, dirEntry("etc/"), regFile("etc/hostname", "x\n")))
+	if err := CustomizeRootfs(context.Background(), noPasswd, target, RootfsSetup{User: "alice"}, nil); err == nil || !strings.Contains(err.Error(), "no /etc/passwd") {
+		t.Errorf("err = %v, want a missing /etc/passwd error", err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("partial output left behind")
+	}
+}
+
+func TestSetIniValue(t *testing.T) {
+	tests := []struct {
+		name, content, section, key, value, want string
+	}{
+		{"empty file", "", "boot", "systemd", "true", "[boot]\nsystemd=true\n"},
+		{"new section", "[boot]\nsystemd=true\n", "user", "default", "alice", "[boot]\nsystemd=true\n\n[user]\ndefault=alice\n"},
+		{"replace value", "[user]\ndefault=bob\n", "user", "default", "alice", "[user]\ndefault=alice\n"},
+		{"case-insensitive", "[User]\n Default = bob\n", "user", "default", "alice", "[User]\ndefault=alice\n"},
+		{
+			"add to existing section before the next",
+			"[boot]\nsystemd=false\n\n[network]\nhostname=x\n", "boot", "command", "echo hi",
+			"[boot]\nsystemd=false\ncommand=echo hi\n\n[network]\nhostname=x\n",
+		},
+		{"same key in other section", "[network]\ndefault=1\n", "user", "default", "alice", "[network]\ndefault=1\n\n[user]\ndefault=alice\n"},
+		{"comments kept", "# managed\n[boot]\n# note\nsystemd=false\n", "boot", "systemd", "true", "# managed\n[boot]\n# note\nsystemd=true\n"},
+		{"no trailing newline", "[boot]\nsystemd=false", "user", "default", "alice", "[boot]\nsystemd=false\n\n[user]\ndefault=alice\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := setIniValue(tt.content, tt.section, tt.key, tt.value); got != tt.want {
+				t.Errorf("setIniValue = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
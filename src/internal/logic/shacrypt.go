@@ -0,0 +1,118 @@
+package logic
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"hash"
+)
+
+// cryptAlphabet is the base64 variant used by crypt(3)
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// HashPassword returns a SHA-512 crypt ("$6$") hash for /etc/shadow with a random salt
+func HashPassword(password string) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	salt := make([]byte, len(raw))
+	for i, b := range raw {
+		salt[i] = cryptAlphabet[int(b)%len(cryptAlphabet)]
+	}
+	return sha512Crypt([]byte(password), salt), nil
+}
+
+// sha512Crypt implements the SHA-crypt scheme (Drepper) with the default 5000 rounds
+func sha512Crypt(pw, salt []byte) string {
+	const rounds = 5000
+	if len(salt) > 16 {
+		salt = salt[:16]
+	}
+
+	b := sha512.New()
+	b.Write(pw)
+	b.Write(salt)
+	b.Write(pw)
+	sumB := b.Sum(nil)
+
+	a := sha512.New()
+	a.Write(pw)
+	a.Write(salt)
+	repeatInto(a, sumB, len(pw))
+	for n := len(pw); n > 0; n >>= 1 {
+		if n&1 != 0 {
+			a.Write(sumB)
+		} else {
+			a.Write(pw)
+		}
+	}
+	sumA := a.Sum(nil)
+
+	dp := sha512.New()
+	for i := 0; i < len(pw); i++ {
+		dp.Write(pw)
+	}
+	p := repeatBytes(dp.Sum(nil), len(pw))
+
+	ds := sha512.New()
+	for i := 0; i < 16+int(sumA[0]); i++ {
+		ds.Write(salt)
+	}
+	s := repeatBytes(ds.Sum(nil), len(salt))
+
+	c := sumA
+	for i := 0; i < rounds; i++ {
+		h := sha512.New()
+		if i&1 != 0 {
+			h.Write(p)
+		} else {
+			h.Write(c)
+		}
+		if i%3 != 0 {
+			h.Write(s)
+		}
+		if i%7 != 0 {
+			h.Write(p)
+		}
+		if i&1 != 0 {
+			h.Write(c)
+		} else {
+			h.Write(p)
+		}
+		c = h.Sum(nil)
+	}
+
+	out := []byte("$6$" + string(salt) + "$")
+	for _, g := range [...][3]int{
+		{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4}, {47, 5, 26}, {6, 27, 48},
+		{28, 49, 7}, {50, 8, 29}, {9, 30, 51}, {31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13},
+		{56, 14, 35}, {15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19}, {62, 20, 41},
+	} {
+		out = appendCrypt64(out, uint(c[g[0]])<<16|uint(c[g[1]])<<8|uint(c[g[2]]), 4)
+	}
+	return string(appendCrypt64(out, uint(c[63]), 2))
+}
+
+// repeatInto writes n bytes taken cyclically from block
+func repeatInto(h hash.Hash, block []byte, n int) {
+	for ; n > len(block); n -= len(block) {
+		h.Write(block)
+	}
+	h.Write(block[:n])
+}
+
+func repeatBytes(block []byte, n int) []byte {
+	out := make([]byte, 0, n)
+	for len(out) < n {
+		out = append(out, block[:min(len(block), n-len(out))]...)
+	}
+	return out
+}
+
+func appendCrypt64(out []byte, v uint, n int) []byte {
+	for ; n > 0; n-- {
+		out = append(out, cryptAlphabet[v&0x3f])
+		v >>= 6
+	}
+	return out
+}
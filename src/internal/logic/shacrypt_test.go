@@ -0,0 +1,55 @@
+package logic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSha512Crypt(t *testing.T) {
+	// Reference values from glibc crypt(3) and openssl passwd -6
+	tests := []struct {
+		password, salt, want string
+	}{
+		{"Hello world!", "saltstring", "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"},
+		{"", "ab", "$6$ab$xnh5Qsr2NdbFw1PgdZie7nLON3gv.S.23iQDBqAzkdoXPtDnVSpludXkM5UWybQO3OI7hBj9wHg9Ow6sUWD60/"},
+		// Salts are cut to 16 characters
+		{"x", "toolongsaltstring1234", "$6$toolongsaltstrin$m5UCdGkMg16fnZB/afayhHcFYEQvnTRoKod8GIJKB0rGGl9IQGUPKeGHHw5xPOFVPjhTAcuzioT6ZcDqsMKJ80"},
+		// Passwords longer than one SHA-512 block
+		{strings.Repeat("p", 200), "Zq/8.9", "$6$Zq/8.9$p79gJcSIPlem5uXy4Y4K4Fm5hQPIFaQe6S35urGZDexm67TyibbNyl6lpIyZFysOWrgYRNdwJF9Ek/RTuiPRI/"},
+		{"pässwörd", "saltstring", "$6$saltstring$6PSVl254uv0cWCoUS0qzSX5NenRA/YFCwPzGA9ONu.MmmxqXTWHerEzD8WyuBl3ukfIZZU9uxLD6Bn6p7S3rG."},
+	}
+	for _, tt := range tests {
+		if got := sha512Crypt([]byte(tt.password), []byte(tt.salt)); got != tt.want {
+			t.Errorf("sha512Crypt(%q, %q) = %s, want %s", tt.password, tt.salt, got, tt.want)
+		}
+	}
+}
+
+// checkCryptHash reports whether hash is the SHA-512 crypt of password
+func checkCryptHash(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	return len(parts) == 4 && parts[1] == "6" && sha512Crypt([]byte(password), []byte(parts[2])) == hash
+}
+
+func TestHashPassword(t *testing.T) {
+	h1, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h1 == h2 {
+		t.Errorf("two hashes share a salt: %s", h1)
+	}
+	for _, h := range []string{h1, h2} {
+		salt := strings.Split(h, "$")[2]
+		if len(salt) != 16 || strings.Trim(salt, cryptAlphabet) != "" {
+			t.Errorf("salt %q is not 16 crypt characters", salt)
+		}
+		if !checkCryptHash(h, "secret") || checkCryptHash(h, "Secret") {
+			t.Errorf("%s does not verify", h)
+		}
+	}
+}
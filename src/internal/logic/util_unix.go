@@ -3,9 +3,26 @@
 package logic
 
 import (
+	"os"
 	"os/exec"
+	"syscall"
+	"time"
 )
 
 func prepareCmd(cmd *exec.Cmd) {
-	// No-op for non-Windows systems
+	// Commands created with a context get their own process group so that
+	// cancelling kills the whole tree, not just the direct child.
+	if cmd.Cancel != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		cmd.Cancel = func() error {
+			return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+		cmd.WaitDelay = 10 * time.Second
+	}
+}
+
+// isSparseFile has no equivalent of the NTFS sparse attribute; it only checks the file exists
+func isSparseFile(path string) (bool, error) {
+	_, err := os.Stat(path)
+	return false, err
 }
@@ -3,10 +3,43 @@
 package logic
 
 import (
+	"os"
 	"os/exec"
+	"strconv"
 	"syscall"
+	"time"
 )
 
 func prepareCmd(cmd *exec.Cmd) {
 	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+
+	// Commands created with a context: on cancellation kill the whole process tree
+	// (powershell.exe -> wsl.exe -> ...), not just powershell.exe itself.
+	if cmd.Cancel != nil {
+		cmd.Cancel = func() error {
+			return killProcessTree(cmd.Process.Pid)
+		}
+		cmd.WaitDelay = 10 * time.Second
+	}
+}
+
+func killProcessTree(pid int) error {
+	kill := exec.Command("taskkill.exe", "/T", "/F", "/PID", strconv.Itoa(pid))
+	kill.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	return kill.Run()
+}
+
+const fileAttributeSparseFile = 0x200
+
+// isSparseFile reports whether NTFS marks the file as sparse
+func isSparseFile(path string) (bool, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	attrs, ok := fi.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return false, nil
+	}
+	return attrs.FileAttributes&fileAttributeSparseFile != 0, nil
 }
@@ -0,0 +1,271 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WslFeatures describes what the installed wsl.exe supports
+type WslFeatures struct {
+	// Version is the Store WSL version ("2.3.26.0"), empty for the inbox version
+	Version string
+	// SetSparse is true when `wsl --manage <name> --set-sparse` is available
+	SetSparse bool
+	// Resize is true when `wsl --manage <name> --resize` is available
+	Resize bool
+	// AllowUnsafe is true when enabling sparse mode requires --allow-unsafe
+	AllowUnsafe bool
+}
+
+var (
+	wslFeaturesMu     sync.Mutex
+	wslFeaturesCached *WslFeatures
+)
+
+var wslVersionPattern = regexp.MustCompile(`\d+\.\d+\.\d+(\.\d+)?`)
+
+// DetectWslFeatures checks `wsl --help` for the --manage flags. The result is cached
+// for the life of the process since WSL updates need a restart of the app anyway.
+func DetectWslFeatures(ctx context.Context) (WslFeatures, error) {
+	wslFeaturesMu.Lock()
+	defer wslFeaturesMu.Unlock()
+	if wslFeaturesCached != nil {
+		return *wslFeaturesCached, nil
+	}
+
+	// Both commands may exit non-zero on older versions but still print what we need
+	help, helpErr := runWsl(ctx, nil, "--help")
+	if help == "" && helpErr != nil {
+		return WslFeatures{}, helpErr
+	}
+	var f WslFeatures
+	f.SetSparse = strings.Contains(help, "--set-sparse")
+	f.Resize = strings.Contains(help, "--resize")
+	f.AllowUnsafe = strings.Contains(help, "--allow-unsafe")
+
+	if version, _ := runWsl(ctx, nil, "--version"); version != "" {
+		firstLine, _, _ := strings.Cut(version, "\n")
+		f.Version = wslVersionPattern.FindString(firstLine)
+	}
+
+	wslFeaturesCached = &f
+	return f, nil
+}
+
+// VhdInfo is the sparse flag and maximum size of an instance's virtual disk
+type VhdInfo struct {
+	Sparse   bool
+	MaxBytes uint64
+}
+
+// GetVhdInfo reads the sparse attribute and the virtual size from the VHDX header.
+// The header is only parsed again when the file's size or modification time changed.
+func GetVhdInfo(basePath string) (VhdInfo, error) {
+	path := filepath.Join(basePath, "ext4.vhdx")
+	var info VhdInfo
+	sparse, err := isSparseFile(path)
+	if err != nil {
+		return info, err
+	}
+	info.Sparse = sparse
+	info.MaxBytes, err = cachedVhdxVirtualSize(path)
+	return info, err
+}
+
+// vhdSizeCache holds parsed virtual sizes by path, so home list refreshes don't
+// open every VHDX
+var vhdSizeCache = struct {
+	sync.Mutex
+	m map[string]vhdSizeEntry
+}{m: make(map[string]vhdSizeEntry)}
+
+type vhdSizeEntry struct {
+	modTime  time.Time
+	fileSize int64
+	maxBytes uint64
+}
+
+func cachedVhdxVirtualSize(path string) (uint64, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	vhdSizeCache.Lock()
+	e, ok := vhdSizeCache.m[path]
+	vhdSizeCache.Unlock()
+	if ok && e.modTime.Equal(fi.ModTime()) && e.fileSize == fi.Size() {
+		return e.maxBytes, nil
+	}
+
+	maxBytes, err := ReadVhdxVirtualSize(path)
+	if err != nil {
+		return 0, err
+	}
+	vhdSizeCache.Lock()
+	vhdSizeCache.m[path] = vhdSizeEntry{modTime: fi.ModTime(), fileSize: fi.Size(), maxBytes: maxBytes}
+	vhdSizeCache.Unlock()
+	return maxBytes, nil
+}
+
+// VHDX layout constants (MS-VHDX 2.2 - 2.6)
+const (
+	vhdxRegionTableOffset = 192 * 1024
+	vhdxRegionEntrySize   = 32
+	vhdxMetaEntrySize     = 32
+	vhdxMetaHeaderSize    = 32
+)
+
+var (
+	vhdxMetadataRegionGUID = mustGUID("8B7CA206-4790-4B9A-B8FE-575F050F886E")
+	vhdxVirtualDiskSizeID  = mustGUID("2FA54224-CD1B-4876-B211-5DBED83BF4B8")
+)
+
+// ReadVhdxVirtualSize returns the maximum size of a VHDX from its metadata region
+func ReadVhdxVirtualSize(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	sig := make([]byte, 8)
+	if _, err := f.ReadAt(sig, 0); err != nil || string(sig) != "vhdxfile" {
+		return 0, fmt.Errorf("%s is not a VHDX file", path)
+	}
+
+	// Region table: "regi", checksum, entry count, reserved, then entries
+	head := make([]byte, 16)
+	if _, err := f.ReadAt(head, vhdxRegionTableOffset); err != nil {
+		return 0, err
+	}
+	if string(head[:4]) != "regi" {
+		return 0, errors.New("VHDX region table not found")
+	}
+	count := binary.LittleEndian.Uint32(head[8:12])
+	if count > 2047 {
+		return 0, errors.New("VHDX region table is corrupt")
+	}
+	entries := make([]byte, int(count)*vhdxRegionEntrySize)
+	if _, err := f.ReadAt(entries, vhdxRegionTableOffset+16); err != nil {
+		return 0, err
+	}
+	var metaOffset int64 = -1
+	for i := 0; i < int(count); i++ {
+		e := entries[i*vhdxRegionEntrySize:]
+		if bytes.Equal(e[:16], vhdxMetadataRegionGUID) {
+			metaOffset = int64(binary.LittleEndian.Uint64(e[16:24]))
+			break
+		}
+	}
+	if metaOffset < 0 {
+		return 0, errors.New("VHDX metadata region not found")
+	}
+
+	// Metadata table: "metadata", reserved, entry count, reserved, then entries
+	mhead := make([]byte, vhdxMetaHeaderSize)
+	if _, err := f.ReadAt(mhead, metaOffset); err != nil {
+		return 0, err
+	}
+	if string(mhead[:8]) != "metadata" {
+		return 0, errors.New("VHDX metadata table not found")
+	}
+	mcount := binary.LittleEndian.Uint16(mhead[10:12])
+	mentries := make([]byte, int(mcount)*vhdxMetaEntrySize)
+	if _, err := f.ReadAt(mentries, metaOffset+vhdxMetaHeaderSize); err != nil {
+		return 0, err
+	}
+	for i := 0; i < int(mcount); i++ {
+		e := mentries[i*vhdxMetaEntrySize:]
+		if !bytes.Equal(e[:16], vhdxVirtualDiskSizeID) {
+			continue
+		}
+		itemOffset := int64(binary.LittleEndian.Uint32(e[16:20]))
+		val := make([]byte, 8)
+		if _, err := f.ReadAt(val, metaOffset+itemOffset); err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		return binary.LittleEndian.Uint64(val), nil
+	}
+	return 0, errors.New("VHDX virtual disk size not found")
+}
+
+// mustGUID converts a textual GUID to its on-disk (mixed-endian) form
+func mustGUID(s string) []byte {
+	raw, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(raw) != 16 {
+		panic("invalid GUID " + s)
+	}
+	out := make([]byte, 16)
+	// Data1 (4 bytes), Data2 and Data3 (2 bytes each) are little-endian; Data4 as-is
+	out[0], out[1], out[2], out[3] = raw[3], raw[2], raw[1], raw[0]
+	out[4], out[5] = raw[5], raw[4]
+	out[6], out[7] = raw[7], raw[6]
+	copy(out[8:], raw[8:])
+	return out
+}
+
+// SetDistroSparse turns sparse mode of the instance's VHDX on or off.
+// allowUnsafe passes --allow-unsafe, which newer WSL versions require to enable sparse mode.
+func SetDistroSparse(ctx context.Context, projectRoot, name string, sparse, allowUnsafe bool, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpSetSparse, name, map[string]string{"Sparse": strconv.FormatBool(sparse)})(&err)
+
+	features, err := DetectWslFeatures(ctx)
+	if err != nil {
+		return err
+	}
+	if !features.SetSparse {
+		return fmt.Errorf("this WSL version (%s) does not support --set-sparse; update WSL with 'wsl --update'", versionOrInbox(features.Version))
+	}
+
+	if _, err = runWsl(ctx, onOutput, "--terminate", name); err != nil {
+		return err
+	}
+	args := []string{"--manage", name, "--set-sparse", strconv.FormatBool(sparse)}
+	if sparse && allowUnsafe && features.AllowUnsafe {
+		args = append(args, "--allow-unsafe")
+	}
+	_, err = runWsl(ctx, onOutput, args...)
+	return err
+}
+
+// ResizeDistroDisk changes the maximum size of the instance's virtual disk
+func ResizeDistroDisk(ctx context.Context, projectRoot, name string, sizeGB int, onOutput func(string)) (err error) {
+	size := fmt.Sprintf("%dGB", sizeGB)
+	defer trackOperation(projectRoot, OpResize, name, map[string]string{"Size": size})(&err)
+
+	if sizeGB <= 0 {
+		return fmt.Errorf("size must be a positive number of GB")
+	}
+	features, err := DetectWslFeatures(ctx)
+	if err != nil {
+		return err
+	}
+	if !features.Resize {
+		return fmt.Errorf("this WSL version (%s) does not support --resize; update WSL with 'wsl --update'", versionOrInbox(features.Version))
+	}
+
+	if _, err = runWsl(ctx, onOutput, "--terminate", name); err != nil {
+		return err
+	}
+	_, err = runWsl(ctx, onOutput, "--manage", name, "--resize", size)
+	return err
+}
+
+func versionOrInbox(v string) string {
+	if v == "" {
+		return "inbox"
+	}
+	return v
+}
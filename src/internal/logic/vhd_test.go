@@ -0,0 +1,148 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testMetaOffset = 256 * 1024
+	testItemOffset = 64 * 1024
+)
+
+// vhdxParts controls which structures writeTestVhdx leaves intact
+type vhdxParts struct {
+	signature, regionTable, metadataRegion, metadataTable, sizeItem bool
+}
+
+var fullVhdx = vhdxParts{true, true, true, true, true}
+
+// writeTestVhdx hand-builds the parts of a VHDX that ReadVhdxVirtualSize reads:
+// file signature, region table, metadata table and the virtual disk size item
+func writeTestVhdx(t *testing.T, path string, size uint64, p vhdxParts) {
+	t.Helper()
+	img := make([]byte, testMetaOffset+testItemOffset+8)
+	if p.signature {
+		copy(img, "vhdxfile")
+	}
+
+	// Region table with a BAT entry first, so the metadata entry has to be searched for
+	rt := img[vhdxRegionTableOffset:]
+	if p.regionTable {
+		copy(rt, "regi")
+	}
+	binary.LittleEndian.PutUint32(rt[8:], 2)
+	bat := rt[16:]
+	copy(bat, mustGUID("2DC27766-F623-4200-9D64-115E9BFD4A08"))
+	binary.LittleEndian.PutUint64(bat[16:], 1024*1024)
+	meta := rt[16+vhdxRegionEntrySize:]
+	if p.metadataRegion {
+		copy(meta, vhdxMetadataRegionGUID)
+	}
+	binary.LittleEndian.PutUint64(meta[16:], testMetaOffset)
+
+	// Metadata table: block size item, then the virtual disk size
+	mt := img[testMetaOffset:]
+	if p.metadataTable {
+		copy(mt, "metadata")
+	}
+	binary.LittleEndian.PutUint16(mt[10:], 2)
+	block := mt[vhdxMetaHeaderSize:]
+	copy(block, mustGUID("CAA16737-FA36-4D43-B3B6-33F0AA44E76B"))
+	binary.LittleEndian.PutUint32(block[16:], testItemOffset-8)
+	sizeEntry := mt[vhdxMetaHeaderSize+vhdxMetaEntrySize:]
+	if p.sizeItem {
+		copy(sizeEntry, vhdxVirtualDiskSizeID)
+	}
+	binary.LittleEndian.PutUint32(sizeEntry[16:], testItemOffset)
+	binary.LittleEndian.PutUint64(mt[testItemOffset:], size)
+
+	if err := os.WriteFile(path, img, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadVhdxVirtualSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		parts   vhdxParts
+		wantErr string
+	}{
+		{"valid", fullVhdx, ""},
+		{"no signature", vhdxParts{false, true, true, true, true}, "not a VHDX file"},
+		{"no region table", vhdxParts{true, false, true, true, true}, "region table not found"},
+		{"no metadata region", vhdxParts{true, true, false, true, true}, "metadata region not found"},
+		{"no metadata table", vhdxParts{true, true, true, false, true}, "metadata table not found"},
+		{"no size item", vhdxParts{true, true, true, true, false}, "virtual disk size not found"},
+	}
+	const size = 1 << 40
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ext4.vhdx")
+			writeTestVhdx(t, path, size, tt.parts)
+			got, err := ReadVhdxVirtualSize(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != size {
+				t.Fatalf("got %d, %v; want %d", got, err, uint64(size))
+			}
+		})
+	}
+}
+
+func TestReadVhdxVirtualSizeTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ext4.vhdx")
+	if err := os.WriteFile(path, []byte("vhdxfile"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadVhdxVirtualSize(path); err == nil {
+		t.Fatal("want an error for a truncated header")
+	}
+}
+
+func TestGetVhdInfoCachesBySizeAndTime(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ext4.vhdx")
+	writeTestVhdx(t, path, 100, fullVhdx)
+	stamp := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, stamp, stamp); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := GetVhdInfo(dir); err != nil || info.MaxBytes != 100 {
+		t.Fatalf("got %+v, %v", info, err)
+	}
+
+	// Same size and time: the cached value is used without parsing
+	writeTestVhdx(t, path, 200, fullVhdx)
+	if err := os.Chtimes(path, stamp, stamp); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := GetVhdInfo(dir); info.MaxBytes != 100 {
+		t.Fatalf("header parsed again although the file looks unchanged: %d", info.MaxBytes)
+	}
+
+	// A newer modification time (as after a resize) invalidates the entry
+	if err := os.Chtimes(path, stamp, stamp.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := GetVhdInfo(dir); info.MaxBytes != 200 {
+		t.Fatalf("stale cached size %d", info.MaxBytes)
+	}
+}
+
+func TestMustGUID(t *testing.T) {
+	got := mustGUID("8B7CA206-4790-4B9A-B8FE-575F050F886E")
+	want := []byte{0x06, 0xA2, 0x7C, 0x8B, 0x90, 0x47, 0x9A, 0x4B, 0xB8, 0xFE, 0x57, 0x5F, 0x05, 0x0F, 0x88, 0x6E}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got % X", got)
+	}
+}
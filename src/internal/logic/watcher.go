@@ -0,0 +1,247 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+)
+
+// DefaultStatePollInterval is used when settings don't specify one
+const DefaultStatePollInterval = 5 * time.Second
+
+// Instance states as reported by wsl --list --verbose
+const (
+	StateRunning = "Running"
+	StateStopped = "Stopped"
+)
+
+// InstanceStateEvent reports that an instance changed between running and stopped
+type InstanceStateEvent struct {
+	Name     string
+	State    string
+	Previous string
+}
+
+// ListRunningDistros returns the names of running instances using the cheap
+// `wsl --list --running --quiet` query (no registry or cache enrichment)
+func ListRunningDistros(ctx context.Context) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "wsl.exe", "--list", "--running", "--quiet")
+	// Ask for UTF-8; older WSL versions ignore this and emit UTF-16LE (handled below)
+	cmd.Env = append(os.Environ(), "WSL_UTF8=1")
+	prepareCmd(cmd)
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// wsl exits non-zero with "There are no running distributions."
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var names []string
+	for _, line := range strings.Split(decodeWslOutput(out), "\n") {
+		name := strings.TrimSpace(line)
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// decodeWslOutput converts wsl.exe output (UTF-16LE unless WSL_UTF8 is honoured) to a string
+func decodeWslOutput(b []byte) string {
+	b = []byte(strings.TrimPrefix(string(b), "\xff\xfe"))
+	if len(b) >= 2 && len(b)%2 == 0 && strings.Count(string(b), "\x00") >= len(b)/4 {
+		u := make([]uint16, len(b)/2)
+		for i := range u {
+			u[i] = uint16(b[2*i]) | uint16(b[2*i+1])<<8
+		}
+		return strings.ReplaceAll(string(utf16.Decode(u)), "\r", "")
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(string(b), "\x00", ""), "\r", "")
+}
+
+// StateWatcher polls the running instance list and publishes changes
+type StateWatcher struct {
+	mu       sync.Mutex
+	interval time.Duration
+	states   map[string]string
+	seeded   bool
+	// registered holds the names from the last Seed; other names are only tracked while running
+	registered map[string]bool
+	subs       map[int]func(InstanceStateEvent)
+	nextSub    int
+	wake       chan struct{}
+
+	// list is swappable so the watcher can be driven without wsl.exe
+	list func(ctx context.Context) ([]string, error)
+}
+
+// NewStateWatcher creates a watcher polling at interval (DefaultStatePollInterval if <= 0)
+func NewStateWatcher(interval time.Duration) *StateWatcher {
+	if interval <= 0 {
+		interval = DefaultStatePollInterval
+	}
+	return &StateWatcher{
+		interval: interval,
+		states:   make(map[string]string),
+		subs:     make(map[int]func(InstanceStateEvent)),
+		wake:     make(chan struct{}, 1),
+		list:     ListRunningDistros,
+	}
+}
+
+// SetInterval changes the poll interval; it takes effect after the current wait
+func (w *StateWatcher) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultStatePollInterval
+	}
+	w.mu.Lock()
+	w.interval = interval
+	w.mu.Unlock()
+	w.Trigger()
+}
+
+// Seed records the states from a full ListDistros so later polls only report real changes.
+// It is the authoritative registration list: instances missing from it (unregistered or
+// renamed since the last refresh) stop being tracked.
+func (w *StateWatcher) Seed(instances []WslInstance) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.states = make(map[string]string, len(instances))
+	w.registered = make(map[string]bool, len(instances))
+	for _, inst := range instances {
+		w.states[inst.Name] = normalizeState(inst.State)
+		w.registered[inst.Name] = true
+	}
+	w.seeded = true
+}
+
+// State returns the last known state of an instance ("" if unknown)
+func (w *StateWatcher) State(name string) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.states[name]
+}
+
+// Subscribe registers fn for state change events, delivered on the watcher goroutine
+func (w *StateWatcher) Subscribe(fn func(InstanceStateEvent)) (unsubscribe func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	id := w.nextSub
+	w.nextSub++
+	w.subs[id] = fn
+	return func() {
+		w.mu.Lock()
+		delete(w.subs, id)
+		w.mu.Unlock()
+	}
+}
+
+// Trigger asks the running watcher to poll now instead of waiting for the next tick
+func (w *StateWatcher) Trigger() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run polls until ctx is cancelled
+func (w *StateWatcher) Run(ctx context.Context) {
+	for {
+		if err := w.Poll(ctx); err != nil && ctx.Err() == nil {
+			slog.Debug("state poll failed", "error", err)
+		}
+
+		w.mu.Lock()
+		interval := w.interval
+		w.mu.Unlock()
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-w.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// Poll queries the running list once and publishes differences from the known states.
+// Instances never seen before are reported with an empty Previous state.
+// Instances outside the last Seed are forgotten once they stop.
+func (w *StateWatcher) Poll(ctx context.Context) error {
+	running, err := w.list(ctx)
+	if err != nil {
+		return err
+	}
+	isRunning := make(map[string]bool, len(running))
+	for _, name := range running {
+		isRunning[name] = true
+	}
+
+	w.mu.Lock()
+	var events []InstanceStateEvent
+	if !w.seeded {
+		// First poll without a seed only establishes the baseline
+		for name := range isRunning {
+			w.states[name] = StateRunning
+		}
+		w.seeded = true
+	} else {
+		for name, prev := range w.states {
+			next := StateStopped
+			if isRunning[name] {
+				next = StateRunning
+			}
+			if next == StateStopped && !w.registered[name] {
+				delete(w.states, name)
+			} else {
+				w.states[name] = next
+			}
+			if next != prev {
+				events = append(events, InstanceStateEvent{Name: name, State: next, Previous: prev})
+			}
+		}
+		for name := range isRunning {
+			if _, known := w.states[name]; !known {
+				w.states[name] = StateRunning
+				events = append(events, InstanceStateEvent{Name: name, State: StateRunning})
+			}
+		}
+	}
+	subs := make([]func(InstanceStateEvent), 0, len(w.subs))
+	for _, fn := range w.subs {
+		subs = append(subs, fn)
+	}
+	w.mu.Unlock()
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Name < events[j].Name })
+	for _, ev := range events {
+		slog.Debug("instance state changed", "name", ev.Name, "state", ev.State, "previous", ev.Previous)
+		for _, fn := range subs {
+			fn(ev)
+		}
+	}
+	return nil
+}
+
+// normalizeState folds the states reported by wsl (Running, Stopped, Installing, ...)
+// into the two the watcher can observe
+func normalizeState(s string) string {
+	if strings.EqualFold(s, StateRunning) {
+		return StateRunning
+	}
+	return StateStopped
+}
@@ -0,0 +1,209 @@
+package logic
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRunning stands in for wsl --list --running
+type fakeRunning struct {
+	mu    sync.Mutex
+	names []string
+	calls int
+}
+
+func (f *fakeRunning) set(names ...string) {
+	f.mu.Lock()
+	f.names = names
+	f.mu.Unlock()
+}
+
+func (f *fakeRunning) list(ctx context.Context) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return append([]string(nil), f.names...), nil
+}
+
+func (f *fakeRunning) polls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func newFakeWatcher(interval time.Duration) (*StateWatcher, *fakeRunning) {
+	fake := &fakeRunning{}
+	w := NewStateWatcher(interval)
+	w.list = fake.list
+	return w, fake
+}
+
+func TestWatcherSeedAndPoll(t *testing.T) {
+	w, fake := newFakeWatcher(time.Hour)
+	var events []InstanceStateEvent
+	unsubscribe := w.Subscribe(func(ev InstanceStateEvent) { events = append(events, ev) })
+
+	w.Seed([]WslInstance{{Name: "Ubuntu", State: "Running"}, {Name: "Debian", State: "Stopped"}, {Name: "Arch", State: "Installing"}})
+	if got := w.State("Arch"); got != StateStopped {
+		t.Fatalf("Seed: Arch state %q, want Stopped", got)
+	}
+
+	// Nothing changed
+	fake.set("Ubuntu")
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("unexpected events %v", events)
+	}
+
+	// Ubuntu stopped, Debian started, Alpine is new
+	fake.set("Debian", "Alpine")
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []InstanceStateEvent{
+		{Name: "Alpine", State: StateRunning},
+		{Name: "Debian", State: StateRunning, Previous: StateStopped},
+		{Name: "Ubuntu", State: StateStopped, Previous: StateRunning},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("events %v, want %v", events, want)
+	}
+
+	unsubscribe()
+	events = nil
+	fake.set()
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("unsubscribed callback still called: %v", events)
+	}
+	if got := w.State("Debian"); got != StateStopped {
+		t.Fatalf("Debian state %q after poll, want Stopped", got)
+	}
+}
+
+func TestWatcherFirstPollIsBaseline(t *testing.T) {
+	w, fake := newFakeWatcher(time.Hour)
+	called := false
+	w.Subscribe(func(InstanceStateEvent) { called = true })
+
+	fake.set("Ubuntu")
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if called {
+		t.Fatal("first poll without a seed must not publish events")
+	}
+	if got := w.State("Ubuntu"); got != StateRunning {
+		t.Fatalf("Ubuntu state %q, want Running", got)
+	}
+}
+
+func TestWatcherReconcilesRegistrations(t *testing.T) {
+	w, fake := newFakeWatcher(time.Hour)
+	var events []InstanceStateEvent
+	w.Subscribe(func(ev InstanceStateEvent) { events = append(events, ev) })
+	poll := func(running ...string) []InstanceStateEvent {
+		t.Helper()
+		events = nil
+		fake.set(running...)
+		if err := w.Poll(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return events
+	}
+
+	w.Seed([]WslInstance{{Name: "Ubuntu", State: "Running"}, {Name: "Debian", State: "Stopped"}})
+
+	// Ubuntu renamed to Noble while running: the new name shows up before the next refresh
+	got := poll("Noble")
+	want := []InstanceStateEvent{
+		{Name: "Noble", State: StateRunning},
+		{Name: "Ubuntu", State: StateStopped, Previous: StateRunning},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rename events %v, want %v", got, want)
+	}
+
+	// The refresh after the rename no longer lists Ubuntu, and Debian was unregistered
+	w.Seed([]WslInstance{{Name: "Noble", State: "Running"}})
+	for _, name := range []string{"Ubuntu", "Debian"} {
+		if s := w.State(name); s != "" {
+			t.Fatalf("%s still tracked as %q after Seed", name, s)
+		}
+	}
+	if got := poll("Noble"); len(got) != 0 {
+		t.Fatalf("unexpected events after Seed %v", got)
+	}
+
+	// An instance outside the registration list is tracked while running and dropped once stopped
+	if got := poll("Noble", "Alpine"); len(got) != 1 || got[0].Name != "Alpine" {
+		t.Fatalf("unregistered start events %v", got)
+	}
+	want = []InstanceStateEvent{{Name: "Alpine", State: StateStopped, Previous: StateRunning}}
+	if got := poll("Noble"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unregistered stop events %v, want %v", got, want)
+	}
+	if s := w.State("Alpine"); s != "" {
+		t.Fatalf("Alpine still tracked as %q after stopping", s)
+	}
+	if got := poll("Noble"); len(got) != 0 {
+		t.Fatalf("dropped instance reported again %v", got)
+	}
+
+	// Registered instances stay tracked when they stop
+	if got := poll(); len(got) != 1 || w.State("Noble") != StateStopped {
+		t.Fatalf("Noble stop events %v, state %q", got, w.State("Noble"))
+	}
+}
+
+func TestWatcherTriggerPollsNow(t *testing.T) {
+	// The interval is far longer than the test, so only Trigger can cause the second poll
+	w, fake := newFakeWatcher(time.Hour)
+	w.Seed([]WslInstance{{Name: "Ubuntu", State: "Stopped"}})
+	got := make(chan InstanceStateEvent, 1)
+	w.Subscribe(func(ev InstanceStateEvent) { got <- ev })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Let the initial poll run, then start the instance and wake the watcher
+	for fake.polls() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	fake.set("Ubuntu")
+	w.Trigger()
+
+	select {
+	case ev := <-got:
+		if ev.Name != "Ubuntu" || ev.State != StateRunning || ev.Previous != StateStopped {
+			t.Fatalf("unexpected event %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Trigger did not cause a poll")
+	}
+}
+
+func TestDecodeWslOutput(t *testing.T) {
+	utf16 := []byte("\xff\xfeU\x00b\x00\r\x00\n\x00")
+	if got := decodeWslOutput(utf16); got != "Ub\n" {
+		t.Fatalf("UTF-16LE: got %q", got)
+	}
+	if got := decodeWslOutput([]byte("Ubuntu\r\nDebian\r\n")); got != "Ubuntu\nDebian\n" {
+		t.Fatalf("UTF-8: got %q", got)
+	}
+}
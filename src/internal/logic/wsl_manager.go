@@ -4,14 +4,19 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 )
 
 type WslInstance struct {
@@ -23,6 +28,13 @@ type WslInstance struct {
 	User        string `json:"User,omitempty"`
 	InstallTime string `json:"InstallTime,omitempty"`
 	DiskSize    string `json:"DiskSize,omitempty"`
+	// Maintained by the user, see SetInstanceMeta
+	Tags  []string `json:"Tags,omitempty"`
+	Note  string   `json:"Note,omitempty"`
+	Owner string   `json:"Owner,omitempty"`
+	// Filled from the VHDX itself, not by the list script
+	Sparse     bool   `json:"Sparse,omitempty"`
+	VhdMaxSize uint64 `json:"VhdMaxSize,omitempty"`
 }
 
 // Check if a directory is empty (or doesn't exist which is also 'clean' for us)
@@ -74,7 +86,8 @@ func IsDistroRegistered(projectRoot string, name string) (bool, error) {
 }
 
 // RunPowerShellScript runs a script located in /scripts with the given arguments
-// It streams output to onOutput if provided, otherwise returns nil on success
+// It streams output to onOutput if provided, otherwise returns nil on success.
+// Progress lines (see ProgressPrefix) are reported through ReportProgress instead of onOutput.
 func RunPowerShellScript(ctx context.Context, projectRoot string, scriptName string, args []string, onOutput func(string)) error {
 	scriptPath := filepath.Join(projectRoot, "scripts", scriptName)
 
@@ -86,12 +99,18 @@ func RunPowerShellScript(ctx context.Context, projectRoot string, scriptName str
 
 	cmd := exec.CommandContext(ctx, "powershell.exe", fullArgs...)
 	prepareCmd(cmd)
+	slog.Debug("running script", "script", scriptName, "args", redactArgs(args))
 
 	if onOutput == nil {
 		// Simple run, capture error only
 		output, err := cmd.CombinedOutput()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if err != nil {
-			return fmt.Errorf("script failed: %s (%w)", string(output), err)
+			text := stripProgressLines(string(output))
+			slog.Warn("script failed", "script", scriptName, "error", err, "output", text)
+			return fmt.Errorf("script failed: %s (%w)", text, err)
 		}
 		return nil
 	}
@@ -110,16 +129,21 @@ func RunPowerShellScript(ctx context.Context, projectRoot string, scriptName str
 		return err
 	}
 
-	// Capture output
+	// Capture output; progress lines are routed to the context's progress sink
+	emit := splitProgress(ctx, onOutput)
 	go func() {
 		scanner := bufio.NewScanner(io.MultiReader(stdout, stderr))
 		for scanner.Scan() {
-			onOutput(scanner.Text() + "\n")
+			emit(scanner.Text() + "\n")
 		}
 	}()
 
 	err = cmd.Wait()
 	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		slog.Warn("script failed", "script", scriptName, "error", err)
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
 				return fmt.Errorf("script exited with code %d", status.ExitStatus())
@@ -145,6 +169,7 @@ func ListDistros(projectRoot string, forceUpdate bool) ([]WslInstance, error) {
 
 	output, err := cmd.Output()
 	if err != nil {
+		slog.Warn("list script failed", "error", err)
 		return nil, fmt.Errorf("failed to execute list script: %w", err)
 	}
 
@@ -179,13 +204,19 @@ func ListDistros(projectRoot string, forceUpdate bool) ([]WslInstance, error) {
 		if distros[i].State == "Running" && distros[i].Release == "" {
 			distros[i].Release = GetDistroReleaseInfo(distros[i].Name, distros[i].State)
 		}
+		if info, err := GetVhdInfo(distros[i].BasePath); err == nil {
+			distros[i].Sparse = info.Sparse
+			distros[i].VhdMaxSize = info.MaxBytes
+		}
 	}
 
 	return distros, nil
 }
 
 // UnregisterDistro calls the uninstall script
-func UnregisterDistro(ctx context.Context, projectRoot string, name string, force bool, onOutput func(string)) error {
+func UnregisterDistro(ctx context.Context, projectRoot string, name string, force bool, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpUninstall, name, map[string]string{"Force": strconv.FormatBool(force)})(&err)
+
 	args := []string{"-DistroName", name}
 	if force {
 		args = append(args, "-Force")
@@ -195,7 +226,9 @@ func UnregisterDistro(ctx context.Context, projectRoot string, name string, forc
 }
 
 // DeleteDistroFiles removes the directory
-func DeleteDistroFiles(path string) error {
+func DeleteDistroFiles(projectRoot, path string) (err error) {
+	defer trackOperation(projectRoot, OpDeleteFiles, "", map[string]string{"Path": path})(&err)
+
 	if path == "" {
 		return fmt.Errorf("path is empty")
 	}
@@ -204,37 +237,126 @@ func DeleteDistroFiles(path string) error {
 
 // GetDistroSize calculates the size of the instance's storage
 func GetDistroSize(basePath string) (string, error) {
-	vhdxPath := filepath.Join(basePath, "ext4.vhdx")
-	info, err := os.Stat(vhdxPath)
+	size, err := GetDistroSizeBytes(basePath)
 	if err != nil {
 		return "Unknown", err
 	}
-	size := info.Size()
+	return FormatBytes(size), nil
+}
+
+// GetDistroSizeBytes returns the size of the instance's ext4.vhdx in bytes
+func GetDistroSizeBytes(basePath string) (int64, error) {
+	info, err := os.Stat(filepath.Join(basePath, "ext4.vhdx"))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
 
+// DirSize sums the sizes of all regular files below root
+func DirSize(root string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// FormatBytes renders a size as "12.3 GB"
+func FormatBytes(size int64) string {
 	const unit = 1024
 	if size < unit {
-		return fmt.Sprintf("%d B", size), nil
+		return fmt.Sprintf("%d B", size)
 	}
 	div, exp := int64(unit), 0
 	for n := size / unit; n >= unit; n /= unit {
 		div *= unit
 		exp++
 	}
-	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp]), nil
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
 }
 
 // StopDistro terminates the instance using stop_instance.ps1
-func StopDistro(ctx context.Context, projectRoot, name string, onOutput func(string)) error {
+func StopDistro(ctx context.Context, projectRoot, name string, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpStop, name, nil)(&err)
+
 	return RunPowerShellScript(ctx, projectRoot, "stop_instance.ps1", []string{"-DistroName", name}, onOutput)
 }
 
+// ShutdownWsl stops every running instance and the WSL 2 utility VM (wsl --shutdown)
+func ShutdownWsl(ctx context.Context, projectRoot string, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpShutdown, "", nil)(&err)
+
+	_, err = runWsl(ctx, onOutput, "--shutdown")
+	return err
+}
+
+// updatePackagesScript upgrades the installed packages with whichever package manager the distro has
+const updatePackagesScript = `if command -v apt-get >/dev/null 2>&1; then
+  export DEBIAN_FRONTEND=noninteractive
+  apt-get update && apt-get -y upgrade
+elif command -v dnf >/dev/null 2>&1; then
+  dnf -y upgrade
+elif command -v yum >/dev/null 2>&1; then
+  yum -y update
+elif command -v zypper >/dev/null 2>&1; then
+  zypper --non-interactive refresh && zypper --non-interactive update
+elif command -v pacman >/dev/null 2>&1; then
+  pacman -Syu --noconfirm
+elif command -v apk >/dev/null 2>&1; then
+  apk update && apk upgrade
+else
+  echo "no supported package manager (apt, dnf, yum, zypper, pacman, apk) found" >&2
+  exit 1
+fi`
+
+// UpdateDistroPackages upgrades the packages inside the instance as root. A stopped
+// instance is started for it.
+func UpdateDistroPackages(ctx context.Context, projectRoot, name string, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpUpdatePackages, name, nil)(&err)
+
+	_, err = runWsl(ctx, onOutput, "-d", name, "-u", "root", "--", "sh", "-c", updatePackagesScript)
+	// The package manager's output is already in the log; don't repeat the script in the error
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		err = fmt.Errorf("package update failed (exit code %d)", exitErr.ExitCode())
+	}
+	return err
+}
+
+// DefaultBackupFile returns <projectRoot>/backups/<name>-<timestamp>.tar
+func DefaultBackupFile(projectRoot, name string) string {
+	return filepath.Join(projectRoot, "backups", fmt.Sprintf("%s-%s.tar", name, time.Now().Format("20060102-150405")))
+}
+
+// BackupDistro exports the instance to a tar archive using backup_instance.ps1
+func BackupDistro(ctx context.Context, projectRoot, name, outputFile string, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpBackup, name, map[string]string{"OutputFile": outputFile})(&err)
+
+	return RunPowerShellScript(ctx, projectRoot, "backup_instance.ps1", []string{"-DistroName", name, "-OutputFile", outputFile}, onOutput)
+}
+
 // ScanDistros calls scan_wsl_instances.ps1
-func ScanDistros(ctx context.Context, projectRoot string, onOutput func(string)) error {
+func ScanDistros(ctx context.Context, projectRoot string, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpScan, "", nil)(&err)
+
 	return RunPowerShellScript(ctx, projectRoot, "scan_wsl_instances.ps1", []string{}, onOutput)
 }
 
 // RenameDistro calls rename_instance.ps1
-func RenameDistro(ctx context.Context, projectRoot, oldName, newName, newPath string, onOutput func(string)) error {
+func RenameDistro(ctx context.Context, projectRoot, oldName, newName, newPath string, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpRename, oldName, map[string]string{"NewName": newName, "NewPath": newPath})(&err)
+
 	args := []string{"-OldName", oldName, "-NewName", newName}
 	if newPath != "" {
 		args = append(args, "-NewPath", newPath)
@@ -243,7 +365,9 @@ func RenameDistro(ctx context.Context, projectRoot, oldName, newName, newPath st
 }
 
 // UpdateDistroList runs the update_distros.ps1 script
-func UpdateDistroList(ctx context.Context, projectRoot, sourceUrl string, onOutput func(string)) error {
+func UpdateDistroList(ctx context.Context, projectRoot, sourceUrl string, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpUpdateSources, "", map[string]string{"SourceUrl": sourceUrl})(&err)
+
 	args := []string{}
 	if sourceUrl != "" {
 		args = append(args, "-SourceUrl", sourceUrl)
@@ -252,12 +376,16 @@ func UpdateDistroList(ctx context.Context, projectRoot, sourceUrl string, onOutp
 }
 
 // MoveDistro calls move_instance.ps1
-func MoveDistro(ctx context.Context, projectRoot, name, newBasePath string, onOutput func(string)) error {
+func MoveDistro(ctx context.Context, projectRoot, name, newBasePath string, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpMove, name, map[string]string{"NewPath": newBasePath})(&err)
+
 	return RunPowerShellScript(ctx, projectRoot, "move_instance.ps1", []string{"-DistroName", name, "-NewPath", newBasePath}, onOutput)
 }
 
 // SetUserPassword calls set_credentials.ps1
-func SetDistroCredentials(ctx context.Context, projectRoot, distroName, user, password string, onOutput func(string)) error {
+func SetDistroCredentials(ctx context.Context, projectRoot, distroName, user, password string, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpSetCredentials, distroName, map[string]string{"UserName": user, "Password": password})(&err)
+
 	args := []string{"-DistroName", distroName, "-UserName", user}
 	if password != "" {
 		args = append(args, "-Password", password)
@@ -268,7 +396,9 @@ func SetDistroCredentials(ctx context.Context, projectRoot, distroName, user, pa
 // StartDistro starts the instance using start_instance.ps1
 // if openTerminal is true, it launches a new window.
 // if false, it runs in background.
-func StartDistro(ctx context.Context, projectRoot, name string, openTerminal bool, startPath string) error {
+func StartDistro(ctx context.Context, projectRoot, name string, openTerminal bool, startPath string) (err error) {
+	defer trackOperation(projectRoot, OpStart, name, map[string]string{"OpenTerminal": strconv.FormatBool(openTerminal), "StartPath": startPath})(&err)
+
 	scriptPath := filepath.Join(projectRoot, "scripts", "start_instance.ps1")
 	args := []string{"-NoProfile", "-ExecutionPolicy", "Bypass", "-File", scriptPath, "-DistroName", name}
 
@@ -316,7 +446,21 @@ func GetDistroReleaseInfo(name, state string) string {
 }
 
 // DownloadDistroOnly downloads the distro package without installing it
-func DownloadDistroOnly(ctx context.Context, projectRoot, family, version string, onOutput func(string)) error {
+func DownloadDistroOnly(ctx context.Context, projectRoot, family, version string, onOutput func(string)) (err error) {
+	defer trackOperation(projectRoot, OpDownload, "", map[string]string{"Family": family, "Version": version})(&err)
+
 	args := []string{"-SelectFamily", family, "-SelectVersion", version}
 	return RunPowerShellScript(ctx, projectRoot, "download_all_distros.ps1", args, onOutput)
 }
+
+// redactArgs masks the value following secret-looking switches (e.g. -Password) for logging
+func redactArgs(args []string) []string {
+	out := append([]string(nil), args...)
+	for i := 0; i < len(out)-1; i++ {
+		if strings.HasPrefix(out[i], "-") && isSecretKey(out[i]) {
+			out[i+1] = redacted
+			i++
+		}
+	}
+	return out
+}
@@ -0,0 +1,33 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runWsl runs wsl.exe directly (no PowerShell script) and returns its decoded output.
+// Non-empty output is also passed to onOutput. A failing command's output is included
+// in the returned error, since wsl reports problems there rather than via exit codes.
+func runWsl(ctx context.Context, onOutput func(string), args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "wsl.exe", args...)
+	cmd.Env = append(os.Environ(), "WSL_UTF8=1")
+	prepareCmd(cmd)
+	out, err := cmd.CombinedOutput()
+	text := strings.TrimSpace(decodeWslOutput(out))
+	if text != "" && onOutput != nil {
+		onOutput(text + "\n")
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return text, ctx.Err()
+		}
+		if text != "" {
+			return text, fmt.Errorf("wsl %s: %w: %s", strings.Join(args, " "), err, text)
+		}
+		return text, fmt.Errorf("wsl %s: %w", strings.Join(args, " "), err)
+	}
+	return text, nil
+}
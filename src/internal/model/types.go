@@ -14,6 +14,17 @@ type Version struct {
 	Filename    string `json:"Filename"`
 	Source      string `json:"Source,omitempty"`
 	LocalPath   string `json:"LocalPath,omitempty"`
+	// Sha256 identifies the cached package LocalPath points to; versions with the same URL share it
+	Sha256 string `json:"Sha256,omitempty"`
+}
+
+// NotificationSettings selects which job outcomes raise a desktop notification
+type NotificationSettings struct {
+	OnSuccess bool `json:"OnSuccess"`
+	OnFailure bool `json:"OnFailure"`
+	OnCancel  bool `json:"OnCancel"`
+	// MinDurationSec skips notifications for operations that finished faster than this
+	MinDurationSec int `json:"MinDurationSec"`
 }
 
 // GlobalSettings represents the application settings
@@ -24,8 +35,37 @@ type GlobalSettings struct {
 	DistroSourceUrl    string `json:"DistroSourceUrl,omitempty"`
 	// DefaultTerminalStartPath acts as the starting directory when opening a terminal.
 	// If empty, it defaults to the user's home directory inside the distro ("~").
-	DefaultTerminalStartPath string          `json:"DefaultTerminalStartPath,omitempty"`
-	CustomPackages           []CustomPackage `json:"CustomPackages"`
+	DefaultTerminalStartPath string `json:"DefaultTerminalStartPath,omitempty"`
+	// LogLevel is the minimum level written to the app log (Debug, Info, Warn, Error). Empty means Info.
+	LogLevel string `json:"LogLevel,omitempty"`
+	// StatePollSeconds is how often the running/stopped state of instances is refreshed.
+	// Zero means the default of 5 seconds.
+	StatePollSeconds int `json:"StatePollSeconds,omitempty"`
+	// MinimizeToTray hides the window to the system tray on close instead of quitting
+	MinimizeToTray bool `json:"MinimizeToTray,omitempty"`
+	// ApiEnabled starts the local HTTP/JSON automation API
+	ApiEnabled bool `json:"ApiEnabled,omitempty"`
+	// ApiListen is "host:port" on a loopback address or "unix:<socket path>". Empty means 127.0.0.1:7788.
+	ApiListen string `json:"ApiListen,omitempty"`
+	// MetricsEnabled exposes Prometheus metrics at /metrics on the local API
+	MetricsEnabled bool `json:"MetricsEnabled,omitempty"`
+	// Notifications controls desktop notifications when operations end. Nil means defaults.
+	Notifications *NotificationSettings `json:"Notifications,omitempty"`
+	// CacheLimitGB caps the package cache; least recently used packages are evicted. Zero means no limit.
+	CacheLimitGB int `json:"CacheLimitGB,omitempty"`
+	// Provisioning is written into the root filesystem of new instances before import
+	Provisioning   *ProvisioningSettings `json:"Provisioning,omitempty"`
+	CustomPackages []CustomPackage       `json:"CustomPackages"`
+}
+
+// ProvisioningSettings configure every new instance offline, before its first boot
+type ProvisioningSettings struct {
+	// Systemd enables systemd in /etc/wsl.conf
+	Systemd bool `json:"Systemd,omitempty"`
+	// CACertFiles are PEM certificates added to the instance's trust store
+	CACertFiles []string `json:"CACertFiles,omitempty"`
+	// DotfilesDir is a folder copied into the default user's home
+	DotfilesDir string `json:"DotfilesDir,omitempty"`
 }
 
 // CustomPackage represents a user-defined source
@@ -33,4 +73,7 @@ type CustomPackage struct {
 	Name      string `json:"Name"`
 	Version   string `json:"Version"`
 	PathOrUrl string `json:"PathOrUrl"`
+	// LocalPath and Sha256 point at the cached copy of a URL source, like Version's
+	LocalPath string `json:"LocalPath,omitempty"`
+	Sha256    string `json:"Sha256,omitempty"`
 }
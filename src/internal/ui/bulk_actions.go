@@ -0,0 +1,241 @@
+package ui
+
+import (
+	"context"
+	"distronexus-gui/internal/logic"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// makeHomeBulkBar builds the toolbar of the selection mode. It stays hidden until
+// selection is switched on.
+func (mw *MainWindow) makeHomeBulkBar() fyne.CanvasObject {
+	hv := &mw.home
+	hv.selected = map[string]bool{}
+	hv.checks = map[string][]*widget.Check{}
+	hv.selCount = widget.NewLabel("")
+
+	btnAll := widget.NewButton("Select All", func() {
+		for _, name := range hv.shown {
+			mw.setHomeSelected(name, true)
+		}
+	})
+	btnNone := widget.NewButton("Clear", func() {
+		for name := range hv.selected {
+			mw.setHomeSelected(name, false)
+		}
+	})
+
+	action := func(label string, icon fyne.Resource, fn func(names []string)) *widget.Button {
+		btn := widget.NewButtonWithIcon(label, icon, func() {
+			if names := mw.selectedInstances(); len(names) > 0 {
+				fn(names)
+			}
+		})
+		hv.bulkButtons = append(hv.bulkButtons, btn)
+		return btn
+	}
+	btnStart := action("Start", theme.MediaPlayIcon(), func(names []string) {
+		mw.confirmBulk("Start", names, "", func(ctx context.Context, name string, log func(string)) error {
+			return logic.StartDistro(ctx, mw.ProjectDir, name, false, "")
+		}, mw.Watcher.Trigger)
+	})
+	btnStop := action("Stop", theme.MediaStopIcon(), func(names []string) {
+		mw.confirmBulk("Stop", names, "", func(ctx context.Context, name string, log func(string)) error {
+			return logic.StopDistro(ctx, mw.ProjectDir, name, log)
+		}, mw.Watcher.Trigger)
+	})
+	btnBackup := action("Backup", theme.DocumentSaveIcon(), func(names []string) {
+		note := "Archives are written to " + filepath.Join(mw.ProjectDir, "backups") + "."
+		mw.confirmBulk("Back up", names, note, func(ctx context.Context, name string, log func(string)) error {
+			return logic.BackupDistro(ctx, mw.ProjectDir, name, logic.DefaultBackupFile(mw.ProjectDir, name), log)
+		}, nil)
+	})
+	btnMove := action("Move", theme.StorageIcon(), func(names []string) {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil || uri == nil {
+				return
+			}
+			target := uri.Path()
+			note := fmt.Sprintf("Each instance moves to %s.", filepath.Join(target, "<name>"))
+			mw.confirmBulk("Move", names, note, func(ctx context.Context, name string, log func(string)) error {
+				return logic.MoveDistro(ctx, mw.ProjectDir, name, filepath.Join(target, name), log)
+			}, func() { mw.RefreshHomeList() })
+		}, mw.Window)
+	})
+	btnUpdate := action("Update", theme.DownloadIcon(), func(names []string) {
+		note := "Packages are upgraded as root with the distro's package manager; stopped instances are started."
+		mw.confirmBulk("Update", names, note, func(ctx context.Context, name string, log func(string)) error {
+			return logic.UpdateDistroPackages(ctx, mw.ProjectDir, name, log)
+		}, mw.Watcher.Trigger)
+	})
+	btnUninstall := action("Uninstall", theme.DeleteIcon(), func(names []string) {
+		note := "The instances and their files are permanently deleted."
+		mw.confirmBulk("Uninstall", names, note, func(ctx context.Context, name string, log func(string)) error {
+			return logic.UnregisterDistro(ctx, mw.ProjectDir, name, true, log)
+		}, func() { mw.RefreshHomeList() })
+	})
+	btnUninstall.Importance = widget.DangerImportance
+
+	hv.bulkBar = container.NewHBox(
+		hv.selCount, btnAll, btnNone, layout.NewSpacer(),
+		btnStart, btnStop, btnBackup, btnMove, btnUpdate, btnUninstall,
+	)
+	hv.bulkBar.Hide()
+	mw.updateBulkBar()
+	return hv.bulkBar
+}
+
+// setHomeSelecting switches the checkboxes on the home cards on or off
+func (mw *MainWindow) setHomeSelecting(on bool) {
+	hv := &mw.home
+	hv.selecting = on
+	if on {
+		hv.bulkBar.Show()
+	} else {
+		hv.bulkBar.Hide()
+		hv.selected = map[string]bool{}
+	}
+	mw.renderHomeList()
+}
+
+// homeCardContent is an instance card, with a selection checkbox in selection mode
+func (mw *MainWindow) homeCardContent(d logic.WslInstance) fyne.CanvasObject {
+	item := mw.createDistroItem(d)
+	hv := &mw.home
+	if !hv.selecting {
+		return item
+	}
+	check := widget.NewCheck("", func(on bool) {
+		mw.setHomeSelected(d.Name, on)
+	})
+	check.Checked = hv.selected[d.Name]
+	hv.checks[d.Name] = append(hv.checks[d.Name], check)
+	return container.NewBorder(nil, nil, container.NewCenter(check), nil, item)
+}
+
+// setHomeSelected marks an instance and keeps its checkboxes (one per tag group) in step
+func (mw *MainWindow) setHomeSelected(name string, on bool) {
+	hv := &mw.home
+	if on {
+		hv.selected[name] = true
+	} else {
+		delete(hv.selected, name)
+	}
+	for _, c := range hv.checks[name] {
+		if c.Checked != on {
+			c.SetChecked(on)
+		}
+	}
+	mw.updateBulkBar()
+}
+
+// selectedInstances returns the selected names in display order
+func (mw *MainWindow) selectedInstances() []string {
+	var names []string
+	for _, name := range mw.home.shown {
+		if mw.home.selected[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (mw *MainWindow) updateBulkBar() {
+	hv := &mw.home
+	if hv.selCount == nil {
+		return
+	}
+	// Count what the actions would run on
+	n := len(mw.selectedInstances())
+	hv.selCount.SetText(fmt.Sprintf("%d selected", n))
+	for _, btn := range hv.bulkButtons {
+		if n == 0 {
+			btn.Disable()
+		} else {
+			btn.Enable()
+		}
+	}
+}
+
+// confirmBulk asks before running op on every named instance, then runs them as one batch.
+// after runs once the batch has ended, off the UI goroutine.
+func (mw *MainWindow) confirmBulk(action string, names []string, note string, op logic.BatchOp, after func()) {
+	msg := fmt.Sprintf("%s %d instances?\n\n%s", action, len(names), nameList(names, 10))
+	if note != "" {
+		msg += "\n\n" + note
+	}
+	dialog.ShowConfirm(action+" Instances", msg, func(ok bool) {
+		if ok {
+			mw.runBulk(action, names, op, after)
+		}
+	}, mw.Window)
+}
+
+// runBulk runs op on each instance in turn and shows the per-instance results in one summary
+func (mw *MainWindow) runBulk(action string, names []string, op logic.BatchOp, after func()) {
+	title := fmt.Sprintf("%s %d instances", action, len(names))
+	var results logic.BatchResults
+	mw.showBlockingProgress(title+"...", "", func(ctx context.Context, log func(string)) error {
+		results = logic.RunBatch(ctx, names, op, log)
+		return results.Err()
+	}, func() {
+		fyne.Do(func() { mw.showBatchSummary(title, results) })
+		if after != nil {
+			after()
+		}
+	})
+}
+
+// showBatchSummary lists the outcome of a batch, one row per instance
+func (mw *MainWindow) showBatchSummary(title string, results logic.BatchResults) {
+	var ok, failed, skipped int
+	rows := container.NewVBox()
+	for _, res := range results {
+		icon, detail := theme.ConfirmIcon(), "done in "+res.Duration.Round(time.Second).String()
+		switch {
+		case res.Skipped:
+			skipped++
+			icon, detail = theme.CancelIcon(), "skipped"
+		case res.Err != nil:
+			failed++
+			icon, detail = theme.ErrorIcon(), res.Err.Error()
+		default:
+			ok++
+		}
+		label := widget.NewLabel(res.Instance + " · " + detail)
+		label.Wrapping = fyne.TextWrapWord
+		rows.Add(container.NewBorder(nil, nil, widget.NewIcon(icon), nil, label))
+	}
+
+	counts := []string{fmt.Sprintf("%d succeeded", ok)}
+	if failed > 0 {
+		counts = append(counts, fmt.Sprintf("%d failed", failed))
+	}
+	if skipped > 0 {
+		counts = append(counts, fmt.Sprintf("%d skipped", skipped))
+	}
+	header := widget.NewLabelWithStyle(strings.Join(counts, ", "), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+
+	scroll := container.NewVScroll(rows)
+	scroll.SetMinSize(fyne.NewSize(500, float32(min(60*len(results), 360))))
+	d := dialog.NewCustom(title, "Close", container.NewBorder(header, nil, nil, nil, scroll), mw.Window)
+	d.Show()
+}
+
+// nameList renders up to limit names, one per line
+func nameList(names []string, limit int) string {
+	if len(names) <= limit {
+		return strings.Join(names, "\n")
+	}
+	return strings.Join(names[:limit], "\n") + fmt.Sprintf("\n... and %d more", len(names)-limit)
+}
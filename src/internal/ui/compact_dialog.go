@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"context"
+	"distronexus-gui/internal/logic"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showCompactDialog offers a dry-run estimate and the actual VHDX compaction for an instance
+func (mw *MainWindow) showCompactDialog(d logic.WslInstance) {
+	info := widget.NewLabel(fmt.Sprintf(
+		"Compacting '%s' runs fstrim inside it, stops it and shrinks its ext4.vhdx with Optimize-VHD or diskpart.\n"+
+			"Windows will ask for administrator rights.\n\nCurrent size: %s", d.Name, d.DiskSize))
+	info.Wrapping = fyne.TextWrapWord
+
+	var dlg dialog.Dialog
+	btnCancel := widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() { dlg.Hide() })
+	btnEstimate := widget.NewButtonWithIcon("Estimate", theme.SearchIcon(), func() {
+		dlg.Hide()
+		mw.estimateCompaction(d)
+	})
+	btnCompact := widget.NewButtonWithIcon("Compact", theme.ZoomOutIcon(), func() {
+		dlg.Hide()
+		mw.compactDistro(d)
+	})
+	btnCompact.Importance = widget.HighImportance
+
+	content := container.NewBorder(nil,
+		container.NewHBox(layout.NewSpacer(), btnCancel, btnEstimate, btnCompact),
+		nil, nil, info)
+	dlg = dialog.NewCustomWithoutButtons("Compact Disk", content, mw.Window)
+	dlg.Resize(fyne.NewSize(500, 250))
+	dlg.Show()
+}
+
+func (mw *MainWindow) estimateCompaction(d logic.WslInstance) {
+	var est logic.CompactEstimate
+	var estErr error
+	mw.showBlockingProgress("Estimating "+d.Name+"...", d.Name, func(ctx context.Context, log func(string)) error {
+		est, estErr = logic.EstimateCompaction(ctx, d.Name, d.BasePath, log)
+		return estErr
+	}, func() {
+		if estErr != nil {
+			return
+		}
+		fyne.Do(func() {
+			msg := fmt.Sprintf("VHDX file: %s\nUsed inside the instance: %s\nEstimated reclaimable: ~%s\n\nCompact now?",
+				logic.FormatBytes(est.VhdxBytes), logic.FormatBytes(est.UsedBytes), logic.FormatBytes(est.Reclaimable))
+			dialog.ShowConfirm("Compaction Estimate", msg, func(ok bool) {
+				if ok {
+					mw.compactDistro(d)
+				}
+			}, mw.Window)
+		})
+	})
+}
+
+func (mw *MainWindow) compactDistro(d logic.WslInstance) {
+	var res logic.CompactResult
+	var compactErr error
+	mw.showBlockingProgress("Compacting "+d.Name+"...", d.Name, func(ctx context.Context, log func(string)) error {
+		res, compactErr = logic.CompactDistro(ctx, mw.ProjectDir, d.Name, d.BasePath, nil, log)
+		return compactErr
+	}, func() {
+		if compactErr == nil {
+			fyne.Do(func() {
+				dialog.ShowInformation("Compaction Complete", fmt.Sprintf("Before: %s\nAfter: %s\nReclaimed: %s (using %s)",
+					logic.FormatBytes(res.BeforeBytes), logic.FormatBytes(res.AfterBytes), logic.FormatBytes(res.Saved()), res.Tool), mw.Window)
+			})
+		}
+		mw.RefreshHomeList()
+	})
+}
@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"context"
+	"distronexus-gui/internal/config"
+	"distronexus-gui/internal/logic"
+	"distronexus-gui/internal/model"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// customFamilyLabel is the family entry under which the install dialog lists custom packages
+const customFamilyLabel = "Custom Packages"
+
+// customPackageLabel is how a custom package appears in version lists
+func customPackageLabel(cp model.CustomPackage) string {
+	return cp.Name + " " + cp.Version
+}
+
+// customDefaultName suggests an instance name for a custom package
+func customDefaultName(cp model.CustomPackage) string {
+	return strings.Join(strings.Fields(cp.Name+"-"+cp.Version), "-")
+}
+
+// showCustomPackageDialog adds a custom package (index < 0) or edits the one at index.
+// The source must exist (local file) or answer (URL) before it is saved.
+func (mw *MainWindow) showCustomPackageDialog(index int, onSaved func()) {
+	var current model.CustomPackage
+	if index >= 0 {
+		current = mw.Settings.CustomPackages[index]
+	}
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("e.g. MyDistro")
+	nameEntry.SetText(current.Name)
+	versionEntry := widget.NewEntry()
+	versionEntry.SetPlaceHolder("e.g. 1.0")
+	versionEntry.SetText(current.Version)
+	sourceEntry := widget.NewEntry()
+	sourceEntry.SetPlaceHolder(`D:\images\rootfs.tar.gz or https://...`)
+	sourceEntry.SetText(current.PathOrUrl)
+	sourceEntry.Validator = config.ValidatePackageSource
+	btnBrowse := widget.NewButtonWithIcon("", theme.FileIcon(), func() {
+		dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
+			if r != nil {
+				sourceEntry.SetText(r.URI().Path())
+				r.Close()
+			}
+		}, mw.Window)
+	})
+
+	title, confirm := "Add Custom Package", "Add"
+	if index >= 0 {
+		title, confirm = "Edit Custom Package", "Save"
+	}
+	items := []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Version", versionEntry),
+		widget.NewFormItem("Path/URL", container.NewBorder(nil, nil, nil, btnBrowse, sourceEntry)),
+	}
+	dlg := dialog.NewForm(title, confirm, "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		pkg := model.CustomPackage{
+			Name:      strings.TrimSpace(nameEntry.Text),
+			Version:   strings.TrimSpace(versionEntry.Text),
+			PathOrUrl: strings.TrimSpace(sourceEntry.Text),
+		}
+		if pkg.PathOrUrl == current.PathOrUrl {
+			// Same source: keep the cached copy
+			pkg.LocalPath, pkg.Sha256 = current.LocalPath, current.Sha256
+		}
+		var others []model.CustomPackage
+		for i, cp := range mw.Settings.CustomPackages {
+			if i != index {
+				others = append(others, cp)
+			}
+		}
+		if errs := config.ValidateCustomPackage(pkg, others); len(errs) > 0 {
+			dialog.ShowError(errs, mw.Window)
+			return
+		}
+
+		var checkErr error
+		mw.showBlockingProgress("Checking "+pkg.PathOrUrl+"...", "", func(ctx context.Context, log func(string)) error {
+			checkErr = logic.CheckPackageSource(ctx, pkg.PathOrUrl)
+			return checkErr
+		}, func() {
+			if checkErr != nil {
+				return
+			}
+			fyne.Do(func() {
+				mw.saveCustomPackage(index, current, pkg)
+				if onSaved != nil {
+					onSaved()
+				}
+			})
+		})
+	}, mw.Window)
+	dlg.Resize(fyne.NewSize(560, 260))
+	dlg.Show()
+}
+
+// saveCustomPackage stores pkg at index (appends when index < 0). When the source changed,
+// the cached copy of the old source is released.
+func (mw *MainWindow) saveCustomPackage(index int, old, pkg model.CustomPackage) {
+	if index >= 0 && index < len(mw.Settings.CustomPackages) {
+		if old.Sha256 != "" && pkg.Sha256 == "" {
+			if _, err := logic.ReleaseCustomPackage(mw.cachePath(), mw.Distros, mw.Settings.CustomPackages, index); err != nil {
+				dialog.ShowError(err, mw.Window)
+			}
+		}
+		mw.Settings.CustomPackages[index] = pkg
+	} else {
+		mw.Settings.CustomPackages = append(mw.Settings.CustomPackages, pkg)
+	}
+	if err := mw.Config.SaveSettings(mw.Settings); err != nil {
+		dialog.ShowError(err, mw.Window)
+	}
+}
+
+// deleteCustomPackage removes the custom package at index and releases its cached copy
+func (mw *MainWindow) deleteCustomPackage(index int, onDone func()) {
+	cp := mw.Settings.CustomPackages[index]
+	dialog.ShowConfirm("Delete Custom Package", "Remove "+customPackageLabel(cp)+" from the library?", func(ok bool) {
+		if !ok {
+			return
+		}
+		if _, err := logic.ReleaseCustomPackage(mw.cachePath(), mw.Distros, mw.Settings.CustomPackages, index); err != nil {
+			dialog.ShowError(err, mw.Window)
+		}
+		mw.Settings.CustomPackages = append(mw.Settings.CustomPackages[:index:index], mw.Settings.CustomPackages[index+1:]...)
+		if err := mw.Config.SaveSettings(mw.Settings); err != nil {
+			dialog.ShowError(err, mw.Window)
+		}
+		onDone()
+	}, mw.Window)
+}
+
+// downloadCustomPackage fetches a custom package's URL into the package cache
+func (mw *MainWindow) downloadCustomPackage(cp model.CustomPackage, onDone func()) {
+	cacheDir := mw.cachePath()
+	mw.showBlockingProgress("Downloading "+customPackageLabel(cp)+"...", "", func(ctx context.Context, log func(string)) error {
+		if err := logic.DownloadCustomPackage(ctx, mw.ProjectDir, cacheDir, &cp, log); err != nil {
+			return err
+		}
+		// Record the reference before the cache limit is applied, or the new file looks unused
+		fyne.DoAndWait(func() { mw.updateCustomCacheRefs([]model.CustomPackage{cp}) })
+		return nil
+	}, func() {
+		mw.enforceCacheLimit()
+		if onDone != nil {
+			fyne.Do(onDone)
+		}
+	})
+}
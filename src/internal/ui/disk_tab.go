@@ -0,0 +1,223 @@
+package ui
+
+import (
+	"distronexus-gui/internal/logic"
+	"fmt"
+	"image/color"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// growthWindows are the periods offered for growth figures on the dashboard
+var growthWindows = []struct {
+	Label string
+	Span  time.Duration
+}{
+	{"Last 24 hours", 24 * time.Hour},
+	{"Last 7 days", 7 * 24 * time.Hour},
+	{"Last 30 days", 30 * 24 * time.Hour},
+	{"Last 90 days", 90 * 24 * time.Hour},
+}
+
+func (mw *MainWindow) makeDiskTab() fyne.CanvasObject {
+	headerLabel := widget.NewLabelWithStyle("Disk Usage", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+
+	windowLabels := make([]string, len(growthWindows))
+	for i, w := range growthWindows {
+		windowLabels[i] = w.Label
+	}
+	windowSelect := widget.NewSelect(windowLabels, nil)
+
+	body := container.NewVBox()
+	var samples []logic.DiskSample
+
+	render := func() {
+		span := growthWindows[1].Span
+		for _, w := range growthWindows {
+			if w.Label == windowSelect.Selected {
+				span = w.Span
+			}
+		}
+		body.Objects = mw.diskDashboard(samples, span)
+		body.Refresh()
+	}
+	windowSelect.OnChanged = func(string) { render() }
+
+	load := func() {
+		go func() {
+			loaded, err := logic.ReadDiskSamples(mw.ProjectDir)
+			fyne.Do(func() {
+				if err != nil {
+					dialog.ShowError(err, mw.Window)
+				}
+				samples = loaded
+				render()
+			})
+		}()
+	}
+
+	btnSample := widget.NewButtonWithIcon("Sample Now", theme.ViewRefreshIcon(), nil)
+	btnSample.OnTapped = func() {
+		btnSample.Disable()
+		go func() {
+			sample, err := logic.SampleDiskUsage(mw.ProjectDir, mw.cachePath())
+			if err == nil {
+				err = logic.RecordDiskSample(mw.ProjectDir, sample)
+			}
+			fyne.Do(func() {
+				btnSample.Enable()
+				if err != nil {
+					dialog.ShowError(err, mw.Window)
+					return
+				}
+				load()
+			})
+		}()
+	}
+
+	btnOrphans := widget.NewButtonWithIcon("Find Orphans", theme.SearchIcon(), mw.findOrphans)
+
+	windowSelect.SetSelected(growthWindows[1].Label)
+	load()
+
+	toolbar := container.NewBorder(nil, nil, headerLabel, container.NewHBox(windowSelect, btnSample, btnOrphans))
+	return container.NewBorder(toolbar, nil, nil, nil, container.NewVScroll(container.NewPadded(body)))
+}
+
+// diskDashboard builds the drive totals, growth trend and top consumers for the window
+func (mw *MainWindow) diskDashboard(samples []logic.DiskSample, span time.Duration) []fyne.CanvasObject {
+	if len(samples) == 0 {
+		return []fyne.CanvasObject{widget.NewLabel("No samples yet. Sizes are recorded every hour while DistroNexus runs, or use Sample Now.")}
+	}
+	latest := samples[len(samples)-1]
+	since := time.Now().Add(-span)
+
+	// Totals per drive
+	drives := container.NewGridWrap(fyne.NewSize(200, 70))
+	for _, d := range logic.UsageByDrive(latest) {
+		detail := fmt.Sprintf("%d instance(s)", d.Instances)
+		if d.HasCache {
+			detail += " + cache"
+		}
+		drives.Add(widget.NewCard("", "", container.NewVBox(
+			widget.NewLabelWithStyle(fmt.Sprintf("%s  %s", d.Drive, logic.FormatBytes(d.Bytes)), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabel(detail),
+		)))
+	}
+
+	// Total trend
+	var totals []float64
+	for _, s := range samples {
+		if !s.Time.Before(since) {
+			totals = append(totals, float64(s.Total()))
+		}
+	}
+	trendText := fmt.Sprintf("Total %s, sampled %s", logic.FormatBytes(latest.Total()), latest.Time.Local().Format("2006-01-02 15:04"))
+	if growth, ok := logic.TotalGrowth(samples, since); ok {
+		trendText += fmt.Sprintf(" · %s in window", formatGrowth(growth))
+	}
+	trend := container.NewVBox(widget.NewLabel(trendText))
+	if len(totals) >= 2 {
+		spark := newSparkline(totals)
+		trend.Add(container.NewGridWrap(fyne.NewSize(560, 80), spark))
+	}
+
+	// Top consumers
+	consumers := logic.TopConsumers(samples, since)
+	var largest int64 = 1
+	if len(consumers) > 0 && consumers[0].Bytes > 0 {
+		largest = consumers[0].Bytes
+	}
+	rows := container.NewVBox()
+	for _, c := range consumers {
+		bar := widget.NewProgressBar()
+		bar.Max = float64(largest)
+		bar.SetValue(float64(c.Bytes))
+		bar.TextFormatter = func() string { return logic.FormatBytes(c.Bytes) }
+
+		growth := formatGrowth(c.Growth)
+		if c.New {
+			growth = "new"
+		}
+		name := widget.NewLabelWithStyle(c.Name, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+		rows.Add(container.NewBorder(nil, nil,
+			container.NewGridWrap(fyne.NewSize(180, name.MinSize().Height), name),
+			container.NewGridWrap(fyne.NewSize(110, name.MinSize().Height), widget.NewLabel(growth)),
+			bar))
+	}
+
+	return []fyne.CanvasObject{
+		widget.NewLabelWithStyle("By Drive", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		drives,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Trend", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		trend,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Top Consumers", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		rows,
+	}
+}
+
+func formatGrowth(delta int64) string {
+	switch {
+	case delta > 0:
+		return "+" + logic.FormatBytes(delta)
+	case delta < 0:
+		return "-" + logic.FormatBytes(-delta)
+	}
+	return "±0"
+}
+
+// newSparkline draws values as a simple line chart scaled to its size
+func newSparkline(values []float64) fyne.CanvasObject {
+	bg := canvas.NewRectangle(color.Transparent)
+	bg.StrokeColor = theme.Color(theme.ColorNameSeparator)
+	bg.StrokeWidth = 1
+	objects := []fyne.CanvasObject{bg}
+	for i := 1; i < len(values); i++ {
+		line := canvas.NewLine(theme.Color(theme.ColorNamePrimary))
+		line.StrokeWidth = 2
+		objects = append(objects, line)
+	}
+	return container.New(&sparklineLayout{values: values}, objects...)
+}
+
+type sparklineLayout struct {
+	values []float64
+}
+
+func (l *sparklineLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	objects[0].Resize(size)
+	objects[0].Move(fyne.NewPos(0, 0))
+
+	lo, hi := l.values[0], l.values[0]
+	for _, v := range l.values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+	const pad = 4
+	w, h := size.Width-2*pad, size.Height-2*pad
+	point := func(i int) fyne.Position {
+		x := pad + w*float32(i)/float32(len(l.values)-1)
+		y := pad + h*(1-float32((l.values[i]-lo)/(hi-lo)))
+		return fyne.NewPos(x, y)
+	}
+	for i := 1; i < len(l.values); i++ {
+		line := objects[i].(*canvas.Line)
+		line.Position1 = point(i - 1)
+		line.Position2 = point(i)
+		line.Refresh()
+	}
+}
+
+func (l *sparklineLayout) MinSize([]fyne.CanvasObject) fyne.Size {
+	return fyne.NewSize(100, 40)
+}
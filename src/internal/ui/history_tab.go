@@ -0,0 +1,164 @@
+package ui
+
+import (
+	"distronexus-gui/internal/logic"
+	"fmt"
+	"sort"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const filterAll = "All"
+
+func (mw *MainWindow) makeHistoryTab() fyne.CanvasObject {
+	var all, shown []logic.HistoryEntry
+
+	list := widget.NewList(
+		func() int { return len(shown) },
+		func() fyne.CanvasObject {
+			title := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			detail := widget.NewLabel("")
+			detail.Truncation = fyne.TextTruncateEllipsis
+			return container.NewBorder(nil, nil, widget.NewIcon(theme.ConfirmIcon()), nil, container.NewVBox(title, detail))
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			e := shown[id]
+			row := obj.(*fyne.Container)
+			texts := row.Objects[0].(*fyne.Container)
+			icon := row.Objects[1].(*widget.Icon)
+
+			switch e.Result {
+			case logic.ResultSuccess:
+				icon.SetResource(theme.ConfirmIcon())
+			case logic.ResultCanceled:
+				icon.SetResource(theme.CancelIcon())
+			default:
+				icon.SetResource(theme.ErrorIcon())
+			}
+
+			target := e.Instance
+			if target == "" {
+				target = "-"
+			}
+			texts.Objects[0].(*widget.Label).SetText(fmt.Sprintf("%s  %s  %s", e.Time.Local().Format("2006-01-02 15:04:05"), e.Operation, target))
+
+			detail := fmt.Sprintf("%s · %s · %s@%s", e.Result, (time.Duration(e.DurationMs) * time.Millisecond).Round(100*time.Millisecond), e.User, e.Host)
+			if len(e.Params) > 0 {
+				detail += " · " + logic.FormatHistoryParams(e.Params)
+			}
+			if e.Error != "" {
+				detail += " · " + e.Error
+			}
+			texts.Objects[1].(*widget.Label).SetText(detail)
+		},
+	)
+
+	// Filters
+	opSelect := widget.NewSelect([]string{filterAll}, nil)
+	opSelect.SetSelected(filterAll)
+	resultSelect := widget.NewSelect([]string{filterAll, logic.ResultSuccess, logic.ResultFailed, logic.ResultCanceled}, nil)
+	resultSelect.SetSelected(filterAll)
+	sinceSelect := widget.NewSelect([]string{filterAll, "Last 24 hours", "Last 7 days", "Last 30 days"}, nil)
+	sinceSelect.SetSelected(filterAll)
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder("Search instance, parameters, errors...")
+
+	countLabel := widget.NewLabel("")
+
+	applyFilter := func() {
+		f := logic.HistoryFilter{Text: searchEntry.Text}
+		if opSelect.Selected != filterAll {
+			f.Operation = opSelect.Selected
+		}
+		if resultSelect.Selected != filterAll {
+			f.Result = resultSelect.Selected
+		}
+		switch sinceSelect.Selected {
+		case "Last 24 hours":
+			f.Since = time.Now().Add(-24 * time.Hour)
+		case "Last 7 days":
+			f.Since = time.Now().AddDate(0, 0, -7)
+		case "Last 30 days":
+			f.Since = time.Now().AddDate(0, 0, -30)
+		}
+		shown = logic.FilterHistory(all, f)
+		countLabel.SetText(fmt.Sprintf("%d of %d entries", len(shown), len(all)))
+		list.UnselectAll()
+		list.Refresh()
+	}
+
+	reload := func() {
+		entries, err := logic.ReadHistory(mw.ProjectDir)
+		if err != nil {
+			dialog.ShowError(err, mw.Window)
+		}
+		all = entries
+
+		// Offer only operations that actually occur
+		ops := map[string]bool{}
+		for _, e := range all {
+			ops[e.Operation] = true
+		}
+		options := []string{}
+		for op := range ops {
+			options = append(options, op)
+		}
+		sort.Strings(options)
+		opSelect.Options = append([]string{filterAll}, options...)
+		opSelect.Refresh()
+
+		applyFilter()
+	}
+
+	opSelect.OnChanged = func(string) { applyFilter() }
+	resultSelect.OnChanged = func(string) { applyFilter() }
+	sinceSelect.OnChanged = func(string) { applyFilter() }
+	searchEntry.OnChanged = func(string) { applyFilter() }
+
+	reload()
+
+	btnRefresh := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), reload)
+
+	btnExport := widget.NewButtonWithIcon("Export CSV", theme.DocumentSaveIcon(), func() {
+		entries := shown
+		save := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, mw.Window)
+				return
+			}
+			if w == nil {
+				return
+			}
+			defer w.Close()
+			if err := logic.ExportHistoryCSV(w, entries); err != nil {
+				dialog.ShowError(err, mw.Window)
+				return
+			}
+			dialog.ShowInformation("Export", fmt.Sprintf("Exported %d entries.", len(entries)), mw.Window)
+		}, mw.Window)
+		save.SetFileName(fmt.Sprintf("distronexus-history-%s.csv", time.Now().Format("20060102")))
+		save.Show()
+	})
+	btnExport.Importance = widget.LowImportance
+
+	headerToolbar := container.NewHBox(
+		widget.NewLabelWithStyle("Operation History", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		layout.NewSpacer(),
+		countLabel,
+		btnExport,
+		btnRefresh,
+	)
+
+	filterBar := container.NewBorder(nil, nil,
+		container.NewHBox(opSelect, resultSelect, sinceSelect), nil,
+		searchEntry,
+	)
+
+	return container.NewBorder(container.NewVBox(headerToolbar, filterBar), nil, nil, nil, list)
+}
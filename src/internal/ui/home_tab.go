@@ -6,8 +6,8 @@ import (
 	"fmt"
 	"image/color"
 	"path/filepath"
+	"sort"
 	"strings"
-	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
@@ -19,46 +19,6 @@ import (
 	"fyne.io/fyne/v2/widget"
 )
 
-// Helper for blocking progress dialog
-func showBlockingProgress(title string, win fyne.Window, task func(func(string)) error, onDone func()) {
-	logBinding := binding.NewString()
-	logLabel := widget.NewLabelWithData(logBinding)
-	logLabel.Wrapping = fyne.TextWrapBreak
-
-	// Scroll container for log
-	scroll := container.NewScroll(logLabel)
-	scroll.SetMinSize(fyne.NewSize(500, 300))
-
-	progressBar := widget.NewProgressBarInfinite()
-
-	content := container.NewBorder(progressBar, nil, nil, nil, scroll)
-
-	d := dialog.NewCustomWithoutButtons(title, content, win)
-	d.Show()
-
-	go func() {
-		err := task(func(line string) {
-			current, _ := logBinding.Get()
-			// Keep last ~2000 chars to avoid memory issues if long running?
-			// Or just append.
-			logBinding.Set(current + line)
-		})
-
-		d.Hide()
-
-		if err != nil {
-			dialog.ShowError(err, win)
-		} else {
-			// Optional success message?
-			// dialog.ShowInformation(title, "Completed Successfully", win)
-		}
-
-		if onDone != nil {
-			onDone()
-		}
-	}()
-}
-
 func (mw *MainWindow) makeHomeTab() fyne.CanvasObject {
 	// Header Label (Title)
 	headerBinding := binding.NewString()
@@ -75,10 +35,10 @@ func (mw *MainWindow) makeHomeTab() fyne.CanvasObject {
 	var refreshFunc func(force bool)
 	refreshFunc = func(force bool) {
 		if force {
-			showBlockingProgress("Scanning...", mw.Window, func(log func(string)) error {
+			mw.showBlockingProgress("Scanning...", "", func(ctx context.Context, log func(string)) error {
 				srcUrl := mw.Settings.DistroSourceUrl // "" defaults to internal script default
-				_ = logic.ScanDistros(context.Background(), mw.ProjectDir, nil)
-				return logic.UpdateDistroList(context.Background(), mw.ProjectDir, srcUrl, nil)
+				_ = logic.ScanDistros(ctx, mw.ProjectDir, nil)
+				return logic.UpdateDistroList(ctx, mw.ProjectDir, srcUrl, nil)
 			}, func() {
 				// Determine content
 				distros, _ := logic.ListDistros(mw.ProjectDir, false)
@@ -106,28 +66,291 @@ func (mw *MainWindow) makeHomeTab() fyne.CanvasObject {
 		refreshFunc(false)
 	}
 
+	btnSelect := widget.NewButtonWithIcon("Select", theme.CheckButtonCheckedIcon(), nil)
+	btnSelect.OnTapped = func() {
+		mw.setHomeSelecting(!mw.home.selecting)
+		if mw.home.selecting {
+			btnSelect.Importance = widget.HighImportance
+		} else {
+			btnSelect.Importance = widget.MediumImportance
+		}
+		btnSelect.Refresh()
+	}
+
+	// Filters exist before the first load renders through them
+	filterBar := mw.makeHomeFilterBar()
+	bulkBar := mw.makeHomeBulkBar()
+
 	// Trigger initial load
 	refreshFunc(false)
 
 	background := canvas.NewRectangle(color.Transparent)
-	headerToolbar := container.NewBorder(nil, nil, headerLabel, btnRefresh, background)
+	headerToolbar := container.NewBorder(nil, nil, headerLabel, container.NewHBox(btnSelect, btnRefresh), background)
 
 	return container.NewBorder(
-		headerToolbar,
+		container.NewVBox(headerToolbar, filterBar, bulkBar),
 		nil, nil, nil,
 		scroll, // Use scroll with vbox instead of List
 	)
 }
 
+// homeView is the filter and sort state of the home list
+type homeView struct {
+	list    *fyne.Container
+	count   *widget.Label
+	release *widget.Select
+	drive   *widget.Select
+	tag     *widget.Select
+	filter  logic.InstanceFilter
+	sortBy  string
+	desc    bool
+	// grouped shows one section per tag; collapsed holds the folded ones by lower-case tag
+	grouped   bool
+	collapsed map[string]bool
+	// shown are the names on screen in display order
+	shown []string
+
+	// Selection mode for bulk actions, see bulk_actions.go
+	selecting   bool
+	selected    map[string]bool
+	checks      map[string][]*widget.Check
+	bulkBar     *fyne.Container
+	bulkButtons []*widget.Button
+	selCount    *widget.Label
+}
+
+// "All" entries of the filter selects map to an empty criterion
+const (
+	allStates   = "All states"
+	allVersions = "All versions"
+	allReleases = "All releases"
+	allDrives   = "All drives"
+	allTags     = "All tags"
+)
+
+// filterValue turns a select's choice into a filter criterion
+func filterValue(selected, all string) string {
+	if selected == all {
+		return ""
+	}
+	return selected
+}
+
+// makeHomeFilterBar builds the search box, filters and sort controls of the home view
+func (mw *MainWindow) makeHomeFilterBar() fyne.CanvasObject {
+	hv := &mw.home
+	search := widget.NewEntry()
+	search.SetPlaceHolder("Search name, release, path, user, tags or note")
+	search.OnChanged = func(text string) {
+		hv.filter.Query = text
+		mw.renderHomeList()
+	}
+
+	state := widget.NewSelect([]string{allStates, "Running", "Stopped"}, func(s string) {
+		hv.filter.State = filterValue(s, allStates)
+		mw.renderHomeList()
+	})
+	state.SetSelected(allStates)
+	version := widget.NewSelect([]string{allVersions, "WSL 1", "WSL 2"}, func(s string) {
+		hv.filter.WslVer = strings.TrimPrefix(filterValue(s, allVersions), "WSL ")
+		mw.renderHomeList()
+	})
+	version.SetSelected(allVersions)
+	hv.release = widget.NewSelect([]string{allReleases}, func(s string) {
+		hv.filter.Release = filterValue(s, allReleases)
+		mw.renderHomeList()
+	})
+	hv.release.SetSelected(allReleases)
+	hv.drive = widget.NewSelect([]string{allDrives}, func(s string) {
+		hv.filter.Drive = filterValue(s, allDrives)
+		mw.renderHomeList()
+	})
+	hv.drive.SetSelected(allDrives)
+	hv.tag = widget.NewSelect([]string{allTags}, func(s string) {
+		hv.filter.Tag = filterValue(s, allTags)
+		mw.renderHomeList()
+	})
+	hv.tag.SetSelected(allTags)
+	hv.collapsed = map[string]bool{}
+	group := widget.NewCheck("Group by tag", func(on bool) {
+		hv.grouped = on
+		mw.renderHomeList()
+	})
+
+	sortSelect := widget.NewSelect([]string{logic.SortByName, logic.SortBySize, logic.SortByInstallTime}, func(s string) {
+		hv.sortBy = s
+		mw.renderHomeList()
+	})
+	sortSelect.SetSelected(logic.SortByName)
+	var btnOrder *widget.Button
+	btnOrder = widget.NewButtonWithIcon("", theme.MoveDownIcon(), func() {
+		hv.desc = !hv.desc
+		if hv.desc {
+			btnOrder.SetIcon(theme.MoveUpIcon())
+		} else {
+			btnOrder.SetIcon(theme.MoveDownIcon())
+		}
+		mw.renderHomeList()
+	})
+	btnOrder.Importance = widget.LowImportance
+
+	hv.count = widget.NewLabel("")
+	controls := container.NewHBox(state, version, hv.release, hv.drive, hv.tag, group, sortSelect, btnOrder, hv.count)
+	return container.NewBorder(nil, nil, nil, controls, search)
+}
+
 func (mw *MainWindow) rebuildHomeList(containerBox *fyne.Container, distros []logic.WslInstance) {
+	mw.Watcher.Seed(distros)
+	fyne.Do(func() {
+		instances := make(map[string]logic.WslInstance, len(distros))
+		for _, d := range distros {
+			instances[d.Name] = d
+		}
+		mw.homeInstances = instances
+		mw.home.list = containerBox
+		mw.updateHomeFilterOptions()
+		mw.renderHomeList()
+		mw.refreshTrayMenu()
+	})
+}
+
+// updateHomeFilterOptions offers the releases, drives and tags of the current instances
+func (mw *MainWindow) updateHomeFilterOptions() {
+	releases, drives := map[string]bool{}, map[string]bool{}
+	for _, d := range mw.homeInstances {
+		if d.Release != "" {
+			releases[d.Release] = true
+		}
+		if drive := logic.InstanceDrive(d); drive != "" {
+			drives[drive] = true
+		}
+	}
+	setOptions := func(sel *widget.Select, all string, values map[string]bool) {
+		options := make([]string, 0, len(values))
+		for v := range values {
+			options = append(options, v)
+		}
+		sort.Strings(options)
+		sel.Options = append([]string{all}, options...)
+		if !values[sel.Selected] {
+			sel.SetSelected(all)
+		}
+		sel.Refresh()
+	}
+	setOptions(mw.home.release, allReleases, releases)
+	setOptions(mw.home.drive, allDrives, drives)
+	tags := map[string]bool{}
+	for _, t := range mw.knownTags() {
+		tags[t] = true
+	}
+	setOptions(mw.home.tag, allTags, tags)
+}
+
+// renderHomeList shows the instances passing the home filter in the chosen order.
+// Must run on the UI goroutine.
+func (mw *MainWindow) renderHomeList() {
+	hv := &mw.home
+	if hv.list == nil {
+		return
+	}
+	var shown []logic.WslInstance
+	for _, d := range mw.homeInstances {
+		if hv.filter.Match(d) {
+			shown = append(shown, d)
+		}
+	}
+	logic.SortInstances(shown, hv.sortBy, hv.desc)
+
+	// Bulk actions only ever apply to instances on screen: drop the selection of any
+	// instance filtered out or gone since the last render
+	hv.shown = hv.shown[:0]
+	onScreen := make(map[string]bool, len(shown))
+	for _, d := range shown {
+		hv.shown = append(hv.shown, d.Name)
+		onScreen[d.Name] = true
+	}
+	for name := range hv.selected {
+		if !onScreen[name] {
+			delete(hv.selected, name)
+		}
+	}
+	hv.checks = map[string][]*widget.Check{}
+
+	// In the grouped view an instance has a card under each of its tags
+	cards := make(map[string][]*fyne.Container, len(shown))
 	var objects []fyne.CanvasObject
-	for _, d := range distros {
-		item := mw.createDistroItem(d)
-		// Wrap in padding to creating margin around the card
-		objects = append(objects, container.NewPadded(item))
+	addCards := func(list []logic.WslInstance) {
+		for _, d := range list {
+			// Wrap in padding to creating margin around the card
+			card := container.NewPadded(mw.homeCardContent(d))
+			cards[d.Name] = append(cards[d.Name], card)
+			objects = append(objects, card)
+		}
+	}
+	if hv.grouped {
+		for _, g := range logic.GroupByTag(shown) {
+			objects = append(objects, mw.homeGroupHeader(g))
+			if !hv.collapsed[strings.ToLower(g.Tag)] {
+				addCards(g.Instances)
+			}
+		}
+	} else {
+		addCards(shown)
+	}
+	if len(objects) == 0 && len(mw.homeInstances) > 0 {
+		objects = append(objects, container.NewCenter(widget.NewLabel("No instances match the filter")))
+	}
+	mw.homeCards = cards
+	hv.list.Objects = objects
+	hv.list.Refresh()
+	if len(shown) < len(mw.homeInstances) {
+		hv.count.SetText(fmt.Sprintf("%d of %d", len(shown), len(mw.homeInstances)))
+	} else {
+		hv.count.SetText(fmt.Sprintf("%d", len(shown)))
+	}
+	mw.updateBulkBar()
+}
+
+// homeGroupHeader is the title of a tag section; tapping it folds or unfolds the section
+func (mw *MainWindow) homeGroupHeader(g logic.InstanceGroup) fyne.CanvasObject {
+	key := strings.ToLower(g.Tag)
+	icon := theme.MenuDropDownIcon()
+	if mw.home.collapsed[key] {
+		icon = theme.MenuExpandIcon()
+	}
+	btn := widget.NewButtonWithIcon(fmt.Sprintf("%s (%d)", g.Tag, len(g.Instances)), icon, func() {
+		mw.home.collapsed[key] = !mw.home.collapsed[key]
+		mw.renderHomeList()
+	})
+	btn.Alignment = widget.ButtonAlignLeading
+	btn.Importance = widget.LowImportance
+	return btn
+}
+
+// updateHomeCard redraws only the card whose instance changed state, or the whole list
+// when a state filter is active. Instances the home view doesn't know about yet trigger a full reload.
+func (mw *MainWindow) updateHomeCard(ev logic.InstanceStateEvent) {
+	inst, known := mw.homeInstances[ev.Name]
+	if !known {
+		if mw.RefreshHomeList != nil {
+			mw.RefreshHomeList()
+		}
+		return
+	}
+	inst.State = ev.State
+	mw.homeInstances[ev.Name] = inst
+	mw.refreshTrayMenu()
+
+	if mw.home.filter.State != "" {
+		// The instance may have entered or left the state filter
+		mw.renderHomeList()
+		return
+	}
+	mw.home.checks[ev.Name] = nil
+	for _, card := range mw.homeCards[ev.Name] {
+		card.Objects = []fyne.CanvasObject{mw.homeCardContent(inst)}
+		card.Refresh()
 	}
-	containerBox.Objects = objects
-	containerBox.Refresh()
 }
 
 func (mw *MainWindow) createDistroItem(d logic.WslInstance) fyne.CanvasObject {
@@ -162,6 +385,24 @@ func (mw *MainWindow) createDistroItem(d logic.WslInstance) fyne.CanvasObject {
 	btnCreds.Importance = widget.LowImportance
 	btnDelete := widget.NewButtonWithIcon("", theme.DeleteIcon(), nil)
 	btnDelete.Importance = widget.LowImportance
+	// Compact is offered in both states; it stops the instance itself
+	btnCompact := widget.NewButtonWithIcon("", theme.ZoomOutIcon(), func() {
+		mw.showCompactDialog(d)
+	})
+	btnCompact.Importance = widget.LowImportance
+	btnVhd := widget.NewButtonWithIcon("", theme.ViewFullScreenIcon(), func() {
+		mw.showVhdSettingsDialog(d)
+	})
+	btnVhd.Importance = widget.LowImportance
+	// wsl --export works on running instances too
+	btnExportImage := widget.NewButtonWithIcon("", theme.DocumentSaveIcon(), func() {
+		mw.showExportImageDialog(d)
+	})
+	btnExportImage.Importance = widget.LowImportance
+	btnMeta := widget.NewButtonWithIcon("", theme.FileTextIcon(), func() {
+		mw.showInstanceMetaDialog(mw.homeInstances[d.Name])
+	})
+	btnMeta.Importance = widget.LowImportance
 
 	isRunning := (d.State == "Running")
 
@@ -173,6 +414,7 @@ func (mw *MainWindow) createDistroItem(d logic.WslInstance) fyne.CanvasObject {
 		btnMove.Hide()
 		btnRename.Hide()
 		btnCreds.Hide()
+		btnVhd.Hide()
 		btnDelete.Hide()
 	} else {
 		btnOpen.Show()
@@ -181,6 +423,7 @@ func (mw *MainWindow) createDistroItem(d logic.WslInstance) fyne.CanvasObject {
 		btnMove.Show()
 		btnRename.Show()
 		btnCreds.Show()
+		btnVhd.Show()
 		btnDelete.Show()
 	}
 
@@ -189,13 +432,9 @@ func (mw *MainWindow) createDistroItem(d logic.WslInstance) fyne.CanvasObject {
 		// Start in background
 		dialog.ShowConfirm("Start Instance", fmt.Sprintf("Start '%s' in background?", d.Name), func(ok bool) {
 			if ok {
-				showBlockingProgress("Starting...", mw.Window, func(log func(string)) error {
-					return logic.StartDistro(context.Background(), mw.ProjectDir, d.Name, false, "")
-				}, func() {
-					// Give a moment for the state to propagate before refreshing
-					time.Sleep(500 * time.Millisecond)
-					mw.RefreshHomeList()
-				})
+				mw.showBlockingProgress("Starting "+d.Name+"...", d.Name, func(ctx context.Context, log func(string)) error {
+					return logic.StartDistro(ctx, mw.ProjectDir, d.Name, false, "")
+				}, mw.Watcher.Trigger)
 			}
 		}, mw.Window)
 	}
@@ -208,14 +447,15 @@ func (mw *MainWindow) createDistroItem(d logic.WslInstance) fyne.CanvasObject {
 		if err != nil {
 			dialog.ShowError(err, mw.Window)
 		}
+		mw.Watcher.Trigger()
 	}
 
 	btnStop.OnTapped = func() {
 		dialog.ShowConfirm("Stop Instance", "Are you sure you want to force stop this instance?", func(ok bool) {
 			if ok {
-				showBlockingProgress("Stopping...", mw.Window, func(log func(string)) error {
-					return logic.StopDistro(context.Background(), mw.ProjectDir, d.Name, log)
-				}, func() { mw.RefreshHomeList() })
+				mw.showBlockingProgress("Stopping "+d.Name+"...", d.Name, func(ctx context.Context, log func(string)) error {
+					return logic.StopDistro(ctx, mw.ProjectDir, d.Name, log)
+				}, mw.Watcher.Trigger)
 			}
 		}, mw.Window)
 	}
@@ -229,8 +469,8 @@ func (mw *MainWindow) createDistroItem(d logic.WslInstance) fyne.CanvasObject {
 
 			dialog.ShowConfirm("Move Instance", fmt.Sprintf("Move to %s?", newPath), func(ok bool) {
 				if ok {
-					showBlockingProgress("Moving Instance...", mw.Window, func(log func(string)) error {
-						return logic.MoveDistro(context.Background(), mw.ProjectDir, d.Name, newPath, log)
+					mw.showBlockingProgress("Moving "+d.Name+"...", d.Name, func(ctx context.Context, log func(string)) error {
+						return logic.MoveDistro(ctx, mw.ProjectDir, d.Name, newPath, log)
 					}, func() { mw.RefreshHomeList() })
 				}
 			}, mw.Window)
@@ -248,8 +488,8 @@ func (mw *MainWindow) createDistroItem(d logic.WslInstance) fyne.CanvasObject {
 				if newName == "" || newName == d.Name {
 					return
 				}
-				showBlockingProgress("Renaming...", mw.Window, func(log func(string)) error {
-					return logic.RenameDistro(context.Background(), mw.ProjectDir, d.Name, newName, "", log)
+				mw.showBlockingProgress("Renaming "+d.Name+"...", d.Name, func(ctx context.Context, log func(string)) error {
+					return logic.RenameDistro(ctx, mw.ProjectDir, d.Name, newName, "", log)
 				}, func() { mw.RefreshHomeList() })
 			}
 		}, mw.Window)
@@ -269,8 +509,8 @@ func (mw *MainWindow) createDistroItem(d logic.WslInstance) fyne.CanvasObject {
 
 		dlog := dialog.NewForm("Credentials", "Set", "Cancel", items, func(ok bool) {
 			if ok {
-				showBlockingProgress("Setting Credentials...", mw.Window, func(log func(string)) error {
-					return logic.SetDistroCredentials(context.Background(), mw.ProjectDir, d.Name, uEntry.Text, pEntry.Text, log)
+				mw.showBlockingProgress("Setting Credentials for "+d.Name+"...", d.Name, func(ctx context.Context, log func(string)) error {
+					return logic.SetDistroCredentials(ctx, mw.ProjectDir, d.Name, uEntry.Text, pEntry.Text, log)
 				}, func() { mw.RefreshHomeList() })
 			}
 		}, mw.Window)
@@ -281,8 +521,8 @@ func (mw *MainWindow) createDistroItem(d logic.WslInstance) fyne.CanvasObject {
 	btnDelete.OnTapped = func() {
 		dialog.ShowConfirm("Uninstall", "Permanently delete this distribution?", func(ok bool) {
 			if ok {
-				showBlockingProgress("Uninstalling...", mw.Window, func(log func(string)) error {
-					return logic.UnregisterDistro(context.Background(), mw.ProjectDir, d.Name, true, log)
+				mw.showBlockingProgress("Uninstalling "+d.Name+"...", d.Name, func(ctx context.Context, log func(string)) error {
+					return logic.UnregisterDistro(ctx, mw.ProjectDir, d.Name, true, log)
 				}, func() { mw.RefreshHomeList() })
 			}
 		}, mw.Window)
@@ -293,7 +533,7 @@ func (mw *MainWindow) createDistroItem(d logic.WslInstance) fyne.CanvasObject {
 	// Buttons Container
 	btnBox := container.NewHBox(
 		btnOpen, btnTerminal, btnStop,
-		btnMove, btnRename, btnCreds, btnDelete,
+		btnMove, btnRename, btnCreds, btnVhd, btnCompact, btnExportImage, btnMeta, btnDelete,
 	)
 
 	// Row 1
@@ -327,6 +567,13 @@ func (mw *MainWindow) createDistroItem(d logic.WslInstance) fyne.CanvasObject {
 		size = "Unknown Size"
 	}
 	pathText := fmt.Sprintf("%s (%s)", displayPath, size)
+	if d.VhdMaxSize > 0 {
+		vhdMode := "Sparse off"
+		if d.Sparse {
+			vhdMode = "Sparse on"
+		}
+		pathText += fmt.Sprintf(" · %s · Max %s", vhdMode, logic.FormatBytes(int64(d.VhdMaxSize)))
+	}
 
 	// Using RichText for rows 2 & 3 to control color (make them look secondary)
 	// User requested darker text as Disabled was too faint. Using default Foreground.
@@ -335,6 +582,23 @@ func (mw *MainWindow) createDistroItem(d logic.WslInstance) fyne.CanvasObject {
 		&widget.TextSegment{Text: pathText, Style: widget.RichTextStyle{Inline: true}},
 	)
 
+	// Row 4: Tags · Owner, then the note
+	var metaParts []string
+	if len(d.Tags) > 0 {
+		metaParts = append(metaParts, "Tags: "+strings.Join(d.Tags, ", "))
+	}
+	if d.Owner != "" {
+		metaParts = append(metaParts, "Owner: "+d.Owner)
+	}
+	if len(metaParts) > 0 {
+		infoRich.Segments = append(infoRich.Segments,
+			&widget.TextSegment{Text: "\n" + strings.Join(metaParts, " · "), Style: widget.RichTextStyle{Inline: true, TextStyle: fyne.TextStyle{Bold: true}}})
+	}
+	if d.Note != "" {
+		infoRich.Segments = append(infoRich.Segments,
+			&widget.TextSegment{Text: "\n" + d.Note, Style: widget.RichTextStyle{Inline: true, TextStyle: fyne.TextStyle{Italic: true}}})
+	}
+
 	// Main Content
 	content := container.NewVBox(
 		row1,
@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"context"
+	"distronexus-gui/internal/logic"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	exportTarball = "Tarball (docker/podman load)"
+	exportLayout  = "OCI layout folder"
+)
+
+// defaultImageReference turns an instance name into an image name ("My_Distro" -> "my_distro:latest")
+func defaultImageReference(name string) string {
+	ref := regexp.MustCompile(`[^a-z0-9._-]+`).ReplaceAllString(strings.ToLower(name), "-")
+	ref = strings.Trim(ref, "._-")
+	if ref == "" {
+		ref = "wsl"
+	}
+	return ref + ":latest"
+}
+
+// showExportImageDialog exports an instance as a single-layer OCI image
+func (mw *MainWindow) showExportImageDialog(d logic.WslInstance) {
+	refEntry := widget.NewEntry()
+	refEntry.SetText(defaultImageReference(d.Name))
+	refEntry.Validator = logic.ValidateImageReference
+
+	home, _ := os.UserHomeDir()
+	outputEntry := widget.NewEntry()
+	outputEntry.SetText(filepath.Join(home, d.Name+".oci.tar"))
+
+	formatSelect := widget.NewSelect([]string{exportTarball, exportLayout}, func(s string) {
+		out := strings.TrimSpace(outputEntry.Text)
+		if s == exportLayout {
+			outputEntry.SetText(strings.TrimSuffix(out, ".oci.tar"))
+		} else if !strings.HasSuffix(strings.ToLower(out), ".tar") {
+			outputEntry.SetText(out + ".oci.tar")
+		}
+	})
+	formatSelect.SetSelected(exportTarball)
+
+	btnBrowse := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if uri == nil {
+				return
+			}
+			base := d.Name + ".oci.tar"
+			if formatSelect.Selected == exportLayout {
+				base = d.Name
+			}
+			outputEntry.SetText(filepath.Join(uri.Path(), base))
+		}, mw.Window)
+	})
+
+	entrypointEntry := widget.NewEntry()
+	entrypointEntry.SetPlaceHolder("e.g. /bin/bash -l")
+	cmdEntry := widget.NewEntry()
+	cmdEntry.SetPlaceHolder("optional")
+	envEntry := widget.NewMultiLineEntry()
+	envEntry.SetPlaceHolder("KEY=VALUE, one per line")
+	envEntry.SetMinRowsVisible(2)
+	labelsEntry := widget.NewMultiLineEntry()
+	labelsEntry.SetPlaceHolder("key=value, one per line")
+	labelsEntry.SetMinRowsVisible(2)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Image Name", refEntry),
+		widget.NewFormItem("Format", formatSelect),
+		widget.NewFormItem("Output", container.NewBorder(nil, nil, nil, btnBrowse, outputEntry)),
+		widget.NewFormItem("Entrypoint", entrypointEntry),
+		widget.NewFormItem("Cmd", cmdEntry),
+		widget.NewFormItem("Env", envEntry),
+		widget.NewFormItem("Labels", labelsEntry),
+	}
+	dlg := dialog.NewForm("Export as Container Image", "Export", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		output := strings.TrimSpace(outputEntry.Text)
+		if output == "" {
+			dialog.ShowError(pluginError("Output is required"), mw.Window)
+			return
+		}
+		opts := logic.ImageExportOptions{
+			Reference:  strings.TrimSpace(refEntry.Text),
+			Entrypoint: strings.Fields(entrypointEntry.Text),
+			Cmd:        strings.Fields(cmdEntry.Text),
+			Layout:     formatSelect.Selected == exportLayout,
+			Labels:     map[string]string{},
+		}
+		env, err := parseKeyValueLines(envEntry.Text, "Env")
+		if err != nil {
+			dialog.ShowError(err, mw.Window)
+			return
+		}
+		for _, kv := range env {
+			opts.Env = append(opts.Env, kv[0]+"="+kv[1])
+		}
+		labels, err := parseKeyValueLines(labelsEntry.Text, "Labels")
+		if err != nil {
+			dialog.ShowError(err, mw.Window)
+			return
+		}
+		for _, kv := range labels {
+			opts.Labels[kv[0]] = kv[1]
+		}
+		if opts.Layout {
+			if entries, err := os.ReadDir(output); err == nil && len(entries) > 0 {
+				dialog.ShowError(fmt.Errorf("%s is not empty; choose a new folder for the OCI layout", output), mw.Window)
+				return
+			}
+		}
+
+		var exportErr error
+		mw.showBlockingProgress("Exporting "+d.Name+" as image...", d.Name, func(ctx context.Context, log func(string)) error {
+			exportErr = logic.ExportDistroImage(ctx, mw.ProjectDir, d.Name, output, opts, log)
+			return exportErr
+		}, func() {
+			if exportErr != nil {
+				return
+			}
+			fyne.Do(func() {
+				hint := "Load it with: docker load -i \"" + output + "\""
+				if opts.Layout {
+					hint = "Copy it with: skopeo copy oci:\"" + output + "\" ..."
+				}
+				dialog.ShowInformation("Export Image", "Image written to "+output+".\n"+hint, mw.Window)
+			})
+		})
+	}, mw.Window)
+	dlg.Resize(fyne.NewSize(600, 520))
+	dlg.Show()
+}
+
+// parseKeyValueLines reads "key=value" lines, skipping blank ones
+func parseKeyValueLines(text, field string) ([][2]string, error) {
+	var out [][2]string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("%s: expected key=value, got %q", field, line)
+		}
+		out = append(out, [2]string{strings.TrimSpace(k), v})
+	}
+	return out, nil
+}
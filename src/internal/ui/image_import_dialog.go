@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"context"
+	"distronexus-gui/internal/logic"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showImportImageDialog installs an OCI image layout or docker save tarball as a new instance
+func (mw *MainWindow) showImportImageDialog() {
+	sourceEntry := widget.NewEntry()
+	sourceEntry.SetPlaceHolder(`image.tar or OCI layout folder`)
+	btnFile := widget.NewButtonWithIcon("", theme.FileIcon(), func() {
+		dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
+			if r != nil {
+				sourceEntry.SetText(r.URI().Path())
+				r.Close()
+			}
+		}, mw.Window)
+	})
+	btnFolder := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if uri != nil {
+				sourceEntry.SetText(uri.Path())
+			}
+		}, mw.Window)
+	})
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Instance Name")
+	nameEntry.Validator = logic.ValidateDistroName
+
+	pathEntry := widget.NewEntry()
+	pathEntry.SetPlaceHolder(filepath.Join(mw.Settings.DefaultInstallPath, "<name>"))
+
+	userEntry := widget.NewEntry()
+	userEntry.SetPlaceHolder("optional, root when empty")
+	passEntry := widget.NewPasswordEntry()
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Image", container.NewBorder(nil, nil, nil, container.NewHBox(btnFile, btnFolder), sourceEntry)),
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Install Location", pathEntry),
+		widget.NewFormItem("Username", userEntry),
+		widget.NewFormItem("Password", passEntry),
+	}
+	dlg := dialog.NewForm("Import Container Image", "Import", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		src := strings.TrimSpace(sourceEntry.Text)
+		name := strings.TrimSpace(nameEntry.Text)
+		user, pass := strings.TrimSpace(userEntry.Text), passEntry.Text
+		if src == "" || name == "" {
+			dialog.ShowError(pluginError("Image and Name are required"), mw.Window)
+			return
+		}
+		if user != "" && pass == "" {
+			dialog.ShowError(pluginError("A password is required for the new user"), mw.Window)
+			return
+		}
+		if user != "" && user != "root" {
+			if err := logic.ValidateLinuxUser(user); err != nil {
+				dialog.ShowError(err, mw.Window)
+				return
+			}
+		}
+		installPath := strings.TrimSpace(pathEntry.Text)
+		if installPath == "" {
+			installPath = filepath.Join(mw.Settings.DefaultInstallPath, name)
+		}
+		if err := logic.ValidateInstallPath(installPath); err != nil {
+			dialog.ShowError(err, mw.Window)
+			return
+		}
+
+		prov := mw.Settings.Provisioning
+		var importErr error
+		mw.showBlockingProgress("Importing image as "+name+"...", name, func(ctx context.Context, log func(string)) error {
+			importErr = logic.InstallImage(ctx, mw.ProjectDir, src, name, installPath, user, pass, prov, log)
+			return importErr
+		}, func() {
+			if mw.RefreshHomeList != nil {
+				mw.RefreshHomeList()
+			}
+			if importErr == nil {
+				fyne.Do(func() {
+					dialog.ShowInformation("Import Image", "Instance '"+name+"' is ready.", mw.Window)
+				})
+			}
+		})
+	}, mw.Window)
+	dlg.Resize(fyne.NewSize(600, 380))
+	dlg.Show()
+}
@@ -27,6 +27,18 @@ func (mw *MainWindow) ShowInstallDialog(preSelectedFamily string, preSelectedVer
 	}
 	sort.Strings(distroNames)
 
+	// Custom packages are listed as one extra family
+	customByLabel := make(map[string]model.CustomPackage)
+	var customLabels []string
+	for _, cp := range mw.Settings.CustomPackages {
+		customByLabel[customPackageLabel(cp)] = cp
+		customLabels = append(customLabels, customPackageLabel(cp))
+	}
+	if len(customLabels) > 0 {
+		sort.Strings(customLabels)
+		distroNames = append(distroNames, customFamilyLabel)
+	}
+
 	// Widgets
 	distroSelect := widget.NewSelect(distroNames, nil)
 	distroSelect.PlaceHolder = "Select Family"
@@ -38,6 +50,12 @@ func (mw *MainWindow) ShowInstallDialog(preSelectedFamily string, preSelectedVer
 	nameEntry.PlaceHolder = "Instance Name (e.g. MyUbuntu)"
 
 	updateVersions := func(fam string) {
+		if fam == customFamilyLabel {
+			versionSelect.Options = customLabels
+			versionSelect.Selected = ""
+			versionSelect.Refresh()
+			return
+		}
 		if cfg, ok := distroMap[fam]; ok {
 			var vers []string
 			// We need versions sorted by version name or some key?
@@ -73,6 +91,12 @@ func (mw *MainWindow) ShowInstallDialog(preSelectedFamily string, preSelectedVer
 		if s == "" {
 			return
 		}
+		if cp, ok := customByLabel[s]; ok && distroSelect.Selected == customFamilyLabel {
+			if nameEntry.Text == "" {
+				nameEntry.SetText(customDefaultName(cp))
+			}
+			return
+		}
 		if cfg, ok := distroMap[distroSelect.Selected]; ok {
 			for _, v := range cfg.Versions {
 				if v.Name == s && nameEntry.Text == "" {
@@ -196,19 +220,69 @@ func (mw *MainWindow) ShowInstallDialog(preSelectedFamily string, preSelectedVer
 					dialog.ShowError(pluginError("All fields are required in Standard Mode"), mainWindow)
 					return
 				}
+				if err := logic.ValidateLinuxUser(user); err != nil {
+					dialog.ShowError(err, mainWindow)
+					return
+				}
 			}
 
 			// Use blocking progress
 			d.Hide() // Close the input dialog first
 
-			showBlockingProgress("Installing "+fam+" "+ver, mainWindow, func(log func(string)) error {
+			custom, isCustom := customByLabel[ver]
+			isCustom = isCustom && fam == customFamilyLabel
+			title := "Installing " + fam + " " + ver
+			if isCustom {
+				title = "Installing " + ver
+			}
+
+			packagePath := cachedPackage(distroMap[fam], ver)
+			famKey, verKey := catalogKeys(mw.Distros, fam, ver)
+			prov := mw.Settings.Provisioning
+
+			var installErr error
+			mw.showBlockingProgress(title, name, func(ctx context.Context, log func(string)) error {
 				resCh := make(chan error)
-				logic.RunInstallScript(context.Background(), mw.ProjectDir, fam, ver, name, targetPath, user, pass, log, func(e error) {
+				onFinish := func(e error) {
 					resCh <- e
-				})
-				return <-resCh
+				}
+				if isCustom {
+					// URLs are fetched into the cache first so the package can be unpacked locally
+					if logic.CustomPackageSource(custom) == custom.PathOrUrl && logic.IsPackageUrl(custom.PathOrUrl) {
+						if err := logic.DownloadCustomPackage(ctx, mw.ProjectDir, mw.cachePath(), &custom, log); err != nil {
+							installErr = err
+							return err
+						}
+						fyne.DoAndWait(func() { mw.updateCustomCacheRefs([]model.CustomPackage{custom}) })
+					}
+					logic.RunInstallFromSource(ctx, mw.ProjectDir, logic.CustomPackageSource(custom), name, targetPath, user, pass, prov, log, onFinish)
+				} else {
+					// Fetched into the cache first (as the script would) so the package is
+					// unpacked and configured locally before import
+					if packagePath == "" && famKey != "" {
+						if err := logic.DownloadDistroOnly(ctx, mw.ProjectDir, famKey, verKey, log); err != nil {
+							installErr = err
+							return err
+						}
+						if distros, err := mw.Config.LoadDistros(); err == nil {
+							packagePath = distros[famKey].Versions[verKey].LocalPath
+							fyne.DoAndWait(func() { mw.Distros = distros })
+						}
+					}
+					logic.RunInstallScript(ctx, mw.ProjectDir, fam, ver, packagePath, name, targetPath, user, pass, prov, log, onFinish)
+				}
+				installErr = <-resCh
+				return installErr
 			}, func() {
-				dialog.ShowInformation("Success", "Installation complete!", mainWindow)
+				if installErr == nil {
+					if isCustom {
+						logic.TouchCachedPackage(mw.cachePath(), custom.LocalPath)
+					} else {
+						mw.touchCachedVersion(fam, ver)
+					}
+					mw.enforceCacheLimit()
+					dialog.ShowInformation("Success", "Installation complete!", mainWindow)
+				}
 				// Trigger refresh of home list if available
 				if mw.RefreshHomeList != nil {
 					mw.RefreshHomeList()
@@ -221,6 +295,31 @@ func (mw *MainWindow) ShowInstallDialog(preSelectedFamily string, preSelectedVer
 	d.Show()
 }
 
+// cachedPackage returns the LocalPath of the version named versionName
+// catalogKeys finds the distros.json keys of a family and version shown by name
+func catalogKeys(distros map[string]model.DistroConfig, familyName, versionName string) (string, string) {
+	for fk, fam := range distros {
+		if fam.Name != familyName {
+			continue
+		}
+		for vk, v := range fam.Versions {
+			if v.Name == versionName {
+				return fk, vk
+			}
+		}
+	}
+	return "", ""
+}
+
+func cachedPackage(cfg model.DistroConfig, versionName string) string {
+	for _, v := range cfg.Versions {
+		if v.Name == versionName {
+			return v.LocalPath
+		}
+	}
+	return ""
+}
+
 func pluginError(s string) error {
 	return &pErr{s}
 }
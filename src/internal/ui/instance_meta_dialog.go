@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"distronexus-gui/internal/logic"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showInstanceMetaDialog edits the tags, owner and note kept for an instance in instances.json
+func (mw *MainWindow) showInstanceMetaDialog(d logic.WslInstance) {
+	tagsEntry := widget.NewEntry()
+	tagsEntry.SetText(strings.Join(d.Tags, ", "))
+	tagsEntry.SetPlaceHolder("e.g. dev, client-a")
+	ownerEntry := widget.NewEntry()
+	ownerEntry.SetText(d.Owner)
+	ownerEntry.SetPlaceHolder("Person or project responsible")
+	noteEntry := widget.NewMultiLineEntry()
+	noteEntry.SetText(d.Note)
+	noteEntry.Wrapping = fyne.TextWrapWord
+	noteEntry.SetMinRowsVisible(4)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Tags", tagsEntry),
+		widget.NewFormItem("Owner", ownerEntry),
+		widget.NewFormItem("Note", noteEntry),
+	}
+	items[0].HintText = "Comma-separated"
+	if known := mw.knownTags(); len(known) > 0 {
+		items[0].HintText += "; in use: " + strings.Join(known, ", ")
+	}
+
+	dlg := dialog.NewForm("Details of "+d.Name, "Save", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		meta := logic.InstanceMeta{
+			Tags:  logic.ParseTags(tagsEntry.Text),
+			Owner: strings.TrimSpace(ownerEntry.Text),
+			Note:  strings.TrimSpace(noteEntry.Text),
+		}
+		if err := logic.SetInstanceMeta(mw.ProjectDir, d.Name, meta); err != nil {
+			dialog.ShowError(err, mw.Window)
+			return
+		}
+		// The list script would only hand back what was just written, so update in place
+		if inst, known := mw.homeInstances[d.Name]; known {
+			inst.Tags, inst.Owner, inst.Note = meta.Tags, meta.Owner, meta.Note
+			mw.homeInstances[d.Name] = inst
+			mw.updateHomeFilterOptions()
+			mw.renderHomeList()
+		}
+	}, mw.Window)
+	dlg.Resize(fyne.NewSize(500, 380))
+	dlg.Show()
+}
+
+// knownTags lists the tags of all instances, sorted, with case variants merged
+func (mw *MainWindow) knownTags() []string {
+	seen := map[string]bool{}
+	var tags []string
+	for _, d := range mw.homeInstances {
+		for _, t := range d.Tags {
+			if !seen[strings.ToLower(t)] {
+				seen[strings.ToLower(t)] = true
+				tags = append(tags, t)
+			}
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i]) < strings.ToLower(tags[j])
+	})
+	return tags
+}
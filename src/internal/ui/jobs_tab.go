@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"distronexus-gui/internal/logic"
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+func (mw *MainWindow) makeJobsTab() fyne.CanvasObject {
+	listContent := container.NewVBox()
+
+	refreshFunc := func() {
+		listContent.Objects = nil
+
+		jobs := mw.Jobs.Jobs()
+		if len(jobs) == 0 {
+			listContent.Add(widget.NewLabelWithStyle("No operations have run yet.", fyne.TextAlignCenter, fyne.TextStyle{Italic: true}))
+		}
+
+		for _, job := range jobs {
+			listContent.Add(container.NewPadded(mw.createJobItem(job)))
+		}
+		listContent.Refresh()
+	}
+
+	mw.RefreshJobsList = refreshFunc
+	refreshFunc()
+
+	btnRefresh := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), refreshFunc)
+
+	btnCancelAll := widget.NewButtonWithIcon("Cancel All", theme.CancelIcon(), func() {
+		if len(mw.Jobs.Active()) == 0 {
+			return
+		}
+		dialog.ShowConfirm("Cancel All", "Cancel every running operation?", func(ok bool) {
+			if ok {
+				mw.Jobs.CancelAll()
+			}
+		}, mw.Window)
+	})
+	btnCancelAll.Importance = widget.LowImportance
+
+	headerToolbar := container.NewHBox(
+		widget.NewLabelWithStyle("Jobs", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		layout.NewSpacer(),
+		btnCancelAll,
+		btnRefresh,
+	)
+
+	return container.NewBorder(headerToolbar, nil, nil, nil, container.NewVScroll(listContent))
+}
+
+func (mw *MainWindow) createJobItem(job *logic.Job) fyne.CanvasObject {
+	status := job.Status()
+
+	statusIcon := theme.MediaPlayIcon()
+	switch status {
+	case logic.JobSucceeded:
+		statusIcon = theme.ConfirmIcon()
+	case logic.JobFailed:
+		statusIcon = theme.ErrorIcon()
+	case logic.JobCanceled:
+		statusIcon = theme.CancelIcon()
+	}
+
+	title := widget.NewLabelWithStyle(job.Title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+
+	detail := fmt.Sprintf("%s · started %s · %s", status, job.Started.Format("2006-01-02 15:04:05"), job.Duration().Round(time.Second))
+	if p, ok := job.Progress(); ok && status == logic.JobRunning {
+		detail += fmt.Sprintf(" · %s", logic.StageTitle(p.Stage))
+		if p.Percent >= 0 {
+			detail += fmt.Sprintf(" %.0f%%", p.Percent)
+		}
+	}
+	if err := job.Err(); err != nil {
+		detail += "\n" + err.Error()
+	}
+	detailLabel := widget.NewLabel(detail)
+	detailLabel.Wrapping = fyne.TextWrapWord
+
+	btnLogs := widget.NewButtonWithIcon("", theme.DocumentIcon(), func() {
+		mw.showJobLog(job)
+	})
+	btnLogs.Importance = widget.LowImportance
+
+	btnCancel := widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
+		dialog.ShowConfirm("Cancel Operation", fmt.Sprintf("Cancel '%s'?", job.Title), func(ok bool) {
+			if ok {
+				job.Cancel()
+			}
+		}, mw.Window)
+	})
+	btnCancel.Importance = widget.LowImportance
+	if status != logic.JobRunning {
+		btnCancel.Hide()
+	}
+
+	row := container.NewBorder(nil, nil, widget.NewIcon(statusIcon), container.NewHBox(btnLogs, btnCancel),
+		container.NewVBox(title, detailLabel))
+
+	return widget.NewCard("", "", row)
+}
+
+// showJobLog opens a window with the captured output of a job, following new lines while it runs
+func (mw *MainWindow) showJobLog(job *logic.Job) {
+	w := mw.App.NewWindow("Log - " + job.Title)
+
+	logEntry := widget.NewMultiLineEntry()
+	logEntry.Wrapping = fyne.TextWrapBreak
+
+	stop := job.Follow(func(line string) {
+		fyne.Do(func() { logEntry.Append(line) })
+	})
+	w.SetOnClosed(stop)
+
+	w.SetContent(logEntry)
+	w.Resize(fyne.NewSize(700, 450))
+	w.Show()
+}
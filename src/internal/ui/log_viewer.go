@@ -0,0 +1,156 @@
+package ui
+
+import (
+	"distronexus-gui/internal/applog"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	logTailBytes    = 512 * 1024
+	logPollInterval = time.Second
+)
+
+// ShowLogViewer opens a window that tails the app log and the script logs
+func (mw *MainWindow) ShowLogViewer() {
+	w := mw.App.NewWindow("Logs")
+
+	var all, shown []applog.Line
+
+	list := widget.NewList(
+		func() int { return len(shown) },
+		func() fyne.CanvasObject {
+			lbl := widget.NewLabel("")
+			lbl.TextStyle = fyne.TextStyle{Monospace: true}
+			lbl.Truncation = fyne.TextTruncateEllipsis
+			return lbl
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			line := shown[id]
+			lbl := obj.(*widget.Label)
+			switch line.Level {
+			case "ERROR":
+				lbl.Importance = widget.DangerImportance
+			case "WARN":
+				lbl.Importance = widget.WarningImportance
+			case "DEBUG":
+				lbl.Importance = widget.LowImportance
+			default:
+				lbl.Importance = widget.MediumImportance
+			}
+			lbl.SetText(line.Text)
+		},
+	)
+
+	// File picker: show base names, keep full paths for reading
+	files := applog.Files()
+	fileByName := map[string]string{}
+	var names []string
+	for _, f := range files {
+		name := filepath.Base(f)
+		names = append(names, name)
+		fileByName[name] = f
+	}
+	fileSelect := widget.NewSelect(names, nil)
+
+	levelSelect := widget.NewSelect(append([]string{filterAll}, "DEBUG", "INFO", "WARN", "ERROR"), nil)
+	levelSelect.SetSelected(filterAll)
+
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder("Search...")
+
+	followCheck := widget.NewCheck("Follow", nil)
+	followCheck.SetChecked(true)
+
+	statusLabel := widget.NewLabel("")
+
+	applyFilter := func() {
+		text := strings.ToLower(strings.TrimSpace(searchEntry.Text))
+		minRank := -1
+		if levelSelect.Selected != filterAll {
+			minRank = applog.LevelRank(levelSelect.Selected)
+		}
+		shown = shown[:0]
+		for _, l := range all {
+			if minRank >= 0 && applog.LevelRank(l.Level) < minRank {
+				continue
+			}
+			if text != "" && !strings.Contains(strings.ToLower(l.Text), text) {
+				continue
+			}
+			shown = append(shown, l)
+		}
+		statusLabel.SetText(fmt.Sprintf("%d of %d lines", len(shown), len(all)))
+		list.Refresh()
+		if followCheck.Checked && len(shown) > 0 {
+			list.ScrollToBottom()
+		}
+	}
+
+	load := func() {
+		path := fileByName[fileSelect.Selected]
+		if path == "" {
+			all = nil
+			applyFilter()
+			return
+		}
+		lines, err := applog.ReadTail(path, logTailBytes)
+		if err != nil {
+			all = []applog.Line{{Level: "ERROR", Text: err.Error()}}
+		} else {
+			all = lines
+		}
+		applyFilter()
+	}
+
+	fileSelect.OnChanged = func(string) { load() }
+	levelSelect.OnChanged = func(string) { applyFilter() }
+	searchEntry.OnChanged = func(string) { applyFilter() }
+
+	if len(names) > 0 {
+		fileSelect.SetSelected(names[0])
+	} else {
+		statusLabel.SetText("No log files found in " + applog.Dir())
+	}
+
+	// Poll the selected file while following
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(logPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				fyne.Do(func() {
+					if followCheck.Checked {
+						load()
+					}
+				})
+			}
+		}
+	}()
+	w.SetOnClosed(func() { close(stop) })
+
+	btnReload := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), load)
+
+	toolbar := container.NewBorder(nil, nil,
+		container.NewHBox(fileSelect, levelSelect),
+		container.NewHBox(followCheck, btnReload),
+		searchEntry,
+	)
+	footer := container.NewHBox(widget.NewLabel(applog.Dir()), layout.NewSpacer(), statusLabel)
+
+	w.SetContent(container.NewBorder(toolbar, footer, nil, nil, list))
+	w.Resize(fyne.NewSize(900, 550))
+	w.Show()
+}
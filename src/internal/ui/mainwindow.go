@@ -2,13 +2,19 @@ package ui
 
 import (
 	"context"
+	"distronexus-gui/internal/api"
+	"distronexus-gui/internal/applog"
 	"distronexus-gui/internal/config"
+	"distronexus-gui/internal/logic"
 	"distronexus-gui/internal/model"
 	"fmt"
+	"log/slog"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
@@ -32,10 +38,24 @@ type MainWindow struct {
 
 	mainContent *fyne.Container // container for swapping views
 
-	cancelCtx       context.Context
-	cancelFunc      context.CancelFunc
-	isInstalling    bool
+	// Jobs tracks every long-running operation started from the UI
+	Jobs *logic.JobManager
+	// Watcher polls instance run state so cards update without a full rescan
+	Watcher *logic.StateWatcher
+
+	// Cards currently shown on the home view, keyed by instance name
+	homeCards     map[string][]*fyne.Container
+	homeInstances map[string]logic.WslInstance
+	home          homeView
+
+	// API is the local automation server (nil when disabled)
+	API *api.Server
+
+	// tray is set when the driver supports a system tray
+	tray desktop.App
+
 	RefreshHomeList func()
+	RefreshJobsList func()
 }
 
 func NewMainWindow(app fyne.App, projectDir string) *MainWindow {
@@ -44,6 +64,7 @@ func NewMainWindow(app fyne.App, projectDir string) *MainWindow {
 		Window:     app.NewWindow("DistroNexus - The WSL Distro Manager"),
 		ProjectDir: projectDir,
 		Config:     config.NewLoader(projectDir),
+		Jobs:       logic.NewJobManager(),
 	}
 	mw.Window.Resize(fyne.NewSize(900, 650))
 	return mw
@@ -60,7 +81,7 @@ func (mw *MainWindow) Init() {
 	}
 	mw.Settings, err = mw.Config.LoadSettings()
 	if err != nil {
-		fmt.Println("Warning loading settings:", err)
+		slog.Warn("failed to load settings, using defaults", "error", err)
 		// Fallback to defaults if nil
 		mw.Settings = &model.GlobalSettings{
 			DefaultInstallPath: "D:\\WSL",
@@ -76,10 +97,117 @@ func (mw *MainWindow) Init() {
 		}
 	}
 
+	applog.SetLevel(mw.Settings.LogLevel)
+
+	// Keep the Jobs view in sync when operations start or finish
+	mw.Jobs.Subscribe(func(ev logic.JobEvent) {
+		if (ev.Type != logic.JobStarted && ev.Type != logic.JobFinished) || mw.RefreshJobsList == nil {
+			return
+		}
+		fyne.Do(mw.RefreshJobsList)
+	})
+
+	// Let users work elsewhere while long operations run. The settings are read on the UI
+	// goroutine, where the settings dialog replaces them.
+	mw.Jobs.Subscribe(func(ev logic.JobEvent) {
+		if ev.Type == logic.JobFinished {
+			job := ev.Job
+			fyne.Do(func() { mw.notifyJobFinished(job) })
+		}
+	})
+
+	// Closing the window hides it to the tray when enabled, otherwise quits
+	mw.Window.SetCloseIntercept(func() {
+		if mw.Settings.MinimizeToTray && mw.tray != nil {
+			mw.Window.Hide()
+			return
+		}
+		mw.quit()
+	})
+
+	// Pick up instances started or stopped outside the app (or by our own jobs)
+	mw.Watcher = logic.NewStateWatcher(time.Duration(mw.Settings.StatePollSeconds) * time.Second)
+	mw.Watcher.Subscribe(func(ev logic.InstanceStateEvent) {
+		fyne.Do(func() { mw.updateHomeCard(ev) })
+	})
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	mw.Window.SetOnClosed(stopWatch)
+	go mw.Watcher.Run(watchCtx)
+	go logic.RunDiskSampler(watchCtx, mw.ProjectDir, mw.cachePath)
+
 	mw.buildUI()
+	mw.setupTray()
+	mw.applyAPISettings()
 	mw.Window.Show()
 }
 
+// applyAPISettings starts, restarts or stops the local API to match the settings
+func (mw *MainWindow) applyAPISettings() {
+	want := ""
+	if mw.Settings.ApiEnabled {
+		want = mw.Settings.ApiListen
+		if want == "" {
+			want = config.DefaultApiListen
+		}
+	}
+	if mw.API != nil {
+		if mw.API.Addr() == want {
+			mw.API.SetMetricsEnabled(mw.Settings.MetricsEnabled)
+			return
+		}
+		if err := mw.API.Close(); err != nil {
+			slog.Warn("failed to stop local API", "error", err)
+		}
+		mw.API = nil
+	}
+	if want == "" {
+		return
+	}
+
+	token, err := mw.Config.LoadAPIToken()
+	if err != nil {
+		dialog.ShowError(err, mw.Window)
+		return
+	}
+	srv := api.NewServer(mw.ProjectDir, mw.Jobs, mw.Config, token)
+	srv.Watcher = mw.Watcher
+	srv.SetMetricsEnabled(mw.Settings.MetricsEnabled)
+	srv.OnChange = func() {
+		mw.Watcher.Trigger()
+		if mw.RefreshHomeList != nil {
+			fyne.Do(mw.RefreshHomeList)
+		}
+	}
+	if err := srv.Start(want); err != nil {
+		dialog.ShowError(err, mw.Window)
+		return
+	}
+	mw.API = srv
+}
+
+// cachePath returns the absolute package cache directory from the current settings
+func (mw *MainWindow) cachePath() string {
+	return mw.Config.ResolveCachePath(mw.Settings.DistroCachePath)
+}
+
+// quit exits the app, but doesn't leave orphaned PowerShell/wsl processes behind
+func (mw *MainWindow) quit() {
+	active := mw.Jobs.Active()
+	if len(active) == 0 {
+		mw.App.Quit()
+		return
+	}
+	mw.showFromTray()
+	dialog.ShowConfirm("Operations Running",
+		fmt.Sprintf("%d operation(s) are still running. Cancel them and quit?", len(active)),
+		func(ok bool) {
+			if ok {
+				mw.Jobs.CancelAll()
+				mw.App.Quit()
+			}
+		}, mw.Window)
+}
+
 func (mw *MainWindow) buildUI() {
 	// Root Container
 	mw.mainContent = container.NewStack()
@@ -101,11 +229,34 @@ func (mw *MainWindow) buildUI() {
 		mw.mainContent.Refresh()
 	})
 
+	btnJobs := widget.NewButtonWithIcon("", theme.ListIcon(), func() {
+		mw.mainContent.Objects = []fyne.CanvasObject{mw.makeJobsTab()}
+		mw.mainContent.Refresh()
+	})
+
+	btnDisk := widget.NewButtonWithIcon("", theme.GridIcon(), func() {
+		mw.mainContent.Objects = []fyne.CanvasObject{mw.makeDiskTab()}
+		mw.mainContent.Refresh()
+	})
+
+	btnHistory := widget.NewButtonWithIcon("", theme.HistoryIcon(), func() {
+		mw.mainContent.Objects = []fyne.CanvasObject{mw.makeHistoryTab()}
+		mw.mainContent.Refresh()
+	})
+
+	btnLogs := widget.NewButtonWithIcon("", theme.FileTextIcon(), func() {
+		mw.ShowLogViewer()
+	})
+
 	btnInstall := widget.NewButtonWithIcon("", theme.ContentAddIcon(), func() {
 		mw.ShowInstallDialog("", "")
 	})
 	btnInstall.Importance = widget.HighImportance
 
+	btnImportImage := widget.NewButtonWithIcon("", theme.UploadIcon(), func() {
+		mw.showImportImageDialog()
+	})
+
 	btnSettings := widget.NewButtonWithIcon("", theme.SettingsIcon(), func() {
 		mw.ShowSettingsDialog()
 	})
@@ -113,8 +264,13 @@ func (mw *MainWindow) buildUI() {
 	toolbar := container.NewHBox(
 		btnHome,
 		btnPackages,
+		btnJobs,
+		btnDisk,
+		btnHistory,
 		layout.NewSpacer(),
 		btnInstall,
+		btnImportImage,
+		btnLogs,
 		btnSettings,
 	)
 
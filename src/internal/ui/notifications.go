@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"distronexus-gui/internal/logic"
+	"distronexus-gui/internal/model"
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// defaultNotifications applies when settings.json has no Notifications block.
+// Quick operations (a start/stop takes a second or two) stay silent.
+var defaultNotifications = model.NotificationSettings{
+	OnSuccess:      true,
+	OnFailure:      true,
+	OnCancel:       false,
+	MinDurationSec: 10,
+}
+
+func (mw *MainWindow) notificationPrefs() model.NotificationSettings {
+	if mw.Settings.Notifications == nil {
+		return defaultNotifications
+	}
+	return *mw.Settings.Notifications
+}
+
+// notifyJobFinished raises a desktop notification for a finished job if the settings ask for it.
+// It runs on the UI goroutine since it reads mw.Settings.
+func (mw *MainWindow) notifyJobFinished(job *logic.Job) {
+	prefs := mw.notificationPrefs()
+	duration := job.Duration().Round(time.Second)
+	if duration < time.Duration(prefs.MinDurationSec)*time.Second {
+		return
+	}
+
+	subject := strings.TrimSuffix(job.Title, "...")
+	if job.Instance != "" && !strings.Contains(subject, job.Instance) {
+		subject = fmt.Sprintf("%s (%s)", subject, job.Instance)
+	}
+
+	var title, content string
+	switch job.Status() {
+	case logic.JobSucceeded:
+		if !prefs.OnSuccess {
+			return
+		}
+		title = "Operation completed"
+		content = fmt.Sprintf("%s finished in %s.", subject, duration)
+	case logic.JobFailed:
+		if !prefs.OnFailure {
+			return
+		}
+		title = "Operation failed"
+		content = fmt.Sprintf("%s failed after %s: %v", subject, duration, job.Err())
+	case logic.JobCanceled:
+		if !prefs.OnCancel {
+			return
+		}
+		title = "Operation canceled"
+		content = fmt.Sprintf("%s was canceled after %s.", subject, duration)
+	default:
+		return
+	}
+
+	mw.App.SendNotification(fyne.NewNotification(title, content))
+}
@@ -0,0 +1,128 @@
+package ui
+
+import (
+	"context"
+	"distronexus-gui/internal/logic"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// findOrphans scans for leftover folders and registry entries and lists them with actions
+func (mw *MainWindow) findOrphans() {
+	var orphans []logic.Orphan
+	var scanErr error
+	installRoot := mw.Settings.DefaultInstallPath
+	mw.showBlockingProgress("Looking for orphaned instances...", "", func(ctx context.Context, log func(string)) error {
+		orphans, scanErr = logic.FindOrphans(ctx, mw.ProjectDir, installRoot)
+		return scanErr
+	}, func() {
+		if scanErr != nil {
+			return
+		}
+		fyne.Do(func() { mw.showOrphansDialog(orphans) })
+	})
+}
+
+func (mw *MainWindow) showOrphansDialog(orphans []logic.Orphan) {
+	if len(orphans) == 0 {
+		dialog.ShowInformation("Orphans", "No orphaned folders or registry entries found.", mw.Window)
+		return
+	}
+
+	var dlg dialog.Dialog
+	// Every action closes the list and scans again so it reflects the new state
+	after := func() {
+		mw.RefreshHomeList()
+		fyne.Do(mw.findOrphans)
+	}
+
+	var total int64
+	rows := container.NewVBox()
+	for _, o := range orphans {
+		total += o.Bytes
+
+		var title, detail string
+		var actions []fyne.CanvasObject
+		switch o.Kind {
+		case logic.OrphanFolder:
+			title = fmt.Sprintf("Unregistered folder (%s)", logic.FormatBytes(o.Bytes))
+			detail = o.Path
+			actions = append(actions,
+				widget.NewButtonWithIcon("Re-register", theme.ContentRedoIcon(), func() {
+					dlg.Hide()
+					mw.reRegisterOrphan(o, after)
+				}),
+				widget.NewButtonWithIcon("Delete Folder", theme.DeleteIcon(), func() {
+					dlg.Hide()
+					dialog.ShowConfirm("Delete Folder", fmt.Sprintf("Permanently delete %s (%s)?", o.Path, logic.FormatBytes(o.Bytes)), func(ok bool) {
+						if !ok {
+							return
+						}
+						mw.showBlockingProgress("Deleting "+o.Path+"...", "", func(ctx context.Context, log func(string)) error {
+							return logic.DeleteDistroFiles(mw.ProjectDir, o.Path)
+						}, after)
+					}, mw.Window)
+				}),
+			)
+		case logic.OrphanRegistry:
+			title = fmt.Sprintf("Registry entry '%s' without files", o.Name)
+			detail = o.Path
+			actions = append(actions,
+				widget.NewButtonWithIcon("Clean Entry", theme.ContentClearIcon(), func() {
+					dlg.Hide()
+					dialog.ShowConfirm("Clean Registry Entry", fmt.Sprintf("Remove the WSL registration '%s' (%s)?", o.Name, o.RegistryId), func(ok bool) {
+						if !ok {
+							return
+						}
+						mw.showBlockingProgress("Cleaning "+o.Name+"...", "", func(ctx context.Context, log func(string)) error {
+							return logic.RemoveOrphanRegistryEntry(ctx, mw.ProjectDir, o, log)
+						}, after)
+					}, mw.Window)
+				}),
+			)
+		}
+		if o.InCache {
+			detail += " · listed in instances.json"
+		}
+
+		info := container.NewVBox(
+			widget.NewLabelWithStyle(title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabel(detail),
+		)
+		rows.Add(widget.NewCard("", "", container.NewBorder(nil, nil, nil, container.NewHBox(actions...), info)))
+	}
+
+	header := widget.NewLabel(fmt.Sprintf("%d orphan(s), %s on disk", len(orphans), logic.FormatBytes(total)))
+	content := container.NewBorder(header, nil, nil, nil, container.NewVScroll(rows))
+	dlg = dialog.NewCustom("Orphans", "Close", content, mw.Window)
+	dlg.Resize(fyne.NewSize(760, 480))
+	dlg.Show()
+}
+
+// reRegisterOrphan asks for a name and imports the folder's disk in place
+func (mw *MainWindow) reRegisterOrphan(o logic.Orphan, onDone func()) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(o.Name)
+	nameEntry.Validator = logic.ValidateDistroName
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Folder", widget.NewLabel(o.Path)),
+		widget.NewFormItem("Name", nameEntry),
+	}
+	dlg := dialog.NewForm("Re-register Instance", "Register", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		name := nameEntry.Text
+		mw.showBlockingProgress("Registering "+name+"...", name, func(ctx context.Context, log func(string)) error {
+			return logic.ReRegisterOrphan(ctx, mw.ProjectDir, name, o.Path, log)
+		}, onDone)
+	}, mw.Window)
+	dlg.Resize(fyne.NewSize(500, 220))
+	dlg.Show()
+}
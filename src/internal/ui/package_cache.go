@@ -0,0 +1,208 @@
+package ui
+
+import (
+	"context"
+	"distronexus-gui/internal/logic"
+	"distronexus-gui/internal/model"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+const bytesPerGB = 1024 * 1024 * 1024
+
+// cacheLimitBytes returns the configured cache limit (0 = unlimited)
+func (mw *MainWindow) cacheLimitBytes() int64 {
+	return int64(mw.Settings.CacheLimitGB) * bytesPerGB
+}
+
+// enforceCacheLimit evicts least recently used packages when the cache is over its limit.
+// It runs off the UI goroutine and reloads the catalog itself.
+func (mw *MainWindow) enforceCacheLimit() {
+	limit := mw.cacheLimitBytes()
+	if limit <= 0 {
+		return
+	}
+	distros, err := mw.Config.LoadDistros()
+	if err != nil {
+		slog.Warn("cache limit: cannot load catalog", "error", err)
+		return
+	}
+	before := append([]model.CustomPackage(nil), mw.Settings.CustomPackages...)
+	custom := append([]model.CustomPackage(nil), before...)
+	evicted, err := logic.EnforceCacheLimit(mw.cachePath(), distros, custom, limit)
+	if err != nil {
+		slog.Warn("cache limit: eviction failed", "error", err)
+	}
+	if len(evicted) == 0 {
+		return
+	}
+	for _, pkg := range evicted {
+		slog.Info("evicted cached package", "file", pkg.Path, "size", pkg.Size)
+	}
+	if err := mw.Config.SaveDistros(distros); err != nil {
+		slog.Warn("cache limit: cannot save catalog", "error", err)
+		return
+	}
+	fyne.Do(func() {
+		mw.Distros = distros
+		mw.updateCustomCacheRefs(unlinkedCustom(before, custom))
+	})
+}
+
+// unlinkedCustom returns the custom packages whose cache reference was dropped between
+// the before and after copies
+func unlinkedCustom(before, after []model.CustomPackage) []model.CustomPackage {
+	var dropped []model.CustomPackage
+	for i := range after {
+		if before[i].Sha256 != "" && after[i].Sha256 == "" {
+			dropped = append(dropped, after[i])
+		}
+	}
+	return dropped
+}
+
+// updateCustomCacheRefs copies the cache references (LocalPath, Sha256) of custom back into
+// the settings and saves them. Packages are matched by name, version and source since the
+// list may have been edited meanwhile. Must run on the UI goroutine.
+func (mw *MainWindow) updateCustomCacheRefs(custom []model.CustomPackage) {
+	changed := false
+	for i, cp := range mw.Settings.CustomPackages {
+		for _, c := range custom {
+			if c.Name != cp.Name || c.Version != cp.Version || c.PathOrUrl != cp.PathOrUrl {
+				continue
+			}
+			if c.LocalPath != cp.LocalPath || c.Sha256 != cp.Sha256 {
+				mw.Settings.CustomPackages[i].LocalPath = c.LocalPath
+				mw.Settings.CustomPackages[i].Sha256 = c.Sha256
+				changed = true
+			}
+			break
+		}
+	}
+	if !changed {
+		return
+	}
+	if err := mw.Config.SaveSettings(mw.Settings); err != nil {
+		slog.Warn("cannot save custom package cache references", "error", err)
+	}
+}
+
+// touchCachedVersion records that a catalog version's package was just used (LRU order)
+func (mw *MainWindow) touchCachedVersion(familyName, versionName string) {
+	distros, err := mw.Config.LoadDistros()
+	if err != nil {
+		return
+	}
+	for _, fam := range distros {
+		if fam.Name != familyName {
+			continue
+		}
+		for _, ver := range fam.Versions {
+			if ver.Name == versionName && ver.LocalPath != "" {
+				if err := logic.TouchCachedPackage(mw.cachePath(), ver.LocalPath); err != nil {
+					slog.Debug("could not update cache use time", "error", err)
+				}
+				return
+			}
+		}
+	}
+}
+
+// collectCacheGarbage removes unreferenced and superseded packages and applies the size limit
+func (mw *MainWindow) collectCacheGarbage(onDone func()) {
+	var res logic.CacheGCResult
+	var gcErr error
+	before := append([]model.CustomPackage(nil), mw.Settings.CustomPackages...)
+	custom := append([]model.CustomPackage(nil), before...)
+	mw.showBlockingProgress("Cleaning up package cache...", "", func(ctx context.Context, log func(string)) error {
+		distros, err := mw.Config.LoadDistros()
+		if err != nil {
+			gcErr = err
+			return err
+		}
+		res, gcErr = logic.CollectCacheGarbage(mw.cachePath(), distros, custom, mw.cacheLimitBytes())
+		// Save even on error: references dropped before the failure must not dangle
+		if err := mw.Config.SaveDistros(distros); err != nil && gcErr == nil {
+			gcErr = err
+		}
+		fyne.Do(func() {
+			mw.Distros = distros
+			mw.updateCustomCacheRefs(unlinkedCustom(before, custom))
+		})
+		for _, pkg := range res.Removed {
+			log(fmt.Sprintf("Removed %s (%s)\n", pkg.Path, logic.FormatBytes(pkg.Size)))
+		}
+		return gcErr
+	}, func() {
+		if gcErr == nil {
+			fyne.Do(func() {
+				msg := fmt.Sprintf("Removed %d package(s), freed %s.", len(res.Removed), logic.FormatBytes(res.FreedBytes))
+				if res.Migrated > 0 {
+					msg += fmt.Sprintf("\nMoved %d file(s) from the old folder layout into the store.", res.Migrated)
+				}
+				if len(res.Unlinked) > 0 {
+					msg += "\n\nNo longer cached (download again when needed):\n" + strings.Join(res.Unlinked, "\n")
+				}
+				dialog.ShowInformation("Cache Clean Up", msg, mw.Window)
+			})
+		}
+		if onDone != nil {
+			onDone()
+		}
+	})
+}
+
+// verifyPackages checks the cached files of versions and records corrupt ones: in the
+// cache index for the content-addressed store, in sessionCorrupt (by path) otherwise
+func (mw *MainWindow) verifyPackages(versions []model.Version, sessionCorrupt map[string]string, onDone func()) {
+	cacheDir := mw.cachePath()
+	var bad int
+	var verifyErr error
+	mw.showBlockingProgress("Verifying packages...", "", func(ctx context.Context, log func(string)) error {
+		for i, ver := range versions {
+			log(fmt.Sprintf("[%d/%d] %s\n", i+1, len(versions), ver.Name))
+			check, err := logic.VerifyCachedPackage(ctx, ver.LocalPath, ver.Sha256, ver.Url, log)
+			if err != nil {
+				verifyErr = err
+				return err
+			}
+			for _, note := range check.Notes {
+				log("  note: " + note + "\n")
+			}
+			problem := strings.Join(check.Problems, "; ")
+			if problem != "" {
+				bad++
+			}
+			if err := logic.MarkCachedPackage(cacheDir, ver.LocalPath, problem); err != nil {
+				log(fmt.Sprintf("  could not record result: %v\n", err))
+			}
+			fyne.Do(func() {
+				if problem != "" {
+					sessionCorrupt[ver.LocalPath] = problem
+				} else {
+					delete(sessionCorrupt, ver.LocalPath)
+				}
+			})
+		}
+		return nil
+	}, func() {
+		fyne.Do(func() {
+			if verifyErr != nil {
+				return
+			}
+			if bad > 0 {
+				dialog.ShowInformation("Verify Packages", fmt.Sprintf("%d of %d package(s) are corrupt. Use Re-download to fetch them again.", bad, len(versions)), mw.Window)
+			} else {
+				dialog.ShowInformation("Verify Packages", fmt.Sprintf("All %d package(s) are intact.", len(versions)), mw.Window)
+			}
+		})
+		if onDone != nil {
+			// After the sessionCorrupt updates queued above
+			fyne.Do(onDone)
+		}
+	})
+}
@@ -6,7 +6,9 @@ import (
 	"distronexus-gui/internal/model"
 	"fmt"
 	"os"
+	"slices"
 	"sort"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -28,12 +30,37 @@ func (mw *MainWindow) makePackageTab() fyne.CanvasObject {
 	// or be called by buttons.
 	var refreshFunc func()
 
+	// Downloads may push the cache over its limit
+	afterDownload := func() {
+		mw.enforceCacheLimit()
+		refreshFunc()
+	}
+
+	// Verification results for files outside the content-addressed store (by path)
+	sessionCorrupt := make(map[string]string)
+
+	// Search, filter and sort state of the list
+	var query, cacheFilter, sourceFilter string
+	sortBy, descending := logic.SortByName, false
+	var sourceSelect *widget.Select
+
+	var corrupt map[string]string
+	var renderFunc func()
 	refreshFunc = func() {
 		// Reload Distros to get latest LocalPaths
 		if d, err := mw.Config.LoadDistros(); err == nil {
 			mw.Distros = d
 		}
+		corrupt, _ = logic.CorruptPackages(mw.cachePath())
+		sourceSelect.Options = sourceOptions(mw.Distros, mw.Settings.CustomPackages)
+		if !slices.Contains(sourceSelect.Options, sourceSelect.Selected) {
+			sourceSelect.Selected, sourceFilter = allSources, ""
+		}
+		sourceSelect.Refresh()
+		renderFunc()
+	}
 
+	renderFunc = func() {
 		listContent.Objects = nil // Clear
 
 		// 1. Prepare Data
@@ -41,40 +68,128 @@ func (mw *MainWindow) makePackageTab() fyne.CanvasObject {
 		for k := range mw.Distros {
 			families = append(families, k)
 		}
-		sort.Strings(families)
+		sort.Slice(families, func(i, j int) bool {
+			return strings.ToLower(mw.Distros[families[i]].Name) < strings.ToLower(mw.Distros[families[j]].Name)
+		})
 
 		// Helper to check status (relies on LocalPath now)
-		isCached := func(localPath string) (bool, string) {
+		cacheInfo := func(localPath string) os.FileInfo {
 			if localPath == "" {
-				return false, ""
+				return nil
 			}
 			info, err := os.Stat(localPath)
 			if err == nil && !info.IsDir() {
+				return info
+			}
+			return nil
+		}
+		isCached := func(localPath string) (bool, string) {
+			if info := cacheInfo(localPath); info != nil {
 				sizeMB := float64(info.Size()) / 1024.0 / 1024.0
 				return true, fmt.Sprintf("%.1f MB", sizeMB)
 			}
 			return false, ""
 		}
+		// Package filter: search text, cache state and source
+		shown := func(localPath, source string, fields ...string) bool {
+			if sourceFilter != "" && source != sourceFilter {
+				return false
+			}
+			cached := cacheInfo(localPath) != nil
+			if (cacheFilter == pkgCached && !cached) || (cacheFilter == pkgNotCached && cached) {
+				return false
+			}
+			return logic.MatchQuery(query, append(fields, source)...)
+		}
+		// Order by name, cached size or download date; uncached packages count as empty
+		less := func(nameA, pathA, nameB, pathB string) bool {
+			a, b := cacheInfo(pathA), cacheInfo(pathB)
+			var ka, kb int64
+			switch sortBy {
+			case logic.SortBySize:
+				if a != nil {
+					ka = a.Size()
+				}
+				if b != nil {
+					kb = b.Size()
+				}
+			case sortByCachedDate:
+				if a != nil {
+					ka = a.ModTime().Unix()
+				}
+				if b != nil {
+					kb = b.ModTime().Unix()
+				}
+			}
+			if ka == kb {
+				ka, kb = 0, 0
+				if c := strings.Compare(strings.ToLower(nameA), strings.ToLower(nameB)); c != 0 {
+					return (c < 0) != descending
+				}
+				return false
+			}
+			return (ka < kb) != descending
+		}
+		matches := 0
+
+		// Problem recorded by the last verification; an empty file is always broken
+		corruptReason := func(ver model.Version) string {
+			if reason := corrupt[ver.Sha256]; ver.Sha256 != "" && reason != "" {
+				return reason
+			}
+			if reason := sessionCorrupt[ver.LocalPath]; reason != "" {
+				return reason
+			}
+			if info, err := os.Stat(ver.LocalPath); err == nil && info.Size() == 0 {
+				return "file is empty"
+			}
+			return ""
+		}
+
+		// Versions sharing one cached file (same content hash)
+		shareCount := make(map[string]int)
+		for _, dCfg := range mw.Distros {
+			for _, ver := range dCfg.Versions {
+				if ver.Sha256 != "" && ver.LocalPath != "" {
+					shareCount[ver.Sha256]++
+				}
+			}
+		}
 
 		// 2. Iterate
 		for _, fam := range families {
 			fam := fam // Capture for closure
 			dCfg := mw.Distros[fam]
-			listContent.Add(widget.NewLabelWithStyle(dCfg.Name, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
 
-			// Sort versions
+			// Filter and sort versions
 			var vKeys []string
-			if dCfg.Versions != nil {
-				for k := range dCfg.Versions {
+			for k, ver := range dCfg.Versions {
+				source := ver.Source
+				if source == "" {
+					source = "Official"
+				}
+				if shown(ver.LocalPath, source, dCfg.Name, ver.Name, ver.Filename) {
 					vKeys = append(vKeys, k)
 				}
-				sort.Strings(vKeys)
 			}
+			if len(vKeys) == 0 {
+				continue
+			}
+			sort.Slice(vKeys, func(i, j int) bool {
+				a, b := dCfg.Versions[vKeys[i]], dCfg.Versions[vKeys[j]]
+				return less(a.Name, a.LocalPath, b.Name, b.LocalPath)
+			})
+			matches += len(vKeys)
+			listContent.Add(widget.NewLabelWithStyle(dCfg.Name, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
 
 			for _, vKey := range vKeys {
 				vKey := vKey // Capture
 				ver := dCfg.Versions[vKey]
 				cached, sizeStr := isCached(ver.LocalPath)
+				broken := ""
+				if cached {
+					broken = corruptReason(ver)
+				}
 
 				nameLabel := widget.NewLabel(ver.Name)
 
@@ -87,13 +202,39 @@ func (mw *MainWindow) makePackageTab() fyne.CanvasObject {
 				statusIcon := theme.DownloadIcon()
 				if cached {
 					statusTxt += " | Cached (" + sizeStr + ")"
+					if n := shareCount[ver.Sha256]; n > 1 {
+						statusTxt += fmt.Sprintf(" | Shared by %d", n)
+					}
 					statusIcon = theme.FileIcon()
 				}
+				if broken != "" {
+					statusTxt = sourceTxt + " | Corrupt: " + broken
+					statusIcon = theme.ErrorIcon()
+				}
 				statusLabel := widget.NewLabelWithStyle(statusTxt, fyne.TextAlignTrailing, fyne.TextStyle{Italic: true})
+				if broken != "" {
+					statusLabel.Importance = widget.DangerImportance
+				}
 
 				var actionContainer *fyne.Container
 
-				if cached {
+				if broken != "" {
+					// One click: drop the bad file (and every entry sharing it) and fetch it again
+					btnFix := widget.NewButtonWithIcon("Re-download", theme.DownloadIcon(), func() {
+						if err := logic.DiscardCachedPackage(mw.cachePath(), mw.Distros, mw.Settings.CustomPackages, fam, vKey); err != nil {
+							dialog.ShowError(err, mw.Window)
+							return
+						}
+						delete(sessionCorrupt, ver.LocalPath)
+						mw.Config.SaveDistros(mw.Distros)
+						mw.Config.SaveSettings(mw.Settings)
+						mw.showBlockingProgress("Downloading "+ver.Name+"...", "", func(ctx context.Context, log func(string)) error {
+							return logic.DownloadDistroOnly(ctx, mw.ProjectDir, fam, vKey, log)
+						}, afterDownload)
+					})
+					btnFix.Importance = widget.HighImportance
+					actionContainer = container.NewHBox(btnFix)
+				} else if cached {
 					btnInstall := widget.NewButtonWithIcon("Install", theme.ContentAddIcon(), func() {
 						// Open standard install dialog pre-filled
 						// We convert ID to Name if needed, but here we used 'fam' which is the key (Distro Name usually)
@@ -106,13 +247,16 @@ func (mw *MainWindow) makePackageTab() fyne.CanvasObject {
 					btnInstall.Importance = widget.LowImportance
 
 					btnDelete := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
-						dialog.ShowConfirm("Delete Cache", "Remove file "+ver.Filename+"?", func(ok bool) {
+						msg := "Remove file " + ver.Filename + "?"
+						if shareCount[ver.Sha256] > 1 {
+							msg = "Other entries use the same file; only this entry's reference is removed. Continue?"
+						}
+						dialog.ShowConfirm("Delete Cache", msg, func(ok bool) {
 							if ok {
-								os.Remove(ver.LocalPath)
-								// Update Config
-								ver.LocalPath = ""
-								dCfg.Versions[vKey] = ver
-								mw.Distros[fam] = dCfg
+								// The file is only deleted once no other catalog entry references it
+								if _, err := logic.ReleaseCachedPackage(mw.cachePath(), mw.Distros, mw.Settings.CustomPackages, fam, vKey); err != nil {
+									dialog.ShowError(err, mw.Window)
+								}
 								mw.Config.SaveDistros(mw.Distros)
 								refreshFunc()
 							}
@@ -123,21 +267,30 @@ func (mw *MainWindow) makePackageTab() fyne.CanvasObject {
 					btnRedownload := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() {
 						dialog.ShowConfirm("Redownload", "Replace existing file?", func(ok bool) {
 							if ok {
-								os.Remove(ver.LocalPath)
-								showBlockingProgress("Downloading "+ver.Name+"...", mw.Window, func(log func(string)) error {
-									return logic.DownloadDistroOnly(context.Background(), mw.ProjectDir, fam, vKey, log)
-								}, refreshFunc)
+								if _, err := logic.ReleaseCachedPackage(mw.cachePath(), mw.Distros, mw.Settings.CustomPackages, fam, vKey); err != nil {
+									dialog.ShowError(err, mw.Window)
+									return
+								}
+								mw.Config.SaveDistros(mw.Distros)
+								mw.showBlockingProgress("Downloading "+ver.Name+"...", "", func(ctx context.Context, log func(string)) error {
+									return logic.DownloadDistroOnly(ctx, mw.ProjectDir, fam, vKey, log)
+								}, afterDownload)
 							}
 						}, mw.Window)
 					})
 					btnRedownload.Importance = widget.LowImportance
 
-					actionContainer = container.NewHBox(btnInstall, btnRedownload, btnDelete)
+					btnVerify := widget.NewButtonWithIcon("", theme.ConfirmIcon(), func() {
+						mw.verifyPackages([]model.Version{ver}, sessionCorrupt, refreshFunc)
+					})
+					btnVerify.Importance = widget.LowImportance
+
+					actionContainer = container.NewHBox(btnInstall, btnVerify, btnRedownload, btnDelete)
 				} else {
 					btnDownload := widget.NewButtonWithIcon("", theme.DownloadIcon(), func() {
-						showBlockingProgress("Downloading "+ver.Name+"...", mw.Window, func(log func(string)) error {
-							return logic.DownloadDistroOnly(context.Background(), mw.ProjectDir, fam, vKey, log)
-						}, refreshFunc)
+						mw.showBlockingProgress("Downloading "+ver.Name+"...", "", func(ctx context.Context, log func(string)) error {
+							return logic.DownloadDistroOnly(ctx, mw.ProjectDir, fam, vKey, log)
+						}, afterDownload)
 					})
 					btnDownload.Importance = widget.LowImportance
 					actionContainer = container.NewHBox(btnDownload)
@@ -160,45 +313,121 @@ func (mw *MainWindow) makePackageTab() fyne.CanvasObject {
 		}
 
 		// 3. Custom
-		if len(mw.Settings.CustomPackages) > 0 {
+		var customShown []int
+		for i, cp := range mw.Settings.CustomPackages {
+			if shown(cp.LocalPath, pkgSourceCustom, cp.Name, cp.Version, cp.PathOrUrl) {
+				customShown = append(customShown, i)
+			}
+		}
+		sort.SliceStable(customShown, func(i, j int) bool {
+			a, b := mw.Settings.CustomPackages[customShown[i]], mw.Settings.CustomPackages[customShown[j]]
+			return less(customPackageLabel(a), a.LocalPath, customPackageLabel(b), b.LocalPath)
+		})
+		matches += len(customShown)
+		if len(customShown) > 0 {
 			listContent.Add(widget.NewLabelWithStyle("Custom Sources", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
-			for _, cp := range mw.Settings.CustomPackages {
-				delBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
-					// Remove from slice logic needed
-					var newSlice []model.CustomPackage
-					for _, item := range mw.Settings.CustomPackages {
-						if item.Name != cp.Name {
-							newSlice = append(newSlice, item)
-						}
-					}
-					mw.Settings.CustomPackages = newSlice
-					mw.Config.SaveSettings(mw.Settings)
-					refreshFunc()
+			for _, i := range customShown {
+				i, cp := i, mw.Settings.CustomPackages[i]
+				isUrl := logic.IsPackageUrl(cp.PathOrUrl)
+				cached, sizeStr := isCached(cp.LocalPath)
+
+				statusTxt := "User | Local file"
+				statusIcon := theme.FileIcon()
+				if isUrl {
+					statusTxt = "User | URL"
+					statusIcon = theme.DownloadIcon()
+				}
+				if cached {
+					statusTxt = "User | Cached (" + sizeStr + ")"
+					statusIcon = theme.FileIcon()
+				}
+
+				btnInstall := widget.NewButtonWithIcon("Install", theme.ContentAddIcon(), func() {
+					mw.ShowInstallDialog(customFamilyLabel, customPackageLabel(cp))
 				})
+				btnInstall.Importance = widget.LowImportance
+
+				actions := container.NewHBox(btnInstall)
+				if isUrl && !cached {
+					btnDownload := widget.NewButtonWithIcon("", theme.DownloadIcon(), func() {
+						mw.downloadCustomPackage(cp, refreshFunc)
+					})
+					btnDownload.Importance = widget.LowImportance
+					actions.Add(btnDownload)
+				}
+
+				btnEdit := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() {
+					mw.showCustomPackageDialog(i, refreshFunc)
+				})
+				btnEdit.Importance = widget.LowImportance
+
+				btnDelete := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+					mw.deleteCustomPackage(i, refreshFunc)
+				})
+				btnDelete.Importance = widget.LowImportance
+				actions.Add(btnEdit)
+				actions.Add(btnDelete)
 
 				row := container.NewHBox(
-					widget.NewIcon(theme.FileIcon()),
-					widget.NewLabel(cp.Name+" "+cp.Version),
+					widget.NewIcon(statusIcon),
+					widget.NewLabel(customPackageLabel(cp)),
 					layout.NewSpacer(),
-					widget.NewLabelWithStyle("User", fyne.TextAlignTrailing, fyne.TextStyle{Italic: true}),
-					delBtn,
+					widget.NewLabelWithStyle(statusTxt, fyne.TextAlignTrailing, fyne.TextStyle{Italic: true}),
+					actions,
 				)
-				listContent.Add(row)
+				listContent.Add(container.NewPadded(widget.NewCard("", "", container.NewPadded(row))))
 			}
 		}
+		if matches == 0 {
+			listContent.Add(container.NewCenter(widget.NewLabel("No packages match the filter")))
+		}
 		listContent.Refresh()
 	}
 
+	search := widget.NewEntry()
+	search.SetPlaceHolder("Search family, version, file or source")
+	search.OnChanged = func(text string) {
+		query = text
+		renderFunc()
+	}
+	cacheSelect := widget.NewSelect([]string{allPackages, pkgCached, pkgNotCached}, func(s string) {
+		cacheFilter = filterValue(s, allPackages)
+		renderFunc()
+	})
+	sourceSelect = widget.NewSelect([]string{allSources}, func(s string) {
+		sourceFilter = filterValue(s, allSources)
+		renderFunc()
+	})
+	sortSelect := widget.NewSelect([]string{logic.SortByName, logic.SortBySize, sortByCachedDate}, func(s string) {
+		sortBy = s
+		renderFunc()
+	})
+	var btnOrder *widget.Button
+	btnOrder = widget.NewButtonWithIcon("", theme.MoveDownIcon(), func() {
+		descending = !descending
+		if descending {
+			btnOrder.SetIcon(theme.MoveUpIcon())
+		} else {
+			btnOrder.SetIcon(theme.MoveDownIcon())
+		}
+		renderFunc()
+	})
+	btnOrder.Importance = widget.LowImportance
+
+	// Initial choices, set without rendering through the callbacks
+	cacheSelect.Selected = allPackages
+	sourceSelect.Selected = allSources
+	sortSelect.Selected = logic.SortByName
 	refreshFunc()
 
 	btnRefreshList := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), refreshFunc)
 
 	// Update Sources Icon: Using SearchReplaceIcon (magnifier with arrows) to imply "Checking/Syncing updates"
 	btnUpdateSources := widget.NewButtonWithIcon("", theme.SearchReplaceIcon(), func() {
-		showBlockingProgress("Updating Sources...", mw.Window, func(log func(string)) error {
+		mw.showBlockingProgress("Updating Sources...", "", func(ctx context.Context, log func(string)) error {
 			srcUrl := mw.Settings.DistroSourceUrl
 			log("Fetching distribution info from source...\n")
-			return logic.UpdateDistroList(context.Background(), mw.ProjectDir, srcUrl, log)
+			return logic.UpdateDistroList(ctx, mw.ProjectDir, srcUrl, log)
 		}, func() {
 			// Reload distros in memory
 			d, err := mw.Config.LoadDistros()
@@ -215,7 +444,7 @@ func (mw *MainWindow) makePackageTab() fyne.CanvasObject {
 	btnDownloadAll := widget.NewButtonWithIcon("", theme.DownloadIcon(), func() {
 		dialog.ShowConfirm("Download All", "Download all official distributions? This may take a long time and require significant disk space.", func(ok bool) {
 			if ok {
-				showBlockingProgress("Downloading All...", mw.Window, func(log func(string)) error {
+				mw.showBlockingProgress("Downloading All...", "", func(ctx context.Context, log func(string)) error {
 					// We invoke the scripts/download_all_distros.ps1 via logic helper or direct exec
 					// Since logic package handles downloads, we can implement a loop there or just call the PS script.
 					// Let's iterate over known distros and call logic.DownloadDistroOnly sequentially to get better progress report.
@@ -234,44 +463,91 @@ func (mw *MainWindow) makePackageTab() fyne.CanvasObject {
 
 					for i, task := range downloadTasks {
 						log(fmt.Sprintf("[%d/%d] Downloading %s...\n", i+1, len(downloadTasks), task.Ver))
-						err := logic.DownloadDistroOnly(context.Background(), mw.ProjectDir, task.Fam, task.Ver, log)
+						err := logic.DownloadDistroOnly(ctx, mw.ProjectDir, task.Fam, task.Ver, log)
 						if err != nil {
 							log(fmt.Sprintf("Error downloading %s: %v\n", task.Ver, err))
 							// Continue or stop? Let's continue.
 						}
 					}
 					return nil
-				}, refreshFunc)
+				}, afterDownload)
 			}
 		}, mw.Window)
 	})
 
 	btnAddCustom := widget.NewButtonWithIcon("", theme.ContentAddIcon(), func() {
-		n := widget.NewEntry()
-		n.SetPlaceHolder("Name")
-		v := widget.NewEntry()
-		v.SetPlaceHolder("Version")
-		p := widget.NewEntry()
-		p.SetPlaceHolder("Path/URL")
-		dialog.ShowCustomConfirm("Add Custom", "Add", "Cancel", container.NewVBox(n, v, p), func(ok bool) {
+		mw.showCustomPackageDialog(-1, refreshFunc)
+	})
+
+	btnCleanCache := widget.NewButtonWithIcon("", theme.ContentClearIcon(), func() {
+		dialog.ShowConfirm("Clean Up Cache", "Remove cached packages no catalog entry uses (or whose URL changed), and apply the cache size limit?", func(ok bool) {
 			if ok {
-				mw.Settings.CustomPackages = append(mw.Settings.CustomPackages, model.CustomPackage{Name: n.Text, Version: v.Text, PathOrUrl: p.Text})
-				mw.Config.SaveSettings(mw.Settings)
-				refreshFunc()
+				mw.collectCacheGarbage(refreshFunc)
 			}
 		}, mw.Window)
 	})
 
+	btnVerifyAll := widget.NewButtonWithIcon("", theme.ConfirmIcon(), func() {
+		var cached []model.Version
+		seen := make(map[string]bool)
+		for _, dCfg := range mw.Distros {
+			for _, ver := range dCfg.Versions {
+				if ver.LocalPath != "" && !seen[ver.LocalPath] {
+					seen[ver.LocalPath] = true
+					cached = append(cached, ver)
+				}
+			}
+		}
+		mw.verifyPackages(cached, sessionCorrupt, refreshFunc)
+	})
+
 	headerToolbar := container.NewHBox(
 		widget.NewLabelWithStyle("Package Library", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		layout.NewSpacer(),
 		btnUpdateSources,
 		btnDownloadAll,
 		btnAddCustom,
+		btnVerifyAll,
+		btnCleanCache,
 		btnRefreshList,
 	)
 
+	filterBar := container.NewBorder(nil, nil, nil, container.NewHBox(cacheSelect, sourceSelect, sortSelect, btnOrder), search)
+
 	scroll := container.NewVScroll(listContent)
 
-	return container.NewBorder(headerToolbar, nil, nil, nil, scroll)
+	return container.NewBorder(container.NewVBox(headerToolbar, filterBar), nil, nil, nil, scroll)
+}
+
+// Package list filter choices
+const (
+	allPackages      = "All packages"
+	pkgCached        = "Cached"
+	pkgNotCached     = "Not cached"
+	allSources       = "All sources"
+	pkgSourceCustom  = "Custom"
+	sortByCachedDate = "Date Cached"
+)
+
+// sourceOptions lists the catalog's sources plus Custom for the source filter
+func sourceOptions(distros map[string]model.DistroConfig, custom []model.CustomPackage) []string {
+	sources := map[string]bool{}
+	for _, fam := range distros {
+		for _, ver := range fam.Versions {
+			if ver.Source == "" {
+				sources["Official"] = true
+			} else {
+				sources[ver.Source] = true
+			}
+		}
+	}
+	options := make([]string, 0, len(sources))
+	for src := range sources {
+		options = append(options, src)
+	}
+	sort.Strings(options)
+	if len(custom) > 0 {
+		options = append(options, pkgSourceCustom)
+	}
+	return append([]string{allSources}, options...)
 }
@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"distronexus-gui/internal/logic"
+	"errors"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showBlockingProgress runs task as a tracked job and shows its output in a modal dialog.
+// instance names the WSL instance the job acts on ("" if none).
+// The dialog offers Cancel (kills the running process tree) and Run in Background
+// (the job keeps going and stays visible in the Jobs view). onDone runs once the job ends.
+// The bar is indeterminate until the job reports progress events, then shows the
+// overall completion and the named stages.
+func (mw *MainWindow) showBlockingProgress(title, instance string, task logic.JobFunc, onDone func()) *logic.Job {
+	logBinding := binding.NewString()
+	logLabel := widget.NewLabelWithData(logBinding)
+	logLabel.Wrapping = fyne.TextWrapBreak
+
+	// Scroll container for log
+	scroll := container.NewScroll(logLabel)
+	scroll.SetMinSize(fyne.NewSize(500, 300))
+
+	progressBar := widget.NewProgressBarInfinite()
+	stageBar := widget.NewProgressBar()
+	stageBar.Hide()
+	stageText := widget.NewRichText()
+	stageText.Hide()
+	stageMessage := widget.NewLabel("")
+	stageMessage.Truncation = fyne.TextTruncateEllipsis
+	stageMessage.Hide()
+
+	var d dialog.Dialog
+	btnBackground := widget.NewButtonWithIcon("Run in Background", theme.VisibilityOffIcon(), func() {
+		d.Hide()
+	})
+	btnCancel := widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), nil)
+	btnCancel.Importance = widget.DangerImportance
+
+	buttons := container.NewHBox(layout.NewSpacer(), btnBackground, btnCancel)
+	header := container.NewVBox(progressBar, stageBar, stageText, stageMessage)
+	content := container.NewBorder(header, buttons, nil, nil, scroll)
+
+	d = dialog.NewCustomWithoutButtons(title, content, mw.Window)
+	d.Show()
+
+	job := mw.Jobs.StartFor(title, instance, task)
+	stopFollow := job.Follow(func(line string) {
+		current, _ := logBinding.Get()
+		logBinding.Set(current + line)
+	})
+
+	showProgress := func(ev logic.ProgressEvent) {
+		if progressBar.Visible() {
+			progressBar.Stop()
+			progressBar.Hide()
+			stageBar.Show()
+			stageText.Show()
+			stageMessage.Show()
+		}
+		stageBar.SetValue(overallProgress(ev))
+		stageText.Segments = stageSegments(ev)
+		stageText.Refresh()
+		stageMessage.SetText(ev.Message)
+	}
+	unsubscribe := mw.Jobs.Subscribe(func(ev logic.JobEvent) {
+		if ev.Job == job && ev.Type == logic.JobProgress {
+			p := ev.Progress
+			fyne.Do(func() { showProgress(p) })
+		}
+	})
+	if p, ok := job.Progress(); ok {
+		showProgress(p)
+	}
+
+	btnCancel.OnTapped = func() {
+		btnCancel.SetText("Canceling...")
+		btnCancel.Disable()
+		job.Cancel()
+	}
+
+	go func() {
+		<-job.Done()
+		stopFollow()
+		unsubscribe()
+
+		fyne.Do(func() {
+			d.Hide()
+
+			switch job.Status() {
+			case logic.JobCanceled:
+				dialog.ShowInformation(title, "Operation canceled.", mw.Window)
+			case logic.JobFailed:
+				// Batches report their per-instance results in their own summary
+				var batchErr *logic.BatchError
+				if !errors.As(job.Err(), &batchErr) {
+					dialog.ShowError(job.Err(), mw.Window)
+				}
+			}
+		})
+
+		if onDone != nil {
+			onDone()
+		}
+	}()
+
+	return job
+}
+
+// overallProgress maps a stage-relative event onto 0..1 across all announced stages
+func overallProgress(ev logic.ProgressEvent) float64 {
+	pct := ev.Percent
+	if pct < 0 {
+		pct = 0
+	}
+	idx := -1
+	for i, st := range ev.Stages {
+		if st == ev.Stage {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return pct / 100
+	}
+	return (float64(idx) + pct/100) / float64(len(ev.Stages))
+}
+
+// stageSegments renders "Download ✓ › Extract 40% › Import" with the current stage in bold
+func stageSegments(ev logic.ProgressEvent) []widget.RichTextSegment {
+	stages := ev.Stages
+	if len(stages) == 0 {
+		stages = []string{ev.Stage}
+	}
+	current := -1
+	for i, st := range stages {
+		if st == ev.Stage {
+			current = i
+		}
+	}
+
+	var segs []widget.RichTextSegment
+	for i, st := range stages {
+		text := logic.StageTitle(st)
+		style := widget.RichTextStyle{Inline: true, ColorName: theme.ColorNamePlaceHolder}
+		switch {
+		case i < current:
+			text += " ✓"
+			style.ColorName = theme.ColorNameForeground
+		case i == current:
+			if ev.Percent >= 0 {
+				text += fmt.Sprintf(" %.0f%%", ev.Percent)
+			}
+			style = widget.RichTextStyleStrong
+			style.Inline = true
+		}
+		if i > 0 {
+			segs = append(segs, &widget.TextSegment{Text: "  ›  ", Style: widget.RichTextStyleInline})
+		}
+		segs = append(segs, &widget.TextSegment{Text: text, Style: style})
+	}
+	return segs
+}
@@ -1,6 +1,14 @@
 package ui
 
 import (
+	"distronexus-gui/internal/applog"
+	"distronexus-gui/internal/config"
+	"distronexus-gui/internal/model"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
@@ -52,6 +60,108 @@ func (mw *MainWindow) ShowSettingsDialog() {
 	})
 	terminalPathContainer := container.NewBorder(nil, nil, nil, btnPickTerminal, terminalPathEntry)
 
+	logLevelSelect := widget.NewSelect(applog.Levels, nil)
+	logLevelSelect.SetSelected(applog.LevelInfo)
+	for _, l := range applog.Levels {
+		if strings.EqualFold(l, mw.Settings.LogLevel) {
+			logLevelSelect.SetSelected(l)
+		}
+	}
+
+	pollEntry := widget.NewEntry()
+	pollEntry.SetPlaceHolder("Default: 5")
+	if mw.Settings.StatePollSeconds > 0 {
+		pollEntry.SetText(strconv.Itoa(mw.Settings.StatePollSeconds))
+	}
+
+	cacheLimitEntry := widget.NewEntry()
+	cacheLimitEntry.SetPlaceHolder("No limit")
+	if mw.Settings.CacheLimitGB > 0 {
+		cacheLimitEntry.SetText(strconv.Itoa(mw.Settings.CacheLimitGB))
+	}
+
+	trayCheck := widget.NewCheck("Keep running in the system tray when closed", nil)
+	trayCheck.SetChecked(mw.Settings.MinimizeToTray)
+
+	notify := mw.notificationPrefs()
+	notifySuccess := widget.NewCheck("Completed", nil)
+	notifySuccess.SetChecked(notify.OnSuccess)
+	notifyFailure := widget.NewCheck("Failed", nil)
+	notifyFailure.SetChecked(notify.OnFailure)
+	notifyCancel := widget.NewCheck("Canceled", nil)
+	notifyCancel.SetChecked(notify.OnCancel)
+	notifyMinEntry := widget.NewEntry()
+	notifyMinEntry.SetText(strconv.Itoa(notify.MinDurationSec))
+	notifyBox := container.NewHBox(notifySuccess, notifyFailure, notifyCancel)
+
+	apiCheck := widget.NewCheck("Enable local automation API", nil)
+	apiCheck.SetChecked(mw.Settings.ApiEnabled)
+	apiListenEntry := widget.NewEntry()
+	apiListenEntry.SetPlaceHolder("Default: " + config.DefaultApiListen + " (or unix:<socket path>)")
+	apiListenEntry.SetText(mw.Settings.ApiListen)
+	btnCopyToken := widget.NewButtonWithIcon("Copy Token", theme.ContentCopyIcon(), func() {
+		token, err := mw.Config.LoadAPIToken()
+		if err != nil {
+			dialog.ShowError(err, mw.Window)
+			return
+		}
+		mw.Window.Clipboard().SetContent(token)
+	})
+	btnNewToken := widget.NewButtonWithIcon("Regenerate", theme.ViewRefreshIcon(), func() {
+		dialog.ShowConfirm("Regenerate Token", "Clients using the current token will stop working. Continue?", func(ok bool) {
+			if !ok {
+				return
+			}
+			token, err := mw.Config.RegenerateAPIToken()
+			if err != nil {
+				dialog.ShowError(err, mw.Window)
+				return
+			}
+			if mw.API != nil {
+				mw.API.SetToken(token)
+			}
+		}, mw.Window)
+	})
+	metricsCheck := widget.NewCheck("Expose Prometheus metrics at /metrics", nil)
+	metricsCheck.SetChecked(mw.Settings.MetricsEnabled)
+	apiBox := container.NewVBox(apiCheck, metricsCheck, container.NewHBox(btnCopyToken, btnNewToken))
+
+	prov := model.ProvisioningSettings{}
+	if mw.Settings.Provisioning != nil {
+		prov = *mw.Settings.Provisioning
+	}
+	systemdCheck := widget.NewCheck("Enable systemd in new instances", nil)
+	systemdCheck.SetChecked(prov.Systemd)
+	caCertsEntry := widget.NewMultiLineEntry()
+	caCertsEntry.SetPlaceHolder("PEM files added to new instances, one per line")
+	caCertsEntry.SetMinRowsVisible(2)
+	caCertsEntry.SetText(strings.Join(prov.CACertFiles, "\n"))
+	btnPickCert := widget.NewButtonWithIcon("", theme.FileIcon(), func() {
+		dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
+			if r == nil {
+				return
+			}
+			r.Close()
+			text := strings.TrimRight(caCertsEntry.Text, "\n")
+			if text != "" {
+				text += "\n"
+			}
+			caCertsEntry.SetText(text + r.URI().Path())
+		}, mw.Window)
+	})
+	caCertsContainer := container.NewBorder(nil, nil, nil, btnPickCert, caCertsEntry)
+	dotfilesEntry := widget.NewEntry()
+	dotfilesEntry.SetPlaceHolder("Folder copied into the new user's home")
+	dotfilesEntry.SetText(prov.DotfilesDir)
+	btnPickDotfiles := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if uri != nil {
+				dotfilesEntry.SetText(uri.Path())
+			}
+		}, mw.Window)
+	})
+	dotfilesContainer := container.NewBorder(nil, nil, nil, btnPickDotfiles, dotfilesEntry)
+
 	// Reset Button
 	btnReset := widget.NewButton("Reset to Defaults", func() {
 		dialog.ShowConfirm("Reset Settings", "Are you sure you want to restore default settings?", func(ok bool) {
@@ -62,44 +172,180 @@ func (mw *MainWindow) ShowSettingsDialog() {
 				defaultDistroEntry.SetText("Ubuntu-24.04")
 				distroSourceEntry.SetText("") // Empty defaults to MS Official in logic
 				terminalPathEntry.SetText("") // Empty defaults to ~
+				logLevelSelect.SetSelected(applog.LevelInfo)
+				pollEntry.SetText("")
+				cacheLimitEntry.SetText("")
+				trayCheck.SetChecked(false)
+				apiCheck.SetChecked(false)
+				metricsCheck.SetChecked(false)
+				apiListenEntry.SetText("")
+				notifySuccess.SetChecked(defaultNotifications.OnSuccess)
+				notifyFailure.SetChecked(defaultNotifications.OnFailure)
+				notifyCancel.SetChecked(defaultNotifications.OnCancel)
+				notifyMinEntry.SetText(strconv.Itoa(defaultNotifications.MinDurationSec))
+				systemdCheck.SetChecked(false)
+				caCertsEntry.SetText("")
+				dotfilesEntry.SetText("")
 			}
 		}, mw.Window)
 	})
 	btnReset.Importance = widget.DangerImportance
 
-	// Create a custom content form
-	items := []*widget.FormItem{
-		widget.NewFormItem("Default Install Path", installPathContainer),
-		widget.NewFormItem("Distro Cache Path", cachePathContainer),
-		widget.NewFormItem("Default Quick Distro", defaultDistroEntry),
-		widget.NewFormItem("Update Source URL", distroSourceEntry),
-		widget.NewFormItem("Default Terminal Path", terminalPathContainer),
-		widget.NewFormItem("", btnReset),
+	// Error labels shown under each field when validation fails
+	fieldEntries := map[string]*widget.Entry{
+		config.FieldDefaultInstallPath:       installPathEntry,
+		config.FieldDistroCachePath:          distroCachePathEntry,
+		config.FieldDefaultDistro:            defaultDistroEntry,
+		config.FieldDistroSourceUrl:          distroSourceEntry,
+		config.FieldDefaultTerminalStartPath: terminalPathEntry,
+		config.FieldStatePollSeconds:         pollEntry,
+		config.FieldNotifyMinDuration:        notifyMinEntry,
+		config.FieldApiListen:                apiListenEntry,
+		config.FieldCacheLimitGB:             cacheLimitEntry,
+		config.FieldCACertFiles:              caCertsEntry,
+		config.FieldDotfilesDir:              dotfilesEntry,
 	}
+	fieldErrors := make(map[string]*widget.Label)
+	withError := func(field string, input fyne.CanvasObject) fyne.CanvasObject {
+		lbl := widget.NewLabel("")
+		lbl.Importance = widget.DangerImportance
+		lbl.Wrapping = fyne.TextWrapWord
+		lbl.Hide()
+		fieldErrors[field] = lbl
 
-	// Create and show dialog
-	d := dialog.NewForm("Global Settings", "Save", "Cancel", items, func(confirm bool) {
-		if confirm {
-			// update struct
-			mw.Settings.DefaultInstallPath = installPathEntry.Text
-			mw.Settings.DistroCachePath = distroCachePathEntry.Text
-			mw.Settings.DefaultDistro = defaultDistroEntry.Text
-			mw.Settings.DistroSourceUrl = distroSourceEntry.Text
-			mw.Settings.DefaultTerminalStartPath = terminalPathEntry.Text
-
-			// Persist to disk
-			err := mw.Config.SaveSettings(mw.Settings)
-			if err != nil {
-				dialog.ShowError(err, mw.Window)
+		// Entries only need a validator so SetValidationError shows the error icon;
+		// editing the field clears it again.
+		entry := fieldEntries[field]
+		entry.Validator = func(string) error { return nil }
+		return container.NewVBox(input, lbl)
+	}
+
+	highlight := func(errs config.ValidationErrors) {
+		for field, lbl := range fieldErrors {
+			if fe := errs.Field(field); fe != nil {
+				lbl.SetText(fe.Message)
+				lbl.Show()
+				fieldEntries[field].SetValidationError(fe)
 			} else {
-				// Optional: Show success or just log
-				if mw.LogArea != nil {
-					mw.LogArea.Append("Settings saved successfully.\n")
-				}
+				lbl.SetText("")
+				lbl.Hide()
+				fieldEntries[field].SetValidationError(nil)
+			}
+		}
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem("Default Install Path", withError(config.FieldDefaultInstallPath, installPathContainer)),
+		widget.NewFormItem("Distro Cache Path", withError(config.FieldDistroCachePath, cachePathContainer)),
+		widget.NewFormItem("Cache Limit (GB)", withError(config.FieldCacheLimitGB, cacheLimitEntry)),
+		widget.NewFormItem("Default Quick Distro", withError(config.FieldDefaultDistro, defaultDistroEntry)),
+		widget.NewFormItem("Update Source URL", withError(config.FieldDistroSourceUrl, distroSourceEntry)),
+		widget.NewFormItem("Default Terminal Path", withError(config.FieldDefaultTerminalStartPath, terminalPathContainer)),
+		widget.NewFormItem("Log Level", logLevelSelect),
+		widget.NewFormItem("State Refresh (sec)", withError(config.FieldStatePollSeconds, pollEntry)),
+		widget.NewFormItem("Tray", trayCheck),
+		widget.NewFormItem("Notify When", notifyBox),
+		widget.NewFormItem("Notify After (sec)", withError(config.FieldNotifyMinDuration, notifyMinEntry)),
+		widget.NewFormItem("Local API", apiBox),
+		widget.NewFormItem("API Address", withError(config.FieldApiListen, apiListenEntry)),
+		widget.NewFormItem("New Instances", systemdCheck),
+		widget.NewFormItem("CA Certificates", withError(config.FieldCACertFiles, caCertsContainer)),
+		widget.NewFormItem("Dotfiles", withError(config.FieldDotfilesDir, dotfilesContainer)),
+		widget.NewFormItem("", btnReset),
+	)
+
+	// Custom buttons so the dialog stays open when validation fails
+	var d dialog.Dialog
+	btnCancel := widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() { d.Hide() })
+	btnSave := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
+		candidate := *mw.Settings
+		candidate.DefaultInstallPath = strings.TrimSpace(installPathEntry.Text)
+		candidate.DistroCachePath = strings.TrimSpace(distroCachePathEntry.Text)
+		candidate.DefaultDistro = strings.TrimSpace(defaultDistroEntry.Text)
+		candidate.DistroSourceUrl = strings.TrimSpace(distroSourceEntry.Text)
+		candidate.DefaultTerminalStartPath = strings.TrimSpace(terminalPathEntry.Text)
+		candidate.LogLevel = logLevelSelect.Selected
+		candidate.MinimizeToTray = trayCheck.Checked
+		candidate.ApiEnabled = apiCheck.Checked
+		candidate.MetricsEnabled = metricsCheck.Checked
+		candidate.ApiListen = strings.TrimSpace(apiListenEntry.Text)
+
+		var pollErr error
+		candidate.StatePollSeconds = 0
+		if text := strings.TrimSpace(pollEntry.Text); text != "" {
+			candidate.StatePollSeconds, pollErr = strconv.Atoi(text)
+		}
+
+		var limitErr error
+		candidate.CacheLimitGB = 0
+		if text := strings.TrimSpace(cacheLimitEntry.Text); text != "" {
+			candidate.CacheLimitGB, limitErr = strconv.Atoi(text)
+		}
+
+		var notifyMin int
+		var notifyErr error
+		if text := strings.TrimSpace(notifyMinEntry.Text); text != "" {
+			notifyMin, notifyErr = strconv.Atoi(text)
+		}
+		candidate.Notifications = &model.NotificationSettings{
+			OnSuccess:      notifySuccess.Checked,
+			OnFailure:      notifyFailure.Checked,
+			OnCancel:       notifyCancel.Checked,
+			MinDurationSec: notifyMin,
+		}
+
+		candidate.Provisioning = nil
+		var certFiles []string
+		for _, line := range strings.Split(caCertsEntry.Text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				certFiles = append(certFiles, line)
 			}
 		}
-	}, mw.Window)
+		if p := (model.ProvisioningSettings{Systemd: systemdCheck.Checked, CACertFiles: certFiles, DotfilesDir: strings.TrimSpace(dotfilesEntry.Text)}); p.Systemd || len(p.CACertFiles) > 0 || p.DotfilesDir != "" {
+			candidate.Provisioning = &p
+		}
+
+		errs := mw.Config.ValidateSettings(&candidate, mw.Distros)
+		if pollErr != nil && errs.Field(config.FieldStatePollSeconds) == nil {
+			errs = append(errs, &config.FieldError{Field: config.FieldStatePollSeconds, Message: fmt.Sprintf("'%s' is not a whole number", pollEntry.Text)})
+		}
+		if limitErr != nil && errs.Field(config.FieldCacheLimitGB) == nil {
+			errs = append(errs, &config.FieldError{Field: config.FieldCacheLimitGB, Message: fmt.Sprintf("'%s' is not a whole number", cacheLimitEntry.Text)})
+		}
+		if notifyErr != nil && errs.Field(config.FieldNotifyMinDuration) == nil {
+			errs = append(errs, &config.FieldError{Field: config.FieldNotifyMinDuration, Message: fmt.Sprintf("'%s' is not a whole number", notifyMinEntry.Text)})
+		}
+		highlight(errs)
+		if len(errs) > 0 {
+			return
+		}
 
+		// Persist first so the running app never uses settings that were not saved
+		if err := mw.Config.SaveSettings(&candidate); err != nil {
+			dialog.ShowError(err, mw.Window)
+			return
+		}
+
+		// update struct
+		*mw.Settings = candidate
+		applog.SetLevel(mw.Settings.LogLevel)
+		mw.Watcher.SetInterval(time.Duration(mw.Settings.StatePollSeconds) * time.Second)
+		mw.applyAPISettings()
+		go mw.enforceCacheLimit()
+
+		d.Hide()
+		// Optional: Show success or just log
+		if mw.LogArea != nil {
+			mw.LogArea.Append("Settings saved successfully.\n")
+		}
+	})
+	btnSave.Importance = widget.HighImportance
+
+	content := container.NewBorder(nil, container.NewGridWithColumns(2, btnCancel, btnSave), nil, nil,
+		container.NewVScroll(form))
+
+	// Create and show dialog
+	d = dialog.NewCustomWithoutButtons("Global Settings", content, mw.Window)
 	d.Resize(fyne.NewSize(600, 500))
 	d.Show()
 }
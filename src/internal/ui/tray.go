@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"context"
+	"distronexus-gui/internal/logic"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// setupTray installs the system tray menu when the driver supports one.
+// It reports whether a tray is available.
+func (mw *MainWindow) setupTray() bool {
+	desk, ok := mw.App.(desktop.App)
+	if !ok {
+		return false
+	}
+	mw.tray = desk
+	mw.refreshTrayMenu()
+	return true
+}
+
+// refreshTrayMenu rebuilds the tray menu from the instances shown on the home view.
+// Must run on the UI goroutine.
+func (mw *MainWindow) refreshTrayMenu() {
+	if mw.tray == nil {
+		return
+	}
+
+	names := make([]string, 0, len(mw.homeInstances))
+	for name := range mw.homeInstances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := []*fyne.MenuItem{
+		fyne.NewMenuItem("Show DistroNexus", mw.showFromTray),
+		fyne.NewMenuItemSeparator(),
+	}
+	if len(names) == 0 {
+		empty := fyne.NewMenuItem("No instances", nil)
+		empty.Disabled = true
+		items = append(items, empty)
+	}
+	for _, name := range names {
+		items = append(items, mw.trayInstanceItem(mw.homeInstances[name]))
+	}
+
+	quit := fyne.NewMenuItem("Quit", mw.quit)
+	quit.IsQuit = true
+	items = append(items,
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Shut down all WSL", func() {
+			mw.runTrayJob("Shutting down WSL", "", func(ctx context.Context, log func(string)) error {
+				return logic.ShutdownWsl(ctx, mw.ProjectDir, log)
+			})
+		}),
+		fyne.NewMenuItemSeparator(),
+		quit,
+	)
+
+	mw.tray.SetSystemTrayMenu(fyne.NewMenu("DistroNexus", items...))
+}
+
+// trayInstanceItem builds the "Name (State)" submenu with start/stop/terminal actions
+func (mw *MainWindow) trayInstanceItem(inst logic.WslInstance) *fyne.MenuItem {
+	name := inst.Name
+	running := inst.State == logic.StateRunning
+
+	start := fyne.NewMenuItem("Start", func() {
+		mw.runTrayJob("Starting "+name, name, func(ctx context.Context, log func(string)) error {
+			return logic.StartDistro(ctx, mw.ProjectDir, name, false, "")
+		})
+	})
+	start.Disabled = running
+
+	stop := fyne.NewMenuItem("Stop", func() {
+		mw.runTrayJob("Stopping "+name, name, func(ctx context.Context, log func(string)) error {
+			return logic.StopDistro(ctx, mw.ProjectDir, name, log)
+		})
+	})
+	stop.Disabled = !running
+
+	terminal := fyne.NewMenuItem("Open Terminal", func() {
+		if err := logic.StartDistro(context.Background(), mw.ProjectDir, name, true, mw.Settings.DefaultTerminalStartPath); err != nil {
+			mw.showFromTray()
+			dialog.ShowError(err, mw.Window)
+		}
+		mw.Watcher.Trigger()
+	})
+
+	item := fyne.NewMenuItem(fmt.Sprintf("%s (%s)", name, inst.State), nil)
+	item.ChildMenu = fyne.NewMenu("", start, stop, terminal)
+	return item
+}
+
+// runTrayJob runs task as a tracked job without a modal dialog (the window may be hidden).
+// Failures bring the window back so the error is visible.
+func (mw *MainWindow) runTrayJob(title, instance string, task logic.JobFunc) {
+	job := mw.Jobs.StartFor(title, instance, task)
+	go func() {
+		<-job.Done()
+		mw.Watcher.Trigger()
+		if job.Status() != logic.JobFailed {
+			return
+		}
+		slog.Warn("tray action failed", "action", title, "error", job.Err())
+		fyne.Do(func() {
+			mw.showFromTray()
+			dialog.ShowError(fmt.Errorf("%s: %w", title, job.Err()), mw.Window)
+		})
+	}()
+}
+
+func (mw *MainWindow) showFromTray() {
+	mw.Window.Show()
+	mw.Window.RequestFocus()
+}
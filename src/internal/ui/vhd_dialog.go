@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"context"
+	"distronexus-gui/internal/logic"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const gib = 1024 * 1024 * 1024
+
+// showVhdSettingsDialog lets the user toggle sparse mode and change the maximum disk size.
+// Controls for flags the installed WSL doesn't know are disabled.
+func (mw *MainWindow) showVhdSettingsDialog(d logic.WslInstance) {
+	go func() {
+		features, err := logic.DetectWslFeatures(context.Background())
+		fyne.Do(func() {
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("cannot detect WSL features: %w", err), mw.Window)
+				return
+			}
+			mw.vhdSettingsForm(d, features)
+		})
+	}()
+}
+
+func (mw *MainWindow) vhdSettingsForm(d logic.WslInstance, features logic.WslFeatures) {
+	version := features.Version
+	if version == "" {
+		version = "inbox (no --version support)"
+	}
+	info := widget.NewLabel(fmt.Sprintf("WSL version: %s\nApplying changes stops '%s'.", version, d.Name))
+	info.Wrapping = fyne.TextWrapWord
+
+	sparseCheck := widget.NewCheck("Sparse VHD (release freed space automatically)", nil)
+	sparseCheck.SetChecked(d.Sparse)
+	if !features.SetSparse {
+		sparseCheck.Disable()
+		sparseCheck.Text += " - needs a newer WSL"
+	}
+
+	currentGB := int((d.VhdMaxSize + gib - 1) / gib)
+	sizeEntry := widget.NewEntry()
+	if currentGB > 0 {
+		sizeEntry.SetText(strconv.Itoa(currentGB))
+	}
+	sizeEntry.Validator = func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("enter a size in GB")
+		}
+		return nil
+	}
+	sizeHint := "Maximum size in GB"
+	if !features.Resize {
+		sizeEntry.Disable()
+		sizeHint += " (needs a newer WSL)"
+	}
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("", info),
+		widget.NewFormItem("Sparse", sparseCheck),
+		widget.NewFormItem("Max Size", sizeEntry),
+	}
+	items[2].HintText = sizeHint
+
+	dlg := dialog.NewForm("Virtual Disk", "Apply", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		sparse := sparseCheck.Checked
+		changeSparse := features.SetSparse && sparse != d.Sparse
+
+		sizeGB := currentGB
+		if text := strings.TrimSpace(sizeEntry.Text); text != "" {
+			sizeGB, _ = strconv.Atoi(text)
+		}
+		changeSize := features.Resize && sizeGB > 0 && sizeGB != currentGB
+		if !changeSparse && !changeSize {
+			return
+		}
+
+		apply := func() {
+			mw.showBlockingProgress("Updating disk of "+d.Name+"...", d.Name, func(ctx context.Context, log func(string)) error {
+				if changeSparse {
+					if err := logic.SetDistroSparse(ctx, mw.ProjectDir, d.Name, sparse, true, log); err != nil {
+						return err
+					}
+				}
+				if changeSize {
+					return logic.ResizeDistroDisk(ctx, mw.ProjectDir, d.Name, sizeGB, log)
+				}
+				return nil
+			}, func() { mw.RefreshHomeList() })
+		}
+
+		var warnings []string
+		if changeSparse && sparse && features.AllowUnsafe {
+			warnings = append(warnings, "This WSL version marks sparse VHDs as experimental (--allow-unsafe); a crash while writing can corrupt the disk.")
+		}
+		if changeSize && sizeGB < currentGB {
+			warnings = append(warnings, fmt.Sprintf("Shrinking from %d GB to %d GB fails if the filesystem uses more than the new size.", currentGB, sizeGB))
+		}
+		if len(warnings) == 0 {
+			apply()
+			return
+		}
+		dialog.ShowConfirm("Virtual Disk", strings.Join(warnings, "\n\n")+"\n\nContinue?", func(ok bool) {
+			if ok {
+				apply()
+			}
+		}, mw.Window)
+	}, mw.Window)
+	dlg.Resize(fyne.NewSize(500, 300))
+	dlg.Show()
+}